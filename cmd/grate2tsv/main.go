@@ -26,9 +26,21 @@ import (
 	"time"
 
 	"github.com/pbnjay/grate"
+	_ "github.com/pbnjay/grate/dbf"
+	_ "github.com/pbnjay/grate/dif"
+	_ "github.com/pbnjay/grate/docx"
+	_ "github.com/pbnjay/grate/dta"
+	_ "github.com/pbnjay/grate/fixedwidth"
+	_ "github.com/pbnjay/grate/html"
+	_ "github.com/pbnjay/grate/numbers"
+	_ "github.com/pbnjay/grate/ods"
+	_ "github.com/pbnjay/grate/sav"
 	_ "github.com/pbnjay/grate/simple"
+	_ "github.com/pbnjay/grate/sylk"
 	_ "github.com/pbnjay/grate/xls"
+	_ "github.com/pbnjay/grate/xlsb"
 	_ "github.com/pbnjay/grate/xlsx"
+	_ "github.com/pbnjay/grate/xmlss"
 )
 
 var (
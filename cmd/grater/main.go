@@ -7,9 +7,21 @@ import (
 	"strings"
 
 	"github.com/pbnjay/grate"
+	_ "github.com/pbnjay/grate/dbf"
+	_ "github.com/pbnjay/grate/dif"
+	_ "github.com/pbnjay/grate/docx"
+	_ "github.com/pbnjay/grate/dta"
+	_ "github.com/pbnjay/grate/fixedwidth"
+	_ "github.com/pbnjay/grate/html"
+	_ "github.com/pbnjay/grate/numbers"
+	_ "github.com/pbnjay/grate/ods"
+	_ "github.com/pbnjay/grate/sav"
 	_ "github.com/pbnjay/grate/simple" // tsv and csv support
+	_ "github.com/pbnjay/grate/sylk"
 	_ "github.com/pbnjay/grate/xls"
+	_ "github.com/pbnjay/grate/xlsb"
 	_ "github.com/pbnjay/grate/xlsx"
+	_ "github.com/pbnjay/grate/xmlss"
 )
 
 func main() {
@@ -0,0 +1,158 @@
+// Package commontext contains text handling shared by the plain text and
+// markup based sources, such as detecting and decoding character encodings.
+package commontext
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is a character encoding which can be decoded to UTF-8.
+type Encoding struct {
+	// Name is the canonical label of the encoding, e.g. "windows-1252".
+	Name string
+
+	high  *[128]rune       // the 0x80-0xFF range of single-byte encodings
+	order binary.ByteOrder // byte order of UTF-16 encodings
+}
+
+// Supported encodings.
+var (
+	UTF8        = &Encoding{Name: "utf-8"}
+	UTF16LE     = &Encoding{Name: "utf-16le", order: binary.LittleEndian}
+	UTF16BE     = &Encoding{Name: "utf-16be", order: binary.BigEndian}
+	Windows1250 = &Encoding{Name: "windows-1250", high: singleByte(cp1250)}
+	Windows1251 = &Encoding{Name: "windows-1251", high: singleByte(cp1251)}
+	Windows1252 = &Encoding{Name: "windows-1252", high: singleByte(cp1252)}
+	Windows1253 = &Encoding{Name: "windows-1253", high: singleByte(cp1253)}
+	Windows1254 = &Encoding{Name: "windows-1254", high: singleByte(cp1254)}
+	Windows1255 = &Encoding{Name: "windows-1255", high: singleByte(cp1255)}
+	Windows1256 = &Encoding{Name: "windows-1256", high: singleByte(cp1256)}
+	Windows1257 = &Encoding{Name: "windows-1257", high: singleByte(cp1257)}
+	Windows1258 = &Encoding{Name: "windows-1258", high: singleByte(cp1258)}
+	IBM437      = &Encoding{Name: "ibm437", high: singleByte(cp437)}
+	IBM850      = &Encoding{Name: "ibm850", high: singleByte(cp850)}
+	IBM852      = &Encoding{Name: "ibm852", high: singleByte(cp852)}
+	IBM866      = &Encoding{Name: "ibm866", high: singleByte(cp866)}
+)
+
+// singleByte builds the table for the high range of a single-byte
+// encoding, from the characters starting at 0x80. Characters after the
+// end of the list map to the same Latin-1 code point.
+func singleByte(chars []rune) *[128]rune {
+	var res [128]rune
+	for i := range res {
+		res[i] = rune(0x80 + i)
+		if i < len(chars) {
+			res[i] = chars[i]
+		}
+	}
+	return &res
+}
+
+// encodingLabels maps the labels used by charset declarations to the
+// supported encodings. As in web browsers, Latin-1 and ASCII are decoded
+// as Windows-1252.
+var encodingLabels = map[string]*Encoding{
+	"windows-1250":      Windows1250,
+	"cp1250":            Windows1250,
+	"x-cp1250":          Windows1250,
+	"windows-1251":      Windows1251,
+	"cp1251":            Windows1251,
+	"x-cp1251":          Windows1251,
+	"windows-1253":      Windows1253,
+	"cp1253":            Windows1253,
+	"windows-1254":      Windows1254,
+	"cp1254":            Windows1254,
+	"iso-8859-9":        Windows1254,
+	"latin5":            Windows1254,
+	"windows-1255":      Windows1255,
+	"cp1255":            Windows1255,
+	"windows-1256":      Windows1256,
+	"cp1256":            Windows1256,
+	"windows-1257":      Windows1257,
+	"cp1257":            Windows1257,
+	"windows-1258":      Windows1258,
+	"cp1258":            Windows1258,
+	"ibm437":            IBM437,
+	"cp437":             IBM437,
+	"437":               IBM437,
+	"ibm850":            IBM850,
+	"cp850":             IBM850,
+	"850":               IBM850,
+	"ibm852":            IBM852,
+	"cp852":             IBM852,
+	"852":               IBM852,
+	"ibm866":            IBM866,
+	"cp866":             IBM866,
+	"866":               IBM866,
+	"utf-8":             UTF8,
+	"utf8":              UTF8,
+	"unicode-1-1-utf-8": UTF8,
+	"utf-16":            UTF16LE,
+	"utf-16le":          UTF16LE,
+	"unicode":           UTF16LE,
+	"utf-16be":          UTF16BE,
+	"windows-1252":      Windows1252,
+	"cp1252":            Windows1252,
+	"x-cp1252":          Windows1252,
+	"iso-8859-1":        Windows1252,
+	"iso8859-1":         Windows1252,
+	"latin1":            Windows1252,
+	"l1":                Windows1252,
+	"us-ascii":          Windows1252,
+	"ascii":             Windows1252,
+}
+
+// LookupEncoding returns the encoding with the given label, or nil if the
+// encoding is not supported.
+func LookupEncoding(label string) *Encoding {
+	return encodingLabels[strings.ToLower(strings.TrimSpace(label))]
+}
+
+// DetectBOM returns the encoding indicated by a byte order mark at the
+// start of data, and the length of the mark. It returns nil if there is no
+// byte order mark.
+func DetectBOM(data []byte) (*Encoding, int) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return UTF8, 3
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return UTF16LE, 2
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return UTF16BE, 2
+	}
+	return nil, 0
+}
+
+// Decode converts data in the encoding to a UTF-8 string. Invalid
+// sequences are replaced with the unicode replacement character.
+func (e *Encoding) Decode(data []byte) string {
+	switch {
+	case e.order != nil:
+		us := make([]uint16, len(data)/2)
+		for i := range us {
+			us[i] = e.order.Uint16(data[i*2:])
+		}
+		s := string(utf16.Decode(us))
+		if len(data)%2 != 0 {
+			s += string(utf8.RuneError)
+		}
+		return s
+	case e.high != nil:
+		var sb strings.Builder
+		sb.Grow(len(data))
+		for _, b := range data {
+			if b < 0x80 {
+				sb.WriteByte(b)
+			} else {
+				sb.WriteRune(e.high[b-0x80])
+			}
+		}
+		return sb.String()
+	}
+	return strings.ToValidUTF8(string(data), string(utf8.RuneError))
+}
@@ -0,0 +1,104 @@
+package commontext
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		label string
+		data  string
+		want  string
+	}{
+		{"UTF-8", "caf\xc3\xa9", "café"},
+		{"utf-8", "bad\xff", "bad�"},
+		{"windows-1252", "\x80 caf\xe9 \x93q\x94", "€ café “q”"},
+		{"ISO-8859-1", "caf\xe9", "café"},
+		{"utf-16le", "c\x00a\x00f\x00\xe9\x00", "café"},
+		{"utf-16be", "\x00c\x00a\x00f\x00\xe9", "café"},
+	}
+	for _, c := range cases {
+		enc := LookupEncoding(c.label)
+		if enc == nil {
+			t.Fatalf("encoding %s not found", c.label)
+		}
+		if got := enc.Decode([]byte(c.data)); got != c.want {
+			t.Fatalf("%s: got %q, expected %q", c.label, got, c.want)
+		}
+	}
+	if LookupEncoding("ebcdic") != nil {
+		t.Fatal("expected an unsupported encoding")
+	}
+}
+
+func TestDetectBOM(t *testing.T) {
+	cases := []struct {
+		data string
+		want *Encoding
+		n    int
+	}{
+		{"\xef\xbb\xbfa", UTF8, 3},
+		{"\xff\xfea\x00", UTF16LE, 2},
+		{"\xfe\xff\x00a", UTF16BE, 2},
+		{"abc", nil, 0},
+	}
+	for _, c := range cases {
+		enc, n := DetectBOM([]byte(c.data))
+		if enc != c.want || n != c.n {
+			t.Fatalf("%q: got %v %d", c.data, enc, n)
+		}
+	}
+}
+
+func TestDetectEncoding(t *testing.T) {
+	cases := []struct {
+		data string
+		want *Encoding
+		text string
+	}{
+		{"caf\xc3\xa9,na\xc3\xafve", UTF8, "café,naïve"},
+		{"\xef\xbb\xbfa,b", UTF8, ""},
+		{"a\x00,\x00b\x00\n\x00", UTF16LE, "a,b\n"},
+		{"\x00a\x00,\x00b\x00\n", UTF16BE, "a,b\n"},
+		{"caf\xe9, na\xefve, fa\xe7ade", Windows1252, "café, naïve, façade"},
+		{"\xcf\xf0\xe8\xe2\xe5\xf2, \xec\xe8\xf0;\xcc\xee\xf1\xea\xe2\xe0", Windows1251, "Привет, мир;Москва"},
+		{"Za\xbf\xf3\xb3\xe6 g\xea\x9cl\xb9 ja\x9f\xf1, \xb3\xb9ka", Windows1250, "Zażółć gęślą jaźń, łąka"},
+		{"\xc5\xeb\xeb\xe7\xed\xe9\xea\xdc \xea\xe5\xdf\xec\xe5\xed\xe1", Windows1253, "Ελληνικά κείμενα"},
+		{"plain ascii", UTF8, "plain ascii"},
+	}
+	for _, c := range cases {
+		enc := DetectEncoding([]byte(c.data))
+		if enc != c.want {
+			t.Fatalf("%q: got %s, expected %s", c.data, enc.Name, c.want.Name)
+		}
+		if c.text != "" && enc.Decode([]byte(c.data)) != c.text {
+			t.Fatalf("%q: decoded as %q", c.data, enc.Decode([]byte(c.data)))
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	cases := []struct {
+		enc  *Encoding
+		data string
+		text string
+	}{
+		{UTF8, "caf\xc3\xa9", "café"},
+		{UTF16LE, "c\x00a\x00f\x00\xe9\x00=\xd8\x00\xde", "café😀"},
+		{UTF16BE, "\x00c\xd8=\xde\x00\x00x\x00", "c😀x�"},
+		{Windows1251, "\xcc\xe8\xf0", "Мир"},
+	}
+	for _, c := range cases {
+		r := c.enc.NewReader(iotest.OneByteReader(strings.NewReader(c.data)))
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != c.text {
+			t.Fatalf("%s: got %q, expected %q", c.enc.Name, got, c.text)
+		}
+	}
+}
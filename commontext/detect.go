@@ -0,0 +1,156 @@
+package commontext
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// DetectEncoding guesses the encoding of a sample of text. It recognizes
+// byte order marks, UTF-16 without a byte order mark, and valid UTF-8.
+// Other text is assumed to use a Windows code page: text in the Cyrillic,
+// Greek, Hebrew and Arabic scripts is recognized by its words being made
+// up entirely of high bytes, and Central European text by the high bytes
+// within words which only decode to letters in Windows-1250.
+func DetectEncoding(sample []byte) *Encoding {
+	if enc, _ := DetectBOM(sample); enc != nil {
+		return enc
+	}
+	if enc := detectUTF16(sample); enc != nil {
+		return enc
+	}
+	if utf8.Valid(trimPartialRune(sample)) {
+		return UTF8
+	}
+
+	// count the high bytes in words without any ASCII letters
+	high, pure := 0, 0
+	for i := 0; i < len(sample); {
+		j := i
+		ascii := false
+		for j < len(sample) && isWordByte(sample[j]) {
+			ascii = ascii || sample[j] < 0x80
+			j++
+		}
+		for k := i; k < j; k++ {
+			if sample[k] >= 0x80 {
+				high++
+				if !ascii {
+					pure++
+				}
+			}
+		}
+		if j == i {
+			j++
+		}
+		i = j
+	}
+	if high == 0 {
+		return Windows1252
+	}
+
+	if pure*2 > high {
+		var best *Encoding
+		bestScore := 0
+		for _, c := range []struct {
+			enc    *Encoding
+			script *unicode.RangeTable
+		}{
+			{Windows1251, unicode.Cyrillic},
+			{Windows1253, unicode.Greek},
+			{Windows1255, unicode.Hebrew},
+			{Windows1256, unicode.Arabic},
+		} {
+			// letters in the script, less any upper case letters
+			// following a lower case letter within a word
+			score := 0
+			var prev rune
+			for _, b := range sample {
+				r := rune(b)
+				if b >= 0x80 {
+					r = c.enc.high[b-0x80]
+					if unicode.Is(c.script, r) {
+						score++
+					}
+				}
+				if unicode.IsUpper(r) && unicode.IsLower(prev) {
+					score -= 2
+				}
+				prev = r
+			}
+			if score > bestScore {
+				best, bestScore = c.enc, score
+			}
+		}
+		if bestScore*10 > high*6 {
+			return best
+		}
+	}
+
+	// Latin scripts: count high bytes next to ASCII letters which decode
+	// to letters, preferring Windows-1252 on ties
+	best, bestScore := Windows1252, -1
+	for _, enc := range []*Encoding{Windows1252, Windows1250} {
+		score := 0
+		for i, b := range sample {
+			if b < 0x80 || !unicode.IsLetter(enc.high[b-0x80]) {
+				continue
+			}
+			if (i > 0 && isASCIILetter(sample[i-1])) || (i+1 < len(sample) && isASCIILetter(sample[i+1])) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = enc, score
+		}
+	}
+	return best
+}
+
+// detectUTF16 recognizes UTF-16 text without a byte order mark by the zero
+// bytes of ASCII characters.
+func detectUTF16(sample []byte) *Encoding {
+	if len(sample) < 4 {
+		return nil
+	}
+	var zeros [2]int
+	n := len(sample) &^ 1
+	for i := 0; i < n; i++ {
+		if sample[i] == 0 {
+			zeros[i&1]++
+		}
+	}
+	half := n / 2
+	switch {
+	case zeros[1]*10 > half*7 && zeros[0]*10 < half:
+		return UTF16LE
+	case zeros[0]*10 > half*7 && zeros[1]*10 < half:
+		return UTF16BE
+	}
+	return nil
+}
+
+// trimPartialRune removes an incomplete UTF-8 sequence at the end of a
+// sample, which may have been cut from a longer text.
+func trimPartialRune(sample []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(sample); i++ {
+		c := sample[len(sample)-i]
+		if c < 0x80 {
+			break
+		}
+		if utf8.RuneStart(c) {
+			if !utf8.FullRune(sample[len(sample)-i:]) {
+				return sample[:len(sample)-i]
+			}
+			break
+		}
+	}
+	return sample
+}
+
+func isWordByte(c byte) bool {
+	return c >= 0x80 || isASCIILetter(c)
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
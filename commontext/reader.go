@@ -0,0 +1,53 @@
+package commontext
+
+import "io"
+
+// NewReader returns a Reader which decodes text read from r to UTF-8.
+// Invalid UTF-8 is passed through as is.
+func (e *Encoding) NewReader(r io.Reader) io.Reader {
+	if e.order == nil && e.high == nil {
+		return r
+	}
+	return &decoder{enc: e, r: r}
+}
+
+type decoder struct {
+	enc *Encoding
+	r   io.Reader
+	buf [4096]byte
+	in  []byte // bytes which are not yet decoded
+	out []byte // decoded text which is not yet read
+	err error
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			if len(d.in) == 0 {
+				return 0, d.err
+			}
+			d.out = []byte(d.enc.Decode(d.in))
+			d.in = nil
+			break
+		}
+		n, err := d.r.Read(d.buf[:])
+		d.in = append(d.in, d.buf[:n]...)
+		d.err = err
+
+		// keep incomplete code units and surrogate pairs for the next read
+		k := len(d.in)
+		if d.enc.order != nil {
+			k &^= 1
+			if k >= 2 {
+				if u := d.enc.order.Uint16(d.in[k-2:]); u >= 0xD800 && u < 0xDC00 {
+					k -= 2
+				}
+			}
+		}
+		d.out = []byte(d.enc.Decode(d.in[:k]))
+		d.in = append(d.in[:0], d.in[k:]...)
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
@@ -0,0 +1,33 @@
+package commonxl
+
+// Chart describes a chart embedded in a sheet, or displayed as a chart sheet.
+type Chart struct {
+	// Type is the kind of chart, e.g. "column", "bar", "line", "pie",
+	// "area", "scatter". Combination charts list the first type.
+	Type  string
+	Title string
+
+	// Sheet is the name of the sheet (or chart sheet) containing the chart.
+	Sheet string
+	// Anchor is the range of cells covered by an embedded chart, or nil.
+	Anchor *CellRange
+
+	Series []*ChartSeries
+}
+
+// ChartSeries is a single data series plotted in a chart.
+type ChartSeries struct {
+	// Name is the series name, either a literal or the cached value of NameRef.
+	Name    string
+	NameRef string
+
+	// CategoriesRef and ValuesRef are the formula references to the source
+	// data (e.g. "Sheet1!$A$2:$A$10"), empty if the data is literal.
+	CategoriesRef string
+	ValuesRef     string
+
+	// Categories and Values contain the cached data points as saved with
+	// the chart. Missing points are nil.
+	Categories []interface{}
+	Values     []interface{}
+}
@@ -0,0 +1,36 @@
+package commonxl
+
+// DefaultPalette contains the RRGGBB hex values for the built-in indexed
+// colors 8-63. Colors 0-7 are duplicates of 8-15, and custom palettes may
+// override any of these values.
+var DefaultPalette = [56]string{
+	"000000", "FFFFFF", "FF0000", "00FF00", "0000FF", "FFFF00", "FF00FF", "00FFFF",
+	"800000", "008000", "000080", "808000", "800080", "008080", "C0C0C0", "808080",
+	"9999FF", "993366", "FFFFCC", "CCFFFF", "660066", "FF8080", "0066CC", "CCCCFF",
+	"000080", "FF00FF", "FFFF00", "00FFFF", "800080", "800000", "008080", "0000FF",
+	"00CCFF", "CCFFFF", "CCFFCC", "FFFF99", "99CCFF", "FF99CC", "CC99FF", "FFCC99",
+	"3366FF", "33CCCC", "99CC00", "FFCC00", "FF9900", "FF6600", "666699", "969696",
+	"003366", "339966", "003300", "333300", "993300", "993366", "333399", "333333",
+}
+
+// IndexedColor returns the RRGGBB hex value of an indexed color using the
+// provided palette (or DefaultPalette if nil). It returns an empty string
+// for the system/automatic colors.
+func IndexedColor(palette []string, icv int) string {
+	if palette == nil {
+		palette = DefaultPalette[:]
+	}
+	switch {
+	case icv < 0:
+		return ""
+	case icv < 8:
+		return DefaultPalette[icv]
+	case icv-8 < len(palette):
+		return palette[icv-8]
+	case icv == 64:
+		return "000000" // system foreground
+	case icv == 65:
+		return "FFFFFF" // system background
+	}
+	return ""
+}
@@ -0,0 +1,279 @@
+package commonxl
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CellRange describes a rectangular block of cells using 0-based,
+// inclusive row and column indexes.
+type CellRange struct {
+	FirstRow int
+	LastRow  int
+	FirstCol int
+	LastCol  int
+}
+
+// Contains returns true if the cell at (row, col) is within the range.
+func (r CellRange) Contains(row, col int) bool {
+	return row >= r.FirstRow && row <= r.LastRow && col >= r.FirstCol && col <= r.LastCol
+}
+
+// String returns the range in A1 reference style, e.g. "A1:C5".
+func (r CellRange) String() string {
+	s := ColumnName(r.FirstCol) + strconv.Itoa(r.FirstRow+1)
+	if r.FirstRow == r.LastRow && r.FirstCol == r.LastCol {
+		return s
+	}
+	return s + ":" + ColumnName(r.LastCol) + strconv.Itoa(r.LastRow+1)
+}
+
+// ColumnName returns the letter label of the 0-based column index:
+//    0="A", 1="B", 26="AA", 53="BB"
+func ColumnName(col int) string {
+	if col < 0 {
+		return "?"
+	}
+	var buf [8]byte
+	i := len(buf)
+	for col >= 0 {
+		i--
+		buf[i] = byte('A' + col%26)
+		col = col/26 - 1
+	}
+	return string(buf[i:])
+}
+
+// DiffStyle is a differential style, which only lists the formatting
+// properties that are applied on top of a cell's existing style.
+type DiffStyle struct {
+	NumFmt    string // number format code, if changed
+	FontColor string // RRGGBB hex color of the text, if changed
+	FillColor string // RRGGBB hex color of the cell background, if changed
+
+	Bold      bool
+	Italic    bool
+	Underline bool
+	Strike    bool
+}
+
+// Conditional formatting rule types, as named by the OOXML spec (section 18.18.12).
+const (
+	CondCellIs            = "cellIs"
+	CondExpression        = "expression"
+	CondColorScale        = "colorScale"
+	CondDataBar           = "dataBar"
+	CondIconSet           = "iconSet"
+	CondTop10             = "top10"
+	CondAboveAverage      = "aboveAverage"
+	CondUniqueValues      = "uniqueValues"
+	CondDuplicateValues   = "duplicateValues"
+	CondContainsText      = "containsText"
+	CondNotContainsText   = "notContainsText"
+	CondBeginsWith        = "beginsWith"
+	CondEndsWith          = "endsWith"
+	CondContainsBlanks    = "containsBlanks"
+	CondNotContainsBlanks = "notContainsBlanks"
+	CondContainsErrors    = "containsErrors"
+	CondNotContainsErrors = "notContainsErrors"
+	CondTimePeriod        = "timePeriod"
+)
+
+// CondRule is a single conditional formatting rule.
+type CondRule struct {
+	// Type of rule, one of the Cond* constants.
+	Type string
+
+	// Operator for CondCellIs rules (section 18.18.15), one of:
+	//   between, notBetween, equal, notEqual, greaterThan, lessThan,
+	//   greaterThanOrEqual, lessThanOrEqual
+	Operator string
+
+	// Formulas used by the rule (without a leading '=').
+	Formulas []string
+
+	// Text to match for the text rule types.
+	Text string
+
+	// Priority of the rule, lower values are evaluated first.
+	Priority int
+
+	// StopIfTrue prevents evaluation of lower priority rules when this one matches.
+	StopIfTrue bool
+
+	// Style applied to matching cells (may be nil).
+	Style *DiffStyle
+}
+
+// CondFormat applies a set of rules to a set of cell ranges.
+type CondFormat struct {
+	Ranges []CellRange
+	Rules  []*CondRule
+}
+
+// Contains returns true if the cell at (row, col) is covered by the format.
+func (c *CondFormat) Contains(row, col int) bool {
+	for _, r := range c.Ranges {
+		if r.Contains(row, col) {
+			return true
+		}
+	}
+	return false
+}
+
+// CondFormats is the list of conditional formats defined on a sheet.
+type CondFormats []*CondFormat
+
+// Matching returns the rules covering the cell at (row, col) which match
+// the value, in order of priority. Only the "simple" rule types that can
+// be decided by the cell value alone are evaluated, see CondRule.Evaluate.
+func (c CondFormats) Matching(row, col int, val interface{}) []*CondRule {
+	var cands []*CondRule
+	for _, cf := range c {
+		if cf.Contains(row, col) {
+			cands = append(cands, cf.Rules...)
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].Priority < cands[j].Priority
+	})
+
+	var res []*CondRule
+	for _, r := range cands {
+		if !r.Evaluate(val) {
+			continue
+		}
+		res = append(res, r)
+		if r.StopIfTrue {
+			break
+		}
+	}
+	return res
+}
+
+// Evaluate returns true if the rule matches the cell value. Only rules
+// which compare the cell value to constants are supported (CondCellIs
+// and the text and blank rule types), all other rules return false.
+func (r *CondRule) Evaluate(val interface{}) bool {
+	sval := ""
+	if val != nil {
+		sval = identFunc(nil, val)
+	}
+	switch r.Type {
+	case CondContainsBlanks:
+		return strings.TrimSpace(sval) == ""
+	case CondNotContainsBlanks:
+		return strings.TrimSpace(sval) != ""
+	case CondContainsText:
+		return strings.Contains(strings.ToLower(sval), strings.ToLower(r.Text))
+	case CondNotContainsText:
+		return !strings.Contains(strings.ToLower(sval), strings.ToLower(r.Text))
+	case CondBeginsWith:
+		return strings.HasPrefix(strings.ToLower(sval), strings.ToLower(r.Text))
+	case CondEndsWith:
+		return strings.HasSuffix(strings.ToLower(sval), strings.ToLower(r.Text))
+	case CondCellIs:
+		// handled below
+	default:
+		return false
+	}
+
+	if len(r.Formulas) == 0 {
+		return false
+	}
+	args := make([]interface{}, len(r.Formulas))
+	for i, f := range r.Formulas {
+		var ok bool
+		args[i], ok = formulaConstant(f)
+		if !ok {
+			// references and functions can't be evaluated
+			return false
+		}
+	}
+
+	switch r.Operator {
+	case "between", "notBetween":
+		if len(args) < 2 {
+			return false
+		}
+		lo, ok1 := compareValues(val, args[0])
+		hi, ok2 := compareValues(val, args[1])
+		if !ok1 || !ok2 {
+			return false
+		}
+		if lo < 0 && hi > 0 {
+			// bounds may be given in either order
+			lo, hi = hi, lo
+		}
+		in := lo >= 0 && hi <= 0
+		return in == (r.Operator == "between")
+	}
+
+	c, ok := compareValues(val, args[0])
+	if !ok {
+		return false
+	}
+	switch r.Operator {
+	case "equal":
+		return c == 0
+	case "notEqual":
+		return c != 0
+	case "greaterThan":
+		return c > 0
+	case "lessThan":
+		return c < 0
+	case "greaterThanOrEqual":
+		return c >= 0
+	case "lessThanOrEqual":
+		return c <= 0
+	}
+	return false
+}
+
+// formulaConstant decodes a constant formula (number, string or boolean).
+func formulaConstant(f string) (interface{}, bool) {
+	f = strings.TrimSpace(strings.TrimPrefix(f, "="))
+	if len(f) >= 2 && f[0] == '"' && f[len(f)-1] == '"' {
+		return strings.ReplaceAll(f[1:len(f)-1], `""`, `"`), true
+	}
+	switch strings.ToUpper(f) {
+	case "TRUE":
+		return true, true
+	case "FALSE":
+		return false, true
+	}
+	if v, err := strconv.ParseFloat(f, 64); err == nil {
+		return v, true
+	}
+	return nil, false
+}
+
+// compareValues compares a cell value to a constant, returning -1, 0, or 1.
+// Numbers are compared numerically, everything else by case-insensitive text.
+func compareValues(val, arg interface{}) (int, bool) {
+	if _, isStr := arg.(string); !isStr {
+		a, ok1 := convertToFloat64(arg)
+		v, ok2 := convertToFloat64(val)
+		if !ok2 {
+			if s, ok := val.(string); ok {
+				v, ok2 = convertToFloat64(strings.ReplaceAll(strings.TrimSpace(s), ",", ""))
+			}
+		}
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		switch {
+		case v < a:
+			return -1, true
+		case v > a:
+			return 1, true
+		}
+		return 0, true
+	}
+	sval := ""
+	if val != nil {
+		sval = identFunc(nil, val)
+	}
+	return strings.Compare(strings.ToLower(sval), strings.ToLower(arg.(string))), true
+}
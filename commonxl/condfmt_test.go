@@ -0,0 +1,39 @@
+package commonxl
+
+import "testing"
+
+func TestColumnName(t *testing.T) {
+	cases := map[int]string{0: "A", 1: "B", 25: "Z", 26: "AA", 53: "BB", 701: "ZZ", 702: "AAA"}
+	for col, name := range cases {
+		if got := ColumnName(col); got != name {
+			t.Errorf("ColumnName(%d) = %q, expected %q", col, got, name)
+		}
+	}
+}
+
+func TestCondMatching(t *testing.T) {
+	cfs := CondFormats{
+		{
+			Ranges: []CellRange{{FirstRow: 0, LastRow: 9, FirstCol: 0, LastCol: 1}},
+			Rules: []*CondRule{
+				{Type: CondCellIs, Operator: "greaterThan", Formulas: []string{"100"}, Priority: 2},
+				{Type: CondCellIs, Operator: "between", Formulas: []string{"10", "1"}, Priority: 1, StopIfTrue: true},
+				{Type: CondContainsText, Text: "abc", Priority: 3},
+				{Type: CondExpression, Formulas: []string{"$A1>5"}, Priority: 4},
+			},
+		},
+	}
+
+	if r := cfs.Matching(0, 0, 150.0); len(r) != 1 || r[0].Priority != 2 {
+		t.Errorf("expected greaterThan rule to match, got %v", r)
+	}
+	if r := cfs.Matching(0, 0, 5); len(r) != 1 || r[0].Priority != 1 {
+		t.Errorf("expected between rule to match and stop, got %v", r)
+	}
+	if r := cfs.Matching(3, 1, "xxABCxx"); len(r) != 1 || r[0].Priority != 3 {
+		t.Errorf("expected containsText rule to match, got %v", r)
+	}
+	if r := cfs.Matching(10, 0, 150.0); len(r) != 0 {
+		t.Errorf("expected no rules outside of range, got %v", r)
+	}
+}
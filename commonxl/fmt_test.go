@@ -136,3 +136,26 @@ func TestBoolFormats(t *testing.T) {
 		t.Fatal(`-99.0 should be "yes"`)
 	}
 }
+
+func TestAddCode(t *testing.T) {
+	var x Formatter
+	if id := x.AddCode("Short Date"); id != 14 {
+		t.Fatalf("Short Date should be built-in format 14, got %d", id)
+	}
+	if id := x.AddCode("0.00"); id != 2 {
+		t.Fatalf("0.00 should be built-in format 2, got %d", id)
+	}
+	id := x.AddCode("0.000")
+	if id < 164 {
+		t.Fatalf("custom formats should not use built-in ids, got %d", id)
+	}
+	if id2 := x.AddCode("0.000"); id2 != id {
+		t.Fatalf("repeated format codes should use the same id, got %d and %d", id, id2)
+	}
+	if id2 := x.AddCode("0.0000"); id2 == id {
+		t.Fatal("different format codes should use different ids")
+	}
+	if s, _ := x.Apply(id, 1.5); s != "1.500" {
+		t.Fatalf(`expected "1.500", got "%s"`, s)
+	}
+}
@@ -11,6 +11,7 @@ import (
 type Formatter struct {
 	flags       uint64
 	customCodes map[uint16]FmtFunc
+	codeIDs     map[string]uint16
 }
 
 const (
@@ -46,6 +47,60 @@ func (x *Formatter) Add(fmtID uint16, formatCode string) error {
 	return nil
 }
 
+// namedFormats maps the named number formats used by the XML and HTML
+// spreadsheet formats to equivalent format codes.
+var namedFormats = map[string]string{
+	"General":        `General`,
+	"General Number": `General`,
+	"General Date":   `m/d/yy h:mm`,
+	"Short Date":     `mm-dd-yy`,
+	"Medium Date":    `d-mmm-yy`,
+	"Long Date":      `dddd, mmmm dd, yyyy`,
+	"Short Time":     `h:mm`,
+	"Medium Time":    `h:mm AM/PM`,
+	"Long Time":      `h:mm:ss AM/PM`,
+	"Fixed":          `0.00`,
+	"Standard":       `#,##0.00`,
+	"Percent":        `0.00%`,
+	"Scientific":     `0.00E+00`,
+	"Currency":       `"$"#,##0.00_);\("$"#,##0.00\)`,
+	"Euro Currency":  `"€"#,##0.00`,
+}
+
+// AddCode returns the ID of the number format with the given format code
+// or name (e.g. "Short Date"), adding a custom number format if needed.
+func (x *Formatter) AddCode(formatCode string) uint16 {
+	if code, ok := namedFormats[formatCode]; ok {
+		formatCode = code
+	}
+	if formatCode == "" {
+		return 0
+	}
+	if id, ok := x.codeIDs[formatCode]; ok {
+		return id
+	}
+	found := false
+	var builtin uint16
+	for id, code := range builtInFormats {
+		if code == formatCode && (!found || id < builtin) {
+			builtin, found = id, true
+		}
+	}
+	if found {
+		return builtin
+	}
+	if x.codeIDs == nil {
+		x.codeIDs = make(map[string]uint16)
+	}
+	id := uint16(164)
+	for x.customCodes[id] != nil {
+		id++
+	}
+	x.Add(id, formatCode)
+	x.codeIDs[formatCode] = id
+	return id
+}
+
 var (
 	minsMatch = regexp.MustCompile("h.*m.*s")
 	nonEsc    = regexp.MustCompile(`([^"]|^)"`)
@@ -284,3 +339,9 @@ var builtInFormats = map[uint16]string{
 	80: `mm:ss.0`,       // `นน:ทท.0`,
 	81: `d/m/bb`,        // `d/m/bb`,
 }
+
+// BuiltInFormat returns the format code of a built-in number format,
+// or an empty string if the fmtID is not built-in.
+func BuiltInFormat(fmtID uint16) string {
+	return builtInFormats[fmtID]
+}
@@ -0,0 +1,17 @@
+package commonxl
+
+// Image is a picture or other media file embedded in a workbook.
+type Image struct {
+	// Name is the image's file or picture name, if known.
+	Name string
+	// Format is the lower-case image type, e.g. "png", "jpeg", "emf".
+	Format string
+	// Data contains the raw image file contents.
+	Data []byte
+
+	// Sheet is the name of the sheet the image is placed on, if any.
+	Sheet string
+	// Anchor is the range of cells covered by the image, or nil if the
+	// image is not anchored to cells.
+	Anchor *CellRange
+}
@@ -0,0 +1,72 @@
+package commonxl
+
+import "strconv"
+
+// PivotCache contains the source records saved with a workbook's pivot tables.
+type PivotCache struct {
+	// ID is the cache identifier referenced by pivot tables.
+	ID int
+	// Name uniquely identifies the cache within the workbook.
+	Name string
+	// Source describes where the cached data came from, e.g. "Sheet1!A1:D20"
+	// or the name of a defined range. Empty if unknown.
+	Source string
+
+	// Fields lists the names of all cache fields, including any calculated
+	// or grouping fields which do not have values in the records.
+	Fields []string
+	// Items contains the shared items for each field (may be nil).
+	Items [][]interface{}
+
+	// Records contains the cached source data, with the names of the record
+	// fields in the first row and shared items resolved to their values.
+	Records *Sheet
+}
+
+// PivotTable describes the layout of a pivot table on a sheet.
+type PivotTable struct {
+	Name string
+	// CacheID identifies the PivotCache holding the table's source data.
+	CacheID int
+	// Location is the output area of the table (excluding page fields).
+	Location CellRange
+
+	// RowFields, ColFields, and PageFields list the names of the fields on
+	// each axis in display order. The pseudo-field "Values" marks the
+	// position of the data fields when there is more than one.
+	RowFields  []string
+	ColFields  []string
+	PageFields []string
+
+	DataFields []PivotDataField
+}
+
+// PivotDataField is a summarized field in the data area of a pivot table.
+type PivotDataField struct {
+	// Name is the display name, e.g. "Sum of Amount".
+	Name string
+	// Field is the name of the source field being summarized.
+	Field string
+	// Function is the aggregation function, one of: sum, count, average,
+	// max, min, product, countNums, stdDev, stdDevp, var, varp.
+	Function string
+}
+
+// PivotValuesField is the name of the pseudo-field for the data fields.
+const PivotValuesField = "Values"
+
+// PivotFunctions lists the aggregation functions by their binary index.
+var PivotFunctions = []string{"sum", "count", "average", "max", "min",
+	"product", "countNums", "stdDev", "stdDevp", "var", "varp"}
+
+// FieldName returns the name of the cache field at index i, or a
+// placeholder if the field is unknown.
+func (c *PivotCache) FieldName(i int) string {
+	if i == -2 {
+		return PivotValuesField
+	}
+	if c != nil && i >= 0 && i < len(c.Fields) {
+		return c.Fields[i]
+	}
+	return "Field" + strconv.Itoa(i+1)
+}
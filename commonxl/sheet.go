@@ -0,0 +1,226 @@
+package commonxl
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pbnjay/grate"
+)
+
+type mergeMarker rune
+
+const (
+	// marks a continuation column within a merged cell.
+	continueColumnMerged mergeMarker = '→'
+	// marks the last column of a merged cell.
+	endColumnMerged mergeMarker = '⇥'
+
+	// marks a continuation row within a merged cell.
+	continueRowMerged mergeMarker = '↓'
+	// marks the last row of a merged cell.
+	endRowMerged mergeMarker = '⤓'
+)
+
+func (m mergeMarker) String() string {
+	return string([]rune{rune(m)})
+}
+
+// Sheet holds an in-memory grid of cell values, and implements the
+// grate.Collection interface. Each value must be one of: nil, bool, int,
+// float64, string, or time.Time.
+//
+// The zero value is an empty sheet ready to use.
+type Sheet struct {
+	rows  [][]interface{}
+	ncols int
+
+	// iterRow is the 1-based index of the current row (0 = before the first)
+	iterRow int
+	err     error
+}
+
+// Resize ensures the sheet has at least the given number of rows and columns.
+func (s *Sheet) Resize(rows, cols int) {
+	if cols > s.ncols {
+		for i, r := range s.rows {
+			s.rows[i] = append(r, make([]interface{}, cols-len(r))...)
+		}
+		s.ncols = cols
+	}
+	for len(s.rows) < rows {
+		s.rows = append(s.rows, make([]interface{}, s.ncols))
+	}
+}
+
+// Put places a value in the (0-based) row and column, growing the sheet as needed.
+func (s *Sheet) Put(row, col int, value interface{}) {
+	if row < 0 || col < 0 {
+		return
+	}
+	s.Resize(row+1, col+1)
+	s.rows[row][col] = value
+}
+
+// Get returns the value at the (0-based) row and column.
+func (s *Sheet) Get(row, col int) interface{} {
+	if row < 0 || col < 0 || row >= len(s.rows) || col >= s.ncols {
+		return nil
+	}
+	return s.rows[row][col]
+}
+
+// Append adds a new row of values to the end of the sheet.
+func (s *Sheet) Append(values ...interface{}) {
+	row := len(s.rows)
+	s.Resize(row+1, len(values))
+	copy(s.rows[row], values)
+}
+
+// Merge marks the cells within the range as merged into the first cell,
+// using the same continuation markers as the xls and xlsx sheets.
+func (s *Sheet) Merge(r CellRange) {
+	for row := r.FirstRow; row <= r.LastRow; row++ {
+		for col := r.FirstCol; col <= r.LastCol; col++ {
+			if row == r.FirstRow && col == r.FirstCol {
+				// has data already!
+			} else if col == r.FirstCol {
+				// first and last column MAY be the same
+				if row == r.LastRow {
+					s.Put(row, col, endRowMerged)
+				} else {
+					s.Put(row, col, continueRowMerged)
+				}
+			} else if col == r.LastCol {
+				// first and last column are NOT the same
+				s.Put(row, col, endColumnMerged)
+			} else {
+				s.Put(row, col, continueColumnMerged)
+			}
+		}
+	}
+}
+
+// NumRows returns the number of rows in the sheet.
+func (s *Sheet) NumRows() int {
+	return len(s.rows)
+}
+
+// NumCols returns the number of columns in the sheet.
+func (s *Sheet) NumCols() int {
+	return s.ncols
+}
+
+// Rewind resets the iterator to before the first row.
+func (s *Sheet) Rewind() {
+	s.iterRow = 0
+}
+
+// SetErr sets the error returned by Err.
+func (s *Sheet) SetErr(err error) {
+	s.err = err
+}
+
+// Next advances to the next row of content.
+// It MUST be called prior to any Scan().
+func (s *Sheet) Next() bool {
+	s.iterRow++
+	return s.iterRow <= len(s.rows)
+}
+
+// Strings returns the contents of the row as string types.
+func (s *Sheet) Strings() []string {
+	currow := s.rows[s.iterRow-1]
+	res := make([]string, len(currow))
+	for i, col := range currow {
+		res[i] = valueString(col)
+	}
+	return res
+}
+
+func valueString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case time.Time:
+		if x.Hour() == 0 && x.Minute() == 0 && x.Second() == 0 && x.Nanosecond() == 0 {
+			return x.Format("2006-01-02")
+		}
+		return x.Format("2006-01-02 15:04:05")
+	case fmt.Stringer:
+		return x.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// Scan extracts values from the row into the provided arguments
+// Arguments must be pointers to one of 5 supported types:
+//     bool, int, float64, string, or time.Time
+// Numeric and boolean values are converted between each other as needed.
+func (s *Sheet) Scan(args ...interface{}) error {
+	currow := s.rows[s.iterRow-1]
+
+	for i, a := range args {
+		var val interface{}
+		if i < len(currow) {
+			val = currow[i]
+		}
+		if _, ok := val.(mergeMarker); ok {
+			val = nil
+		}
+
+		switch v := a.(type) {
+		case *bool:
+			switch x := val.(type) {
+			case bool:
+				*v = x
+			case string:
+				*v, _ = strconv.ParseBool(x)
+			default:
+				f, _ := convertToFloat64(val)
+				*v = f != 0
+			}
+		case *int:
+			if x, ok := val.(int); ok {
+				*v = x
+			} else {
+				n, _ := convertToInt64(val)
+				*v = int(n)
+			}
+		case *float64:
+			*v, _ = convertToFloat64(val)
+		case *string:
+			*v = valueString(val)
+		case *time.Time:
+			if x, ok := val.(time.Time); ok {
+				*v = x
+			} else {
+				*v = time.Time{}
+			}
+		default:
+			return grate.ErrInvalidScanType
+		}
+	}
+	return nil
+}
+
+// IsEmpty returns true if there are no data values.
+func (s *Sheet) IsEmpty() bool {
+	for _, r := range s.rows {
+		for _, v := range r {
+			if v != nil && v != "" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Err returns the last error that occured.
+func (s *Sheet) Err() error {
+	return s.err
+}
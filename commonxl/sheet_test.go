@@ -0,0 +1,45 @@
+package commonxl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSheet(t *testing.T) {
+	s := &Sheet{}
+	if !s.IsEmpty() {
+		t.Fatal("new sheet should be empty")
+	}
+	s.Append("a", "b", "c")
+	s.Put(2, 1, 12.5)
+	s.Put(1, 0, true)
+	s.Merge(CellRange{FirstRow: 1, LastRow: 2, FirstCol: 2, LastCol: 3})
+
+	got := []string{}
+	for s.Next() {
+		got = append(got, strings.Join(s.Strings(), "\t"))
+	}
+	want := []string{"a\tb\tc\t", "true\t\t\t⇥", "\t12.5\t⤓\t⇥"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d rows, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+
+	s.Rewind()
+	s.Next()
+	s.Next()
+	s.Next()
+	var n int
+	var f float64
+	var str string
+	if err := s.Scan(&str, &f, &n); err != nil {
+		t.Fatal(err)
+	}
+	if str != "" || f != 12.5 || n != 0 {
+		t.Errorf("unexpected scan results %q %v %v", str, f, n)
+	}
+}
@@ -0,0 +1,205 @@
+// Package dbf reads dBase, FoxPro and compatible .dbf tables, such as the
+// attribute tables of shapefiles, including memo fields stored in .dbt and
+// .fpt files.
+package dbf
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commontext"
+)
+
+var _ = grate.Register("dbf", 4, Open)
+
+// Options configure how tables are read.
+type Options struct {
+	// Deleted includes the records which are marked as deleted.
+	Deleted bool
+
+	// Encoding is the label of the character encoding, or empty to use
+	// the encoding of the table.
+	Encoding string
+}
+
+// Document contains a .dbf table, or the tables of a zipped shapefile.
+type Document struct {
+	filename string
+	tables   []*Table
+	closers  []io.Closer
+}
+
+// Open a .dbf table, or a zip file containing .dbf tables.
+func Open(filename string) (grate.Source, error) {
+	return OpenWithOptions(filename, Options{})
+}
+
+// OpenWithOptions opens a .dbf table, or a zip file containing .dbf
+// tables, with the given options.
+func OpenWithOptions(filename string, opts Options) (grate.Source, error) {
+	var enc *commontext.Encoding
+	if opts.Encoding != "" {
+		if enc = commontext.LookupEncoding(opts.Encoding); enc == nil {
+			return nil, errors.New("dbf: unsupported encoding " + opts.Encoding)
+		}
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	var magic [4]byte
+	f.ReadAt(magic[:], 0)
+	if string(magic[:]) == "PK\x03\x04" {
+		defer f.Close()
+		return openZip(filename, f, info.Size(), opts, enc)
+	}
+
+	d := &Document{filename: filename, closers: []io.Closer{f}}
+	t, err := newTable(filepath.Base(filename), f, info.Size(), opts, enc)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	d.tables = append(d.tables, t)
+
+	// find the memo and code page files beside the table
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	if t.hasMemos() {
+		for _, ext := range memoExts(t.h) {
+			mf, err := os.Open(base + ext)
+			if err != nil {
+				continue
+			}
+			d.closers = append(d.closers, mf)
+			if info, err := mf.Stat(); err == nil {
+				t.memo, _ = newMemoFile(mf, info.Size(), ext[1] == 'f' || ext[1] == 'F')
+			}
+			break
+		}
+	}
+	if t.enc == nil {
+		for _, ext := range []string{".cpg", ".CPG"} {
+			if label, err := ioutil.ReadFile(base + ext); err == nil {
+				t.enc = cpgEncoding(string(label))
+				break
+			}
+		}
+	}
+	t.detectEncoding()
+	return d, nil
+}
+
+// openZip opens the tables of a zip file, such as a shapefile.
+func openZip(filename string, r io.ReaderAt, size int64, opts Options, enc *commontext.Encoding) (grate.Source, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+	}
+	files := make(map[string]*zip.File)
+	for _, zf := range z.File {
+		files[strings.ToLower(zf.Name)] = zf
+	}
+	readFile := func(name string) ([]byte, bool) {
+		zf, ok := files[strings.ToLower(name)]
+		if !ok {
+			return nil, false
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, false
+		}
+		defer rc.Close()
+		data, err := ioutil.ReadAll(rc)
+		return data, err == nil
+	}
+
+	d := &Document{filename: filename}
+	for _, zf := range z.File {
+		if !strings.EqualFold(path.Ext(zf.Name), ".dbf") {
+			continue
+		}
+		data, ok := readFile(zf.Name)
+		if !ok {
+			continue
+		}
+		t, err := newTable(path.Base(zf.Name), bytes.NewReader(data), int64(len(data)), opts, enc)
+		if err != nil {
+			continue
+		}
+		base := strings.TrimSuffix(zf.Name, path.Ext(zf.Name))
+		if t.hasMemos() {
+			for _, ext := range memoExts(t.h)[:1] {
+				if memo, ok := readFile(base + ext); ok {
+					t.memo, _ = newMemoFile(bytes.NewReader(memo), int64(len(memo)), ext == ".fpt")
+				}
+			}
+		}
+		if t.enc == nil {
+			if label, ok := readFile(base + ".cpg"); ok {
+				t.enc = cpgEncoding(string(label))
+			}
+		}
+		t.detectEncoding()
+		d.tables = append(d.tables, t)
+	}
+	if len(d.tables) == 0 {
+		return nil, grate.ErrNotInFormat
+	}
+	return d, nil
+}
+
+// memoExts returns the possible extensions of the memo file of a table.
+func memoExts(h *header) []string {
+	switch h.version {
+	case 0x30, 0x31, 0x32, 0xF5, 0xFB:
+		return []string{".fpt", ".FPT"}
+	}
+	return []string{".dbt", ".DBT"}
+}
+
+// List returns the names of the tables.
+func (d *Document) List() ([]string, error) {
+	res := make([]string, len(d.tables))
+	for i, t := range d.tables {
+		res[i] = t.name
+	}
+	return res, nil
+}
+
+// Get the named table.
+func (d *Document) Get(name string) (grate.Collection, error) {
+	for _, t := range d.tables {
+		if t.name == name {
+			t.Rewind()
+			return t, nil
+		}
+	}
+	return nil, errors.New("dbf: table not found")
+}
+
+// Close the files of the document.
+func (d *Document) Close() error {
+	var err error
+	for _, c := range d.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	d.closers = nil
+	d.tables = nil
+	return err
+}
@@ -0,0 +1,209 @@
+package dbf
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pbnjay/grate"
+)
+
+// buildDBF returns a dBase III table with the given fields and records,
+// where each record starts with its deleted flag.
+func buildDBF(language byte, fields []Field, records []string) []byte {
+	var b bytes.Buffer
+	recordLen := 1
+	for _, f := range fields {
+		recordLen += f.Length
+	}
+	head := make([]byte, 32)
+	head[0] = 0x83
+	head[1], head[2], head[3] = 121, 6, 15
+	binary.LittleEndian.PutUint32(head[4:], uint32(len(records)))
+	binary.LittleEndian.PutUint16(head[8:], uint16(32+32*len(fields)+1))
+	binary.LittleEndian.PutUint16(head[10:], uint16(recordLen))
+	head[29] = language
+	b.Write(head)
+	for _, f := range fields {
+		d := make([]byte, 32)
+		copy(d, f.Name)
+		d[11] = f.Type
+		d[16] = byte(f.Length)
+		d[17] = byte(f.Decimals)
+		b.Write(d)
+	}
+	b.WriteByte(0x0D)
+	for _, r := range records {
+		b.WriteString(r)
+	}
+	b.WriteByte(0x1A)
+	return b.Bytes()
+}
+
+// buildDBT returns a dBase III memo file with one memo per block.
+func buildDBT(memos ...string) []byte {
+	res := make([]byte, 512*(len(memos)+1))
+	binary.LittleEndian.PutUint32(res, uint32(len(memos)+1))
+	for i, m := range memos {
+		copy(res[512*(i+1):], m+"\x1a\x1a")
+	}
+	return res
+}
+
+var testFields = []Field{
+	{Name: "NAME", Type: 'C', Length: 10},
+	{Name: "AMOUNT", Type: 'N', Length: 8, Decimals: 2},
+	{Name: "BORN", Type: 'D', Length: 8},
+	{Name: "ACTIVE", Type: 'L', Length: 1},
+	{Name: "NOTES", Type: 'M', Length: 10},
+}
+
+var testRecords = []string{
+	fmt.Sprintf(" %-10s%8s%8s%s%10s", "Ann", "12.50", "19800102", "T", "1"),
+	fmt.Sprintf("*%-10s%8s%8s%s%10s", "Bob", "3.00", "19751231", "F", ""),
+	fmt.Sprintf(" %-10s%8s%8s%s%10s", "Cl\x82o", "-0.25", "", "?", "2"),
+}
+
+func readAll(t *testing.T, c grate.Collection) [][]string {
+	t.Helper()
+	var res [][]string
+	for c.Next() {
+		res = append(res, c.Strings())
+	}
+	if err := c.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestTable(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "people.dbf")
+	if err := os.WriteFile(fn, buildDBF(0x01, testFields, testRecords), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "people.dbt"), buildDBT("first memo", "second\r\nmemo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Open(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	names, _ := src.List()
+	if !reflect.DeepEqual(names, []string{"people.dbf"}) {
+		t.Fatalf("got tables %q", names)
+	}
+	c, err := src.Get(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"NAME", "AMOUNT", "BORN", "ACTIVE", "NOTES"},
+		{"Ann", "12.5", "1980-01-02", "true", "first memo"},
+		{"Cléo", "-0.25", "", "", "second\r\nmemo"},
+	}
+	if got := readAll(t, c); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	c.(*Table).Rewind()
+	c.Next()
+	c.Next()
+	var (
+		name   string
+		amount float64
+		born   time.Time
+		active bool
+		notes  string
+	)
+	if err := c.Scan(&name, &amount, &born, &active, &notes); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Ann" || amount != 12.5 || !born.Equal(time.Date(1980, 1, 2, 0, 0, 0, 0, time.UTC)) || !active {
+		t.Errorf("scanned %q %v %v %v", name, amount, born, active)
+	}
+}
+
+func TestDeleted(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "people.dbf")
+	if err := os.WriteFile(fn, buildDBF(0x01, testFields, testRecords), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src, err := OpenWithOptions(fn, Options{Deleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	c, _ := src.Get("people.dbf")
+	var deleted []bool
+	for c.Next() {
+		deleted = append(deleted, c.(*Table).Deleted())
+	}
+	if want := []bool{false, false, true, false}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("got deleted flags %v, want %v", deleted, want)
+	}
+}
+
+func TestZip(t *testing.T) {
+	fields := []Field{
+		{Name: "ID", Type: 'N', Length: 4},
+		{Name: "CITY", Type: 'C', Length: 8},
+	}
+	records := []string{" " + "   1" + "Zürich ", " " + "   2" + "Genève "}
+
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for name, data := range map[string][]byte{
+		"cities/cities.shp": {0, 0, 0x27, 0x0a},
+		"cities/cities.dbf": buildDBF(0, fields, records),
+		"cities/cities.cpg": []byte("UTF-8\n"),
+	} {
+		w, _ := zw.Create(name)
+		w.Write(data)
+	}
+	zw.Close()
+	fn := filepath.Join(t.TempDir(), "cities.zip")
+	if err := os.WriteFile(fn, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Open(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	c, err := src.Get("cities.dbf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"ID", "CITY"}, {"1", "Zürich"}, {"2", "Genève"}}
+	if got := readAll(t, c); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"text.dbf":  "name,amount\nAnn,12.50\n",
+		"short.dbf": "\x03\x79\x06",
+		"empty.zip": "PK\x05\x06" + string(make([]byte, 18)),
+	} {
+		fn := filepath.Join(dir, name)
+		if err := os.WriteFile(fn, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open(fn); !errors.Is(err, grate.ErrNotInFormat) {
+			t.Errorf("%s: got error %v, want ErrNotInFormat", name, err)
+		}
+	}
+}
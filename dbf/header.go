@@ -0,0 +1,191 @@
+package dbf
+
+import (
+	"encoding/binary"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commontext"
+)
+
+// Field describes a column of a table.
+type Field struct {
+	Name     string
+	Type     byte // e.g. 'C' for character or 'N' for numeric
+	Length   int
+	Decimals int
+
+	offset int // within a record, after the deleted flag
+}
+
+// versions of dBase, FoxPro and compatible tables
+var versions = map[byte]string{
+	0x02: "FoxBASE",
+	0x03: "dBase III",
+	0x04: "dBase IV",
+	0x05: "dBase V",
+	0x30: "Visual FoxPro",
+	0x31: "Visual FoxPro",
+	0x32: "Visual FoxPro",
+	0x43: "dBase IV",
+	0x63: "dBase IV",
+	0x83: "dBase III",
+	0x8B: "dBase IV",
+	0x8E: "dBase IV",
+	0xCB: "dBase IV",
+	0xE5: "HiPer-Six",
+	0xF5: "FoxPro",
+	0xFB: "FoxBASE",
+}
+
+// field types which are read
+const fieldTypes = "CNFDLMIBTYGP0"
+
+type header struct {
+	version    byte
+	numRecords int
+	headerLen  int
+	recordLen  int
+	language   byte
+	fields     []Field
+}
+
+func (h *header) visualFoxPro() bool {
+	return h.version == 0x30 || h.version == 0x31 || h.version == 0x32
+}
+
+// parseHeader reads and validates the header and field descriptors of a
+// table of the given size.
+func parseHeader(r io.ReaderAt, size int64) (*header, error) {
+	var buf [32]byte
+	if _, err := r.ReadAt(buf[:], 0); err != nil {
+		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+	}
+	h := &header{
+		version:    buf[0],
+		numRecords: int(binary.LittleEndian.Uint32(buf[4:])),
+		headerLen:  int(binary.LittleEndian.Uint16(buf[8:])),
+		recordLen:  int(binary.LittleEndian.Uint16(buf[10:])),
+		language:   buf[29],
+	}
+	if _, ok := versions[h.version]; !ok || buf[2] > 12 || buf[3] > 31 {
+		return nil, grate.ErrNotInFormat
+	}
+	if h.headerLen < 33 || h.recordLen < 2 || int64(h.headerLen) > size {
+		return nil, grate.ErrNotInFormat
+	}
+
+	desc := make([]byte, h.headerLen-32)
+	if _, err := r.ReadAt(desc, 32); err != nil {
+		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+	}
+	offset := 0
+	for i := 0; ; i += 32 {
+		if i < len(desc) && desc[i] == 0x0D {
+			break
+		}
+		if i+32 > len(desc) {
+			return nil, grate.ErrNotInFormat
+		}
+		d := desc[i : i+32]
+		f := Field{
+			Name:     strings.TrimRight(string(d[:11]), "\x00 "),
+			Type:     d[11],
+			Length:   int(d[16]),
+			Decimals: int(d[17]),
+		}
+		if n := strings.IndexByte(f.Name, 0); n >= 0 {
+			f.Name = f.Name[:n]
+		}
+		if f.Name == "" || f.Length == 0 {
+			return nil, grate.ErrNotInFormat
+		}
+		if f.Type == 'C' && f.Decimals > 0 && h.recordLen > 256 {
+			// Clipper and FoxPro store long character fields with the
+			// decimal count as the high byte of the length
+			if long := f.Length + f.Decimals*256; 1+offset+long <= h.recordLen {
+				f.Length, f.Decimals = long, 0
+			}
+		}
+		if !strings.ContainsRune(fieldTypes, rune(f.Type)) {
+			// unknown types are returned as text
+			f.Type = 'C'
+		}
+		f.offset = offset
+		offset += f.Length
+		h.fields = append(h.fields, f)
+	}
+	if len(h.fields) == 0 || 1+offset > h.recordLen {
+		return nil, grate.ErrNotInFormat
+	}
+
+	if avail := (size - int64(h.headerLen)) / int64(h.recordLen); int64(h.numRecords) > avail {
+		// truncated file
+		h.numRecords = int(avail)
+	}
+	return h, nil
+}
+
+// languageDrivers maps the language driver IDs of tables to encodings.
+var languageDrivers = map[byte]*commontext.Encoding{
+	0x01: commontext.IBM437,
+	0x02: commontext.IBM850,
+	0x03: commontext.Windows1252,
+	0x09: commontext.IBM437,
+	0x0A: commontext.IBM850,
+	0x0B: commontext.IBM437,
+	0x0D: commontext.IBM437,
+	0x0E: commontext.IBM850,
+	0x0F: commontext.IBM437,
+	0x10: commontext.IBM850,
+	0x11: commontext.IBM437,
+	0x12: commontext.IBM850,
+	0x14: commontext.IBM850,
+	0x15: commontext.IBM437,
+	0x16: commontext.IBM850,
+	0x17: commontext.IBM850,
+	0x18: commontext.IBM437,
+	0x19: commontext.IBM437,
+	0x1A: commontext.IBM850,
+	0x1B: commontext.IBM437,
+	0x1F: commontext.IBM852,
+	0x22: commontext.IBM852,
+	0x23: commontext.IBM852,
+	0x25: commontext.IBM850,
+	0x26: commontext.IBM866,
+	0x37: commontext.IBM850,
+	0x40: commontext.IBM852,
+	0x57: commontext.Windows1252,
+	0x58: commontext.Windows1252,
+	0x59: commontext.Windows1252,
+	0x64: commontext.IBM852,
+	0x65: commontext.IBM866,
+	0x7D: commontext.Windows1255,
+	0x7E: commontext.Windows1256,
+	0x87: commontext.IBM852,
+	0xC8: commontext.Windows1250,
+	0xC9: commontext.Windows1251,
+	0xCA: commontext.Windows1254,
+	0xCB: commontext.Windows1253,
+	0xCC: commontext.Windows1257,
+}
+
+// cpgEncoding returns the encoding named in the .cpg file of a shapefile,
+// such as "UTF-8", "1252" or "ISO-8859-1".
+func cpgEncoding(label string) *commontext.Encoding {
+	label = strings.TrimSpace(label)
+	if enc := commontext.LookupEncoding(label); enc != nil {
+		return enc
+	}
+	switch n, _ := strconv.Atoi(label); {
+	case n == 65001:
+		return commontext.UTF8
+	case n == 88591:
+		return commontext.Windows1252
+	case n >= 1250 && n <= 1258:
+		return commontext.LookupEncoding("windows-" + label)
+	}
+	return nil
+}
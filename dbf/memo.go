@@ -0,0 +1,84 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// maxMemo limits the length of a memo.
+const maxMemo = 16 << 20
+
+// memoFile reads the memos of a table from a .dbt or .fpt file.
+type memoFile struct {
+	r         io.ReaderAt
+	size      int64
+	blockSize int64
+	foxPro    bool
+}
+
+func newMemoFile(r io.ReaderAt, size int64, foxPro bool) (*memoFile, error) {
+	var buf [32]byte
+	if _, err := r.ReadAt(buf[:], 0); err != nil {
+		return nil, err
+	}
+	m := &memoFile{r: r, size: size, foxPro: foxPro, blockSize: 512}
+	if foxPro {
+		m.blockSize = int64(binary.BigEndian.Uint16(buf[6:]))
+	} else if bs := int64(binary.LittleEndian.Uint16(buf[20:])); bs != 0 {
+		// dBase IV
+		m.blockSize = bs
+	}
+	if m.blockSize == 0 {
+		return nil, errors.New("dbf: invalid memo block size")
+	}
+	return m, nil
+}
+
+// read the memo starting at a block.
+func (m *memoFile) read(block int64) ([]byte, error) {
+	pos := block * m.blockSize
+	if block <= 0 || pos >= m.size {
+		return nil, errors.New("dbf: invalid memo block")
+	}
+	var head [8]byte
+	if n, err := m.r.ReadAt(head[:], pos); n == 0 {
+		return nil, err
+	}
+
+	length := int64(-1)
+	switch {
+	case m.foxPro:
+		length = int64(binary.BigEndian.Uint32(head[4:]))
+		pos += 8
+	case head[0] == 0xFF && head[1] == 0xFF && head[2] == 0x08 && head[3] == 0x00:
+		// dBase IV, where the length includes the block header
+		length = int64(binary.LittleEndian.Uint32(head[4:])) - 8
+		pos += 8
+	}
+	if length > maxMemo || pos+length > m.size {
+		return nil, errors.New("dbf: invalid memo length")
+	}
+	if length >= 0 {
+		buf := make([]byte, length)
+		_, err := m.r.ReadAt(buf, pos)
+		return buf, err
+	}
+
+	// dBase III memos end with 0x1A
+	var res []byte
+	buf := make([]byte, m.blockSize)
+	for len(res) < maxMemo && pos < m.size {
+		n, err := m.r.ReadAt(buf, pos)
+		if i := bytes.IndexByte(buf[:n], 0x1A); i >= 0 {
+			return append(res, buf[:i]...), nil
+		}
+		res = append(res, buf[:n]...)
+		if err != nil {
+			break
+		}
+		pos += int64(n)
+	}
+	return res, nil
+}
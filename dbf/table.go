@@ -0,0 +1,269 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbnjay/grate/commontext"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// Table is a .dbf table. The first row holds the field names, and records
+// are read from the file as needed.
+type Table struct {
+	name    string
+	r       io.ReaderAt
+	h       *header
+	fields  []Field // without the hidden _NullFlags field
+	memo    *memoFile
+	enc     *commontext.Encoding
+	deleted bool // include deleted records
+
+	rec     int // index of the next record, or -1 before the field names
+	buf     []byte
+	current bool // the current record is marked as deleted
+	row     commonxl.Sheet
+	err     error
+}
+
+func newTable(name string, r io.ReaderAt, size int64, opts Options, enc *commontext.Encoding) (*Table, error) {
+	h, err := parseHeader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{
+		name:    name,
+		r:       r,
+		h:       h,
+		enc:     enc,
+		deleted: opts.Deleted,
+		rec:     -1,
+		buf:     make([]byte, h.recordLen),
+	}
+	for _, f := range h.fields {
+		if f.Type != '0' {
+			t.fields = append(t.fields, f)
+		}
+	}
+	if t.enc == nil {
+		t.enc = languageDrivers[h.language]
+	}
+	return t, nil
+}
+
+func (t *Table) hasMemos() bool {
+	for _, f := range t.fields {
+		switch f.Type {
+		case 'M', 'G', 'P':
+			return true
+		case 'B':
+			if !t.h.visualFoxPro() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// detectEncoding guesses the encoding from the character fields of the
+// first records, when the table does not declare one.
+func (t *Table) detectEncoding() {
+	if t.enc != nil {
+		return
+	}
+	var sample []byte
+	for i := 0; i < t.h.numRecords && i < 1000 && len(sample) < 64<<10; i++ {
+		if _, err := t.r.ReadAt(t.buf, int64(t.h.headerLen+i*t.h.recordLen)); err != nil {
+			break
+		}
+		for _, f := range t.fields {
+			if f.Type == 'C' {
+				v := bytes.TrimRight(t.buf[1+f.offset:1+f.offset+f.Length], " \x00")
+				sample = append(append(sample, v...), ' ')
+			}
+		}
+	}
+	t.enc = commontext.DetectEncoding(sample)
+}
+
+// Fields returns the fields of the table.
+func (t *Table) Fields() []Field {
+	return append([]Field{}, t.fields...)
+}
+
+// Rewind returns to the field names before the first record.
+func (t *Table) Rewind() {
+	t.rec, t.current, t.err = -1, false, nil
+}
+
+// Next advances to the next record, skipping deleted records unless they
+// are included by the options.
+func (t *Table) Next() bool {
+	if t.err != nil {
+		return false
+	}
+	if t.rec < 0 {
+		names := make([]interface{}, len(t.fields))
+		for i, f := range t.fields {
+			names[i] = f.Name
+		}
+		t.setRow(names)
+		t.rec = 0
+		return true
+	}
+	for t.rec < t.h.numRecords {
+		pos := int64(t.h.headerLen + t.rec*t.h.recordLen)
+		t.rec++
+		if _, err := t.r.ReadAt(t.buf, pos); err != nil {
+			if err != io.EOF {
+				t.err = err
+			}
+			t.rec = t.h.numRecords
+			return false
+		}
+		switch t.buf[0] {
+		case 0x1A:
+			// end of file marker
+			t.rec = t.h.numRecords
+			return false
+		case '*':
+			t.current = true
+			if !t.deleted {
+				continue
+			}
+		default:
+			t.current = false
+		}
+		values := make([]interface{}, len(t.fields))
+		for i, f := range t.fields {
+			values[i] = t.value(f, t.buf[1+f.offset:1+f.offset+f.Length])
+		}
+		t.setRow(values)
+		return true
+	}
+	return false
+}
+
+func (t *Table) setRow(values []interface{}) {
+	t.row = commonxl.Sheet{}
+	t.row.Append(values...)
+	t.row.Next()
+}
+
+// Deleted returns true if the current record is marked as deleted.
+func (t *Table) Deleted() bool {
+	return t.rec > 0 && t.current
+}
+
+// value decodes a field of the current record.
+func (t *Table) value(f Field, b []byte) interface{} {
+	switch f.Type {
+	case 'C':
+		if s := t.enc.Decode(bytes.TrimRight(b, " \x00")); s != "" {
+			return s
+		}
+	case 'N', 'F':
+		s := strings.TrimSpace(string(bytes.TrimRight(b, "\x00")))
+		if s == "" || strings.Trim(s, "*") == "" {
+			return nil
+		}
+		if v, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64); err == nil {
+			return v
+		}
+	case 'D':
+		s := strings.TrimSpace(string(b))
+		if v, err := time.Parse("20060102", s); err == nil {
+			return v
+		}
+	case 'L':
+		switch b[0] {
+		case 'T', 't', 'Y', 'y':
+			return true
+		case 'F', 'f', 'N', 'n':
+			return false
+		}
+	case 'M':
+		if s := t.memoText(f, b); s != "" {
+			return s
+		}
+	case 'B':
+		if t.h.visualFoxPro() && len(b) == 8 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+	case 'I':
+		if len(b) == 4 {
+			return float64(int32(binary.LittleEndian.Uint32(b)))
+		}
+	case 'T':
+		if len(b) == 8 {
+			return julianTime(int32(binary.LittleEndian.Uint32(b)), int32(binary.LittleEndian.Uint32(b[4:])))
+		}
+	case 'Y':
+		if len(b) == 8 {
+			return float64(int64(binary.LittleEndian.Uint64(b))) / 10000
+		}
+	}
+	return nil
+}
+
+// memoText reads the text of a memo field, which holds the number of the
+// first block of the memo.
+func (t *Table) memoText(f Field, b []byte) string {
+	if t.memo == nil {
+		return ""
+	}
+	var block int64
+	if f.Length == 4 {
+		block = int64(binary.LittleEndian.Uint32(b))
+	} else {
+		block, _ = strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	}
+	if block == 0 {
+		return ""
+	}
+	data, err := t.memo.read(block)
+	if err != nil {
+		return ""
+	}
+	return t.enc.Decode(bytes.TrimRight(data, " \x00"))
+}
+
+// julianEpoch is the Julian day number of 1970-01-01.
+const julianEpoch = 2440588
+
+// julianTime converts a Julian day number and milliseconds since midnight
+// to a time, or nil for an empty timestamp.
+func julianTime(day, ms int32) interface{} {
+	if day == 0 {
+		return nil
+	}
+	v := time.Unix(int64(day-julianEpoch)*86400, 0).UTC()
+	return v.Add(time.Duration(ms) * time.Millisecond)
+}
+
+// Strings extracts values from the current record into a list of strings.
+func (t *Table) Strings() []string {
+	return t.row.Strings()
+}
+
+// Scan extracts values from the current record into the provided arguments
+// Arguments must be pointers to one of 5 supported types:
+//     bool, int, float64, string, or time.Time
+func (t *Table) Scan(args ...interface{}) error {
+	return t.row.Scan(args...)
+}
+
+// IsEmpty returns true if there are no records.
+func (t *Table) IsEmpty() bool {
+	return t.h.numRecords == 0
+}
+
+// Err returns the last error that occured.
+func (t *Table) Err() error {
+	return t.err
+}
@@ -0,0 +1,232 @@
+// Package dif reads Data Interchange Format (DIF) files, a text format for
+// tables written by VisiCalc, Lotus 1-2-3, Excel and instrument software.
+package dif
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commontext"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+var _ = grate.Register("dif", 14, Open)
+
+// limits on the cells of a table
+const (
+	maxRows = 1 << 20
+	maxCols = 1 << 14
+)
+
+// Document contains the single table of a DIF file. Vectors are the
+// columns of the table and tuples are its rows.
+type Document struct {
+	filename string
+	title    string
+	vectors  int
+	tuples   int
+	labels   map[int]string
+	sheet    *commonxl.Sheet
+}
+
+// Open a DIF file.
+func Open(filename string) (grate.Source, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	head, _ := br.Peek(32)
+	if enc, n := commontext.DetectBOM(head); enc == commontext.UTF8 {
+		head = head[n:]
+		br.Discard(n)
+	}
+	if !isDIF(head) {
+		return nil, grate.ErrNotInFormat
+	}
+	data, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Document{
+		filename: filename,
+		labels:   make(map[int]string),
+		sheet:    &commonxl.Sheet{},
+	}
+	text := commontext.DetectEncoding(data).Decode(data)
+	if err := d.parse(strings.Split(text, "\n")); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// isDIF reports whether a file starts with the TABLE header item.
+func isDIF(head []byte) bool {
+	lines := bytes.SplitN(head, []byte("\n"), 3)
+	if len(lines) < 3 || string(bytes.TrimSpace(lines[0])) != "TABLE" {
+		return false
+	}
+	_, _, ok := parsePair(string(lines[1]))
+	return ok
+}
+
+// parsePair parses a line with a number pair, such as "0,1".
+func parsePair(line string) (int, string, bool) {
+	i := strings.IndexByte(line, ',')
+	if i < 0 {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[:i]))
+	if err != nil {
+		return 0, "", false
+	}
+	return n, strings.TrimSpace(line[i+1:]), true
+}
+
+// parse the header items, which are a topic, a number pair and a string,
+// and then the data values, which are a number pair and a string.
+func (d *Document) parse(lines []string) error {
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+
+	i := 0
+	for topic := ""; topic != "DATA"; {
+		if i+2 >= len(lines) {
+			return errors.New("dif: missing DATA header item")
+		}
+		topic = strings.ToUpper(strings.TrimSpace(lines[i]))
+		n, v, ok := parsePair(lines[i+1])
+		if !ok {
+			return errors.New("dif: invalid header item " + topic)
+		}
+		s := unquote(lines[i+2])
+		i += 3
+		switch topic {
+		case "TABLE":
+			d.title = s
+		case "VECTORS":
+			d.vectors, _ = strconv.Atoi(v)
+		case "TUPLES":
+			d.tuples, _ = strconv.Atoi(v)
+		case "LABEL":
+			// the first line of the label of a vector
+			if _, ok := d.labels[n]; !ok && n > 0 && s != "" {
+				d.labels[n] = s
+			}
+		}
+	}
+
+	first := 0
+	cols := d.vectors
+	if cols > maxCols || cols < 0 {
+		cols = maxCols
+	}
+	if len(d.labels) > 0 {
+		first = 1
+		for n, label := range d.labels {
+			if n <= maxCols {
+				d.sheet.Put(0, n-1, label)
+			}
+		}
+	}
+	d.sheet.Resize(first, cols)
+
+	row, col := first-1, 0
+	for ; i+1 < len(lines); i += 2 {
+		kind, v, ok := parsePair(lines[i])
+		if !ok {
+			return errors.New("dif: invalid data value on line " + strconv.Itoa(i+1))
+		}
+		s := strings.TrimSpace(lines[i+1])
+		var value interface{}
+		switch kind {
+		case -1:
+			switch strings.ToUpper(s) {
+			case "BOT":
+				row++
+				col = 0
+				if row < maxRows {
+					d.sheet.Resize(row+1, cols)
+				}
+			case "EOD":
+				return nil
+			}
+			continue
+		case 0:
+			switch strings.ToUpper(s) {
+			case "V":
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					value = f
+				}
+			case "TRUE":
+				value = true
+			case "FALSE":
+				value = false
+			}
+			// NA and ERROR values are empty
+		case 1:
+			if s = unquote(s); s != "" {
+				value = s
+			}
+		}
+		if row >= first && row < maxRows && col < maxCols {
+			d.sheet.Put(row, col, value)
+		}
+		col++
+	}
+	return nil
+}
+
+// unquote removes the quotes around a string, where a doubled quote is a
+// quote within the string.
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = strings.ReplaceAll(s[1:len(s)-1], `""`, `"`)
+	}
+	return s
+}
+
+// Title returns the title of the table, from the TABLE header item.
+func (d *Document) Title() string {
+	return d.title
+}
+
+// Vectors returns the number of vectors (columns) declared by the header.
+func (d *Document) Vectors() int {
+	return d.vectors
+}
+
+// Tuples returns the number of tuples (rows) declared by the header.
+func (d *Document) Tuples() int {
+	return d.tuples
+}
+
+// List returns the name of the single table in the file.
+func (d *Document) List() ([]string, error) {
+	return []string{filepath.Base(d.filename)}, nil
+}
+
+// Get the table of the file.
+func (d *Document) Get(name string) (grate.Collection, error) {
+	if name != filepath.Base(d.filename) {
+		return nil, errors.New("dif: table not found")
+	}
+	d.sheet.Rewind()
+	return d.sheet, nil
+}
+
+// Close the document.
+func (d *Document) Close() error {
+	return nil
+}
@@ -0,0 +1,82 @@
+package dif
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pbnjay/grate"
+)
+
+func writeTestFile(t *testing.T, name string, lines ...string) string {
+	t.Helper()
+	fn := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fn, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func readAll(t *testing.T, src grate.Source, name string) [][]string {
+	t.Helper()
+	c, err := src.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res [][]string
+	for c.Next() {
+		res = append(res, c.Strings())
+	}
+	return res
+}
+
+func TestTable(t *testing.T) {
+	fn := writeTestFile(t, "readings.dif",
+		"TABLE", "0,1", `"Readings"`,
+		"VECTORS", "0,3", `""`,
+		"TUPLES", "0,3", `""`,
+		"LABEL", "1,0", `"Sample"`,
+		"LABEL", "3,0", `"Valid"`,
+		"COMMENT", "0,0", `"exported by the analyzer"`,
+		"DATA", "0,0", `""`,
+		"-1,0", "BOT",
+		"1,0", `"A ""1"""`,
+		"0,1.25E+2", "V",
+		"0,1", "TRUE",
+		"-1,0", "BOT",
+		"1,0", `"B2"`,
+		"0,0", "NA",
+		"0,0", "FALSE",
+		"-1,0", "BOT",
+		"1,0", "C3",
+		"-1,0", "EOD",
+	)
+	src, err := Open(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	d := src.(*Document)
+	if d.Title() != "Readings" || d.Vectors() != 3 || d.Tuples() != 3 {
+		t.Errorf("got title %q, %d vectors and %d tuples", d.Title(), d.Vectors(), d.Tuples())
+	}
+	want := [][]string{
+		{"Sample", "", "Valid"},
+		{`A "1"`, "125", "true"},
+		{"B2", "", "false"},
+		{"C3", "", ""},
+	}
+	if got := readAll(t, src, "readings.dif"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	fn := writeTestFile(t, "text.dif", "TABLE", "sample,value", "A,1")
+	if _, err := Open(fn); !errors.Is(err, grate.ErrNotInFormat) {
+		t.Errorf("got error %v, want ErrNotInFormat", err)
+	}
+}
@@ -0,0 +1,232 @@
+// Package docx reads the tables of Word (.docx) documents, such as
+// specifications and invoices. Each table, including each table nested
+// within a cell, is a separate collection.
+package docx
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/xlsx"
+)
+
+var _ = grate.Register("docx", 11, Open)
+
+// content types of the main part of Word documents and templates, with
+// and without macros
+var documentContentTypes = []string{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml",
+	"application/vnd.ms-word.document.macroEnabled.main+xml",
+	"application/vnd.ms-word.template.macroEnabledTemplate.main+xml",
+}
+
+// Document contains the tables of a Word document.
+type Document struct {
+	*xlsx.Package
+
+	tables []*table
+}
+
+// Open a Word document, and parse all of its tables.
+func Open(filename string) (grate.Source, error) {
+	p, err := xlsx.OpenPackage(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !isDocumentPart(p) {
+		p.Close()
+		return nil, grate.ErrNotInFormat
+	}
+	d := &Document{Package: p}
+	dec, c, err := p.OpenXML(p.PrimaryDoc())
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	err = d.parse(dec)
+	c.Close()
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func isDocumentPart(p *xlsx.Package) bool {
+	ct := p.ContentType(p.PrimaryDoc())
+	for _, dct := range documentContentTypes {
+		if strings.EqualFold(ct, dct) {
+			return true
+		}
+	}
+	return ct == "" && strings.HasSuffix(p.PrimaryDoc(), "document.xml")
+}
+
+// parse the tables of the document body, including tables nested within
+// cells.
+func (d *Document) parse(dec *xml.Decoder) error {
+	var stack []*table
+	inText := false
+	skip := 0 // depth within fallback content
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		var cur *table
+		if len(stack) > 0 {
+			cur = stack[len(stack)-1]
+		}
+		switch v := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 || v.Name.Local == "Fallback" {
+				// alternate content repeats the text of its choice
+				skip++
+				continue
+			}
+			if v.Name.Space != "w" && v.Name.Space != "" {
+				continue
+			}
+			switch v.Name.Local {
+			case "tbl":
+				t := newTable()
+				d.tables = append(d.tables, t)
+				stack = append(stack, t)
+			case "tblCaption":
+				if cur != nil {
+					cur.caption = attr(v, "val")
+				}
+			case "tr":
+				if cur != nil {
+					cur.startRow()
+				}
+			case "gridBefore":
+				if cur != nil && !cur.inCell {
+					cur.col += spanValue(attr(v, "val"))
+				}
+			case "tc":
+				if cur != nil {
+					cur.startCell()
+				}
+			case "gridSpan":
+				if cur != nil && cur.inCell {
+					cur.span = spanValue(attr(v, "val"))
+				}
+			case "vMerge":
+				if cur != nil && cur.inCell {
+					cur.vMerge = mergeContinue
+					if attr(v, "val") == "restart" {
+						cur.vMerge = mergeRestart
+					}
+				}
+			case "t":
+				inText = true
+			case "tab":
+				// rather than a tab stop, which has a position
+				if cur != nil && attr(v, "pos") == "" {
+					cur.writeText("\t")
+				}
+			case "br", "cr":
+				if cur != nil {
+					cur.writeText("\n")
+				}
+			}
+
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if v.Name.Space != "w" && v.Name.Space != "" {
+				continue
+			}
+			switch v.Name.Local {
+			case "tbl":
+				if cur != nil {
+					cur.finish()
+					stack = stack[:len(stack)-1]
+				}
+			case "tc":
+				if cur != nil {
+					cur.endCell()
+				}
+			case "p":
+				if cur != nil {
+					cur.endParagraph()
+				}
+			case "t":
+				inText = false
+			}
+
+		case xml.CharData:
+			if inText && skip == 0 && cur != nil {
+				cur.writeText(string(v))
+			}
+		}
+	}
+	if err != io.EOF {
+		return err
+	}
+	for len(stack) > 0 {
+		stack[len(stack)-1].finish()
+		stack = stack[:len(stack)-1]
+	}
+	d.nameTables()
+	return nil
+}
+
+// attr returns the value of the attribute with the local name.
+func attr(v xml.StartElement, name string) string {
+	for _, a := range v.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// nameTables names each table by its caption or position, ensuring that
+// every name is unique.
+func (d *Document) nameTables() {
+	seen := make(map[string]bool)
+	for i, t := range d.tables {
+		name := strings.TrimSpace(t.caption)
+		if name == "" {
+			name = "Table" + strconv.Itoa(i+1)
+		}
+		base := name
+		for n := 2; seen[name]; n++ {
+			name = base + " (" + strconv.Itoa(n) + ")"
+		}
+		seen[name] = true
+		t.name = name
+	}
+}
+
+// Close the document.
+func (d *Document) Close() error {
+	d.tables = nil
+	return d.Package.Close()
+}
+
+// List returns the names of the tables in the document.
+func (d *Document) List() ([]string, error) {
+	res := make([]string, 0, len(d.tables))
+	for _, t := range d.tables {
+		res = append(res, t.name)
+	}
+	return res, nil
+}
+
+// Get the named table.
+func (d *Document) Get(name string) (grate.Collection, error) {
+	for _, t := range d.tables {
+		if t.name == name {
+			t.sheet.Rewind()
+			return t.sheet, nil
+		}
+	}
+	return nil, errors.New("docx: table not found")
+}
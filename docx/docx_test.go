@@ -0,0 +1,147 @@
+package docx
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate"
+)
+
+const (
+	testRels = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+		`</Relationships>`
+	testDocRels = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"></Relationships>`
+	testTypes = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/word/document.xml" ContentType="%s"/></Types>`
+)
+
+func writeTestFile(t *testing.T, contentType, body string) string {
+	fn := filepath.Join(t.TempDir(), "test.docx")
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	for name, data := range map[string]string{
+		"[Content_Types].xml":          fmt.Sprintf(testTypes, contentType),
+		"_rels/.rels":                  testRels,
+		"word/_rels/document.xml.rels": testDocRels,
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+			`xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006"><w:body>` +
+			body + `</w:body></w:document>`,
+	} {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	if err = z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return fn
+}
+
+// cell returns a table cell with properties and paragraphs of text.
+func cell(props string, paras ...string) string {
+	res := `<w:tc><w:tcPr><w:tcW w:w="2000" w:type="dxa"/>` + props + `</w:tcPr>`
+	for _, p := range paras {
+		res += `<w:p><w:pPr><w:tabs><w:tab w:val="left" w:pos="720"/></w:tabs></w:pPr><w:r><w:t xml:space="preserve">` + p + `</w:t></w:r></w:p>`
+	}
+	if len(paras) == 0 {
+		res += `<w:p/>`
+	}
+	return res + `</w:tc>`
+}
+
+func row(cells ...string) string {
+	res := `<w:tr>`
+	for _, c := range cells {
+		res += c
+	}
+	return res + `</w:tr>`
+}
+
+func readAll(t *testing.T, src grate.Source, name string) [][]string {
+	t.Helper()
+	c, err := src.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res [][]string
+	for c.Next() {
+		res = append(res, c.Strings())
+	}
+	return res
+}
+
+func TestTables(t *testing.T) {
+	nested := `<w:tbl>` + row(cell("", "a"), cell("", "b")) + `</w:tbl>`
+	body := `<w:p><w:r><w:t>Invoice</w:t></w:r></w:p>` +
+		`<w:tbl><w:tblPr><w:tblCaption w:val="Items"/></w:tblPr>` +
+		row(cell(`<w:gridSpan w:val="2"/>`, "Item and code"), cell("", "Price")) +
+		row(cell(`<w:vMerge w:val="restart"/>`, "Widget"), cell("", "W-1"), cell("", "1.50")) +
+		row(cell(`<w:vMerge/>`), cell("", "W-2"), cell("", "1.75")) +
+		row(cell("", "Gadget"), cell("", "G-1"), cell("", "first line", "second line")) +
+		`</w:tbl>` +
+		`<w:tbl>` +
+		row(cell("", "Notes"), `<w:tc><w:p><w:r><w:t>see</w:t></w:r></w:p>`+nested+`<w:p/></w:tc>`) +
+		row(`<w:tc><w:p><w:r><mc:AlternateContent><mc:Choice Requires="wps"><w:t>shape</w:t></mc:Choice>`+
+			`<mc:Fallback><w:t>shape</w:t></mc:Fallback></mc:AlternateContent></w:r></w:p></w:tc>`) +
+		`</w:tbl>`
+	fn := writeTestFile(t, "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml", body)
+
+	src, err := Open(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	names, _ := src.List()
+	if want := []string{"Items", "Table2", "Table3"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got tables %q, want %q", names, want)
+	}
+
+	want := [][]string{
+		{"Item and code", "⇥", "Price"},
+		{"Widget", "W-1", "1.50"},
+		{"⤓", "W-2", "1.75"},
+		{"Gadget", "G-1", "first line\nsecond line"},
+	}
+	if got := readAll(t, src, "Items"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	want = [][]string{{"Notes", "see"}, {"shape", ""}}
+	if got := readAll(t, src, "Table2"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	want = [][]string{{"a", "b"}}
+	if got := readAll(t, src, "Table3"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	fn := writeTestFile(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml", "")
+	if _, err := Open(fn); !errors.Is(err, grate.ErrNotInFormat) {
+		t.Errorf("got error %v, want ErrNotInFormat", err)
+	}
+	fn = filepath.Join(t.TempDir(), "text.docx")
+	if err := os.WriteFile(fn, []byte("not a zip file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(fn); !errors.Is(err, grate.ErrNotInFormat) {
+		t.Errorf("got error %v, want ErrNotInFormat", err)
+	}
+}
@@ -0,0 +1,129 @@
+package docx
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// maxGridSpan limits the columns spanned by a cell.
+const maxGridSpan = 1000
+
+// vertical merge states of a cell
+const (
+	noMerge = iota
+	mergeRestart
+	mergeContinue
+)
+
+type table struct {
+	name    string
+	caption string
+	sheet   *commonxl.Sheet
+
+	row, col int
+	merges   []commonxl.CellRange
+	vmerges  map[int]int // column => index of the open vertical merge
+
+	// current cell
+	inCell bool
+	span   int
+	vMerge int
+	text   strings.Builder
+}
+
+func newTable() *table {
+	return &table{
+		sheet:   &commonxl.Sheet{},
+		row:     -1,
+		vmerges: make(map[int]int),
+	}
+}
+
+func (t *table) startRow() {
+	t.endCell()
+	t.row++
+	t.col = 0
+}
+
+func (t *table) startCell() {
+	t.endCell()
+	if t.row < 0 {
+		t.startRow()
+	}
+	t.inCell = true
+	t.span = 1
+	t.vMerge = noMerge
+	t.text.Reset()
+}
+
+// writeText adds text to the current cell.
+func (t *table) writeText(s string) {
+	if t.inCell {
+		t.text.WriteString(s)
+	}
+}
+
+// endParagraph starts a new line in the current cell.
+func (t *table) endParagraph() {
+	if t.inCell {
+		t.text.WriteByte('\n')
+	}
+}
+
+// endCell places the text of the current cell, and records the cells it
+// spans. The text of cells which continue a vertical merge is ignored, as
+// in Word.
+func (t *table) endCell() {
+	if !t.inCell {
+		return
+	}
+	t.inCell = false
+	t.sheet.Resize(t.row+1, t.col+t.span)
+
+	if t.vMerge == mergeContinue {
+		if i, ok := t.vmerges[t.col]; ok {
+			t.merges[i].LastRow = t.row
+		}
+		t.col += t.span
+		return
+	}
+	if s := strings.TrimSpace(t.text.String()); s != "" {
+		t.sheet.Put(t.row, t.col, s)
+	}
+	delete(t.vmerges, t.col)
+	if t.span > 1 || t.vMerge == mergeRestart {
+		if t.vMerge == mergeRestart {
+			t.vmerges[t.col] = len(t.merges)
+		}
+		t.merges = append(t.merges, commonxl.CellRange{
+			FirstRow: t.row, LastRow: t.row,
+			FirstCol: t.col, LastCol: t.col + t.span - 1,
+		})
+	}
+	t.col += t.span
+}
+
+// finish closes the current cell and applies the merged cells.
+func (t *table) finish() {
+	t.endCell()
+	for _, m := range t.merges {
+		if m.LastRow > m.FirstRow || m.LastCol > m.FirstCol {
+			t.sheet.Merge(m)
+		}
+	}
+	t.merges = nil
+	t.vmerges = nil
+}
+
+func spanValue(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 {
+		return 1
+	}
+	if n > maxGridSpan {
+		return maxGridSpan
+	}
+	return n
+}
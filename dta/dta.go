@@ -0,0 +1,239 @@
+// Package dta reads Stata .dta data files, in the formats written by Stata
+// 10 and later (releases 114 to 119).
+package dta
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commontext"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+var _ = grate.Register("dta", 2, Open)
+
+// Options configure how a data file is read.
+type Options struct {
+	// ValueLabels replaces the values of variables which have value labels
+	// with their labels.
+	ValueLabels bool
+}
+
+// Variable describes a column of a data file.
+type Variable struct {
+	Name  string
+	Label string
+
+	// Format is the display format, such as "%9.0g" or "%td".
+	Format string
+
+	// Labels maps the values of the variable, as returned by Strings, to
+	// their value labels, or is nil when the variable has none.
+	Labels map[string]string
+}
+
+// Document is a Stata data file, with the names of the variables in the
+// first row and an observation in each following row.
+type Document struct {
+	filename string
+	label    string
+	vars     []Variable
+	sheet    *commonxl.Sheet
+}
+
+// Open a Stata data file.
+func Open(filename string) (grate.Source, error) {
+	return OpenWithOptions(filename, Options{})
+}
+
+// OpenWithOptions opens a Stata data file with the given options.
+func OpenWithOptions(filename string, opts Options) (grate.Source, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	var head [11]byte
+	if _, err := io.ReadFull(fh, head[:]); err != nil {
+		return nil, grate.ErrNotInFormat
+	}
+	tagged := string(head[:]) == "<stata_dta>"
+	if !tagged && !((head[0] == 114 || head[0] == 115) && (head[1] == 1 || head[1] == 2) && head[2] == 1 && head[3] == 0) {
+		return nil, grate.ErrNotInFormat
+	}
+	if _, err := fh.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf, err := ioutil.ReadAll(fh)
+	if err != nil {
+		return nil, err
+	}
+
+	var f *file
+	if tagged {
+		f, err = parseNew(buf)
+	} else {
+		f, err = parseOld(buf)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d := &Document{filename: filename}
+	d.load(f, opts)
+	return d, nil
+}
+
+// load decodes the variables and observations of a parsed file.
+func (d *Document) load(f *file, opts Options) {
+	enc := commontext.Windows1252
+	if f.release >= 118 {
+		enc = commontext.UTF8
+	}
+	d.label = enc.Decode(f.label)
+
+	names := make([]interface{}, len(f.types))
+	kinds := make([]byte, len(f.types))
+	d.vars = make([]Variable, len(f.types))
+	for i := range f.types {
+		v := &d.vars[i]
+		v.Name = enc.Decode(f.names[i])
+		v.Label = enc.Decode(f.varlabels[i])
+		v.Format = enc.Decode(f.formats[i])
+		if table, ok := f.labels[string(f.lblnames[i])]; ok && len(table) > 0 {
+			v.Labels = make(map[string]string, len(table))
+			for n, s := range table {
+				v.Labels[strconv.Itoa(int(n))] = enc.Decode(s)
+			}
+		}
+		names[i] = v.Name
+		kinds[i] = dateKind(v.Format)
+	}
+
+	d.sheet = &commonxl.Sheet{}
+	d.sheet.Append(names...)
+	r := &reader{buf: f.data, order: f.order}
+	for obs := 0; obs < f.nobs; obs++ {
+		row := make([]interface{}, len(f.types))
+		for i, t := range f.types {
+			b := r.bytes(width(t))
+			switch {
+			case t <= 2045:
+				if s := cstring(b); len(s) > 0 {
+					row[i] = enc.Decode(s)
+				}
+			case t == typeStrL:
+				sr := &reader{buf: b, order: f.order}
+				key := [2]uint64{sr.uint(f.vLen), sr.uint(8 - f.vLen)}
+				if s := f.strls[key]; len(s) > 0 {
+					row[i] = enc.Decode(s)
+				}
+			default:
+				num, ok := number(t, b, f)
+				if !ok {
+					continue
+				}
+				row[i] = value(num, kinds[i])
+				if opts.ValueLabels && d.vars[i].Labels != nil {
+					if s, ok := d.vars[i].Labels[strconv.FormatFloat(num, 'f', -1, 64)]; ok {
+						row[i] = s
+					}
+				}
+			}
+		}
+		d.sheet.Append(row...)
+	}
+}
+
+// number decodes a numeric value, returning false for missing values.
+// Stata reserves the largest values of each type for the missing values
+// ., .a, .b and so on to .z.
+func number(t uint16, b []byte, f *file) (float64, bool) {
+	switch t {
+	case typeByte:
+		v := int8(b[0])
+		return float64(v), v <= 100
+	case typeInt:
+		v := int16(f.order.Uint16(b))
+		return float64(v), v <= 32740
+	case typeLong:
+		v := int32(f.order.Uint32(b))
+		return float64(v), v <= 2147483620
+	case typeFloat:
+		v := math.Float32frombits(f.order.Uint32(b))
+		if v >= 0x1p127 || v != v {
+			return 0, false
+		}
+		// the shortest decimal which is the same float32
+		n, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+		return n, true
+	case typeDouble:
+		v := math.Float64frombits(f.order.Uint64(b))
+		return v, v < 0x1p1023 && v == v
+	}
+	return 0, false
+}
+
+// stataEpoch is 1960-01-01, from which Stata counts dates and times.
+var stataEpoch = time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// dateKind returns 'd' for the formats of dates, which count days, 'c' for
+// the formats of times, which count milliseconds, or 0.
+func dateKind(format string) byte {
+	f := strings.TrimPrefix(strings.TrimPrefix(format, "%"), "-")
+	switch {
+	case strings.HasPrefix(f, "td"), strings.HasPrefix(f, "d"):
+		return 'd'
+	case strings.HasPrefix(f, "tc"), strings.HasPrefix(f, "tC"):
+		return 'c'
+	}
+	return 0
+}
+
+// value returns a number as a date or time for the formats of dates.
+func value(num float64, kind byte) interface{} {
+	switch kind {
+	case 'd':
+		return stataEpoch.AddDate(0, 0, int(num))
+	case 'c':
+		ms := int64(num)
+		return time.Unix(stataEpoch.Unix()+ms/1000, ms%1000*int64(time.Millisecond)).UTC()
+	}
+	return num
+}
+
+// Label returns the label of the data file.
+func (d *Document) Label() string {
+	return d.label
+}
+
+// Variables returns the variables of the data file.
+func (d *Document) Variables() []Variable {
+	return append([]Variable{}, d.vars...)
+}
+
+// List returns the name of the single table in the file.
+func (d *Document) List() ([]string, error) {
+	return []string{filepath.Base(d.filename)}, nil
+}
+
+// Get the table of the file.
+func (d *Document) Get(name string) (grate.Collection, error) {
+	if name != filepath.Base(d.filename) {
+		return nil, errors.New("dta: table not found")
+	}
+	d.sheet.Rewind()
+	return d.sheet, nil
+}
+
+// Close the document.
+func (d *Document) Close() error {
+	return nil
+}
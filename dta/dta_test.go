@@ -0,0 +1,263 @@
+package dta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pbnjay/grate"
+)
+
+// field writes s in a NUL-padded field of n bytes.
+func field(b *bytes.Buffer, s string, n int) {
+	b.WriteString(s)
+	b.Write(make([]byte, n-len(s)))
+}
+
+// labelTable returns a value label table.
+func labelTable(order binary.ByteOrder, vals []int32, labels []string) []byte {
+	var txt bytes.Buffer
+	var b bytes.Buffer
+	offs := make([]int32, len(labels))
+	for i, s := range labels {
+		offs[i] = int32(txt.Len())
+		txt.WriteString(s + "\x00")
+	}
+	binary.Write(&b, order, int32(len(vals)))
+	binary.Write(&b, order, int32(txt.Len()))
+	binary.Write(&b, order, offs)
+	binary.Write(&b, order, vals)
+	b.Write(txt.Bytes())
+	return b.Bytes()
+}
+
+// build118 returns a release 118 file with variables id (byte, labelled),
+// born (double, %td), name (str8) and notes (strL).
+func build118() []byte {
+	le := binary.LittleEndian
+	var b bytes.Buffer
+	var offsets [14]uint64
+	w := func(v interface{}) { binary.Write(&b, le, v) }
+
+	b.WriteString("<stata_dta><header><release>118</release><byteorder>LSF</byteorder><K>")
+	w(uint16(4))
+	b.WriteString("</K><N>")
+	w(uint64(3))
+	b.WriteString("</N><label>")
+	w(uint16(len("Café survey")))
+	b.WriteString("Café survey")
+	b.WriteString("</label><timestamp>")
+	b.WriteByte(17)
+	b.WriteString("15 Oct 2026 09:30")
+	b.WriteString("</timestamp></header>")
+	mapPos := b.Len()
+	b.WriteString("<map>")
+	w(offsets)
+	b.WriteString("</map><variable_types>")
+	w([]uint16{typeByte, typeDouble, 8, typeStrL})
+	b.WriteString("</variable_types><varnames>")
+	for _, s := range []string{"id", "born", "name", "notes"} {
+		field(&b, s, 129)
+	}
+	b.WriteString("</varnames><sortlist>")
+	b.Write(make([]byte, 2*5))
+	b.WriteString("</sortlist><formats>")
+	for _, s := range []string{"%8.0g", "%td", "%9s", "%9s"} {
+		field(&b, s, 57)
+	}
+	b.WriteString("</formats><value_label_names>")
+	for _, s := range []string{"yesno", "", "", ""} {
+		field(&b, s, 129)
+	}
+	b.WriteString("</value_label_names><variable_labels>")
+	for _, s := range []string{"Identifier", "Date of birth", "", ""} {
+		field(&b, s, 321)
+	}
+	b.WriteString("</variable_labels><characteristics></characteristics>")
+
+	offsets[9] = uint64(b.Len())
+	b.WriteString("<data>")
+	record := func(id int8, born float64, name string, v, o uint64) {
+		w(id)
+		w(born)
+		field(&b, name, 8)
+		w(uint16(v))
+		b.Write([]byte{byte(o), byte(o >> 8), byte(o >> 16), byte(o >> 24), byte(o >> 32), byte(o >> 40)})
+	}
+	record(1, 7306, "Zoë", 4, 1) // 1980-01-02
+	record(101, -1, "", 0, 0)    // . missing
+	record(2, math.Ldexp(1, 1023), "Ann", 4, 3)
+	b.WriteString("</data>")
+
+	offsets[10] = uint64(b.Len())
+	b.WriteString("<strls>GSO")
+	w(uint32(4))
+	w(uint64(1))
+	b.WriteByte(130)
+	w(uint32(len("a long note\x00")))
+	b.WriteString("a long note\x00")
+	b.WriteString("GSO")
+	w(uint32(4))
+	w(uint64(3))
+	b.WriteByte(130)
+	w(uint32(len("another\x00")))
+	b.WriteString("another\x00")
+	b.WriteString("</strls>")
+
+	offsets[11] = uint64(b.Len())
+	b.WriteString("<value_labels><lbl>")
+	table := labelTable(le, []int32{1, 2}, []string{"yes", "no"})
+	w(int32(len(table)))
+	field(&b, "yesno", 129)
+	b.Write(make([]byte, 3))
+	b.Write(table)
+	b.WriteString("</lbl></value_labels></stata_dta>")
+
+	res := b.Bytes()
+	for i, off := range offsets {
+		le.PutUint64(res[mapPos+len("<map>")+8*i:], off)
+	}
+	return res
+}
+
+// build114 returns a big-endian release 114 file with variables count
+// (int, labelled), weight (float) and city (str6).
+func build114() []byte {
+	be := binary.BigEndian
+	var b bytes.Buffer
+	w := func(v interface{}) { binary.Write(&b, be, v) }
+
+	b.Write([]byte{114, 1, 1, 0})
+	w(uint16(3))
+	w(uint32(2))
+	field(&b, "Towns", 81)
+	field(&b, "15 Oct 2026 09:30", 18)
+	b.Write([]byte{252, 254, 6})
+	for _, s := range []string{"count", "weight", "city"} {
+		field(&b, s, 33)
+	}
+	b.Write(make([]byte, 2*4))
+	for _, s := range []string{"%8.0g", "%9.0g", "%9s"} {
+		field(&b, s, 49)
+	}
+	for _, s := range []string{"sizes", "", ""} {
+		field(&b, s, 33)
+	}
+	for _, s := range []string{"", "", "City"} {
+		field(&b, s, 81)
+	}
+	b.Write(make([]byte, 5)) // no expansion fields
+
+	w(int16(3))
+	w(float32(1.1))
+	field(&b, "Gen\xe8ve", 6)
+	w(int16(32741))
+	w(float32(math.Ldexp(1, 127)))
+	field(&b, "Z\xfcrich", 6)
+
+	table := labelTable(be, []int32{3}, []string{"three"})
+	w(int32(len(table)))
+	field(&b, "sizes", 33)
+	b.Write(make([]byte, 3))
+	b.Write(table)
+	return b.Bytes()
+}
+
+func readAll(t *testing.T, fn string, opts Options) [][]string {
+	t.Helper()
+	src, err := OpenWithOptions(fn, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	c, err := src.Get(filepath.Base(fn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res [][]string
+	for c.Next() {
+		res = append(res, c.Strings())
+	}
+	return res
+}
+
+func TestRelease118(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "survey.dta")
+	if err := os.WriteFile(fn, build118(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"id", "born", "name", "notes"},
+		{"1", "1980-01-02", "Zoë", "a long note"},
+		{"", "1959-12-31", "", ""},
+		{"2", "", "Ann", "another"},
+	}
+	if got := readAll(t, fn, Options{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	want[1][0], want[3][0] = "yes", "no"
+	if got := readAll(t, fn, Options{ValueLabels: true}); !reflect.DeepEqual(got, want) {
+		t.Errorf("with labels got %q, want %q", got, want)
+	}
+
+	src, _ := Open(fn)
+	d := src.(*Document)
+	if d.Label() != "Café survey" {
+		t.Errorf("got label %q", d.Label())
+	}
+	vars := d.Variables()
+	if vars[0].Label != "Identifier" || !reflect.DeepEqual(vars[0].Labels, map[string]string{"1": "yes", "2": "no"}) {
+		t.Errorf("got variable %+v", vars[0])
+	}
+
+	c, _ := src.Get("survey.dta")
+	c.Next()
+	c.Next()
+	var id int
+	var born time.Time
+	var name, notes string
+	if err := c.Scan(&id, &born, &name, &notes); err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 || !born.Equal(time.Date(1980, 1, 2, 0, 0, 0, 0, time.UTC)) || name != "Zoë" {
+		t.Errorf("scanned %v %v %q %q", id, born, name, notes)
+	}
+}
+
+func TestRelease114(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "towns.dta")
+	if err := os.WriteFile(fn, build114(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"count", "weight", "city"},
+		{"three", "1.1", "Genève"},
+		{"", "", "Zürich"},
+	}
+	if got := readAll(t, fn, Options{ValueLabels: true}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"text.dta":  "id,name\n1,Ann\n",
+		"short.dta": "<stata",
+		"old.dta":   "\x72\x02\x01\x00\x00\x00",
+	} {
+		fn := filepath.Join(dir, name)
+		if err := os.WriteFile(fn, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open(fn); !errors.Is(err, grate.ErrNotInFormat) {
+			t.Errorf("%s: got error %v, want ErrNotInFormat", name, err)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package dta
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+
+	"github.com/pbnjay/grate"
+)
+
+// variable types, as numbered by releases 117 and later. Types 1 to 2045
+// are strings of that length.
+const (
+	typeStrL   = 32768
+	typeDouble = 65526
+	typeFloat  = 65527
+	typeLong   = 65528
+	typeInt    = 65529
+	typeByte   = 65530
+)
+
+// file holds the parsed sections of a .dta file.
+type file struct {
+	release int
+	order   binary.ByteOrder
+	nobs    int
+	label   []byte
+
+	types     []uint16
+	names     [][]byte
+	formats   [][]byte
+	lblnames  [][]byte
+	varlabels [][]byte
+
+	labels map[string]map[int32][]byte // value label tables by name
+	strls  map[[2]uint64][]byte        // long strings by (variable, observation)
+	vLen   int                         // length of the variable of a strL reference
+	data   []byte                      // the records
+}
+
+// width returns the length in bytes of a variable of type t.
+func width(t uint16) int {
+	switch t {
+	case typeStrL, typeDouble:
+		return 8
+	case typeFloat, typeLong:
+		return 4
+	case typeInt:
+		return 2
+	case typeByte:
+		return 1
+	}
+	return int(t)
+}
+
+func (f *file) recordLen() int {
+	n := 0
+	for _, t := range f.types {
+		n += width(t)
+	}
+	return n
+}
+
+// records reads the records, after checking that the file is long enough.
+func (f *file) records(r *reader) {
+	n := f.recordLen()
+	if n == 0 || f.nobs > (len(r.buf)-r.pos)/n {
+		if r.err == nil {
+			r.err = errTruncated
+		}
+		return
+	}
+	f.data = r.bytes(f.nobs * n)
+}
+
+// parseOld parses the formats of releases 114 and 115, used by Stata 10
+// to 12.
+func parseOld(buf []byte) (*file, error) {
+	f := &file{release: int(buf[0]), labels: make(map[string]map[int32][]byte)}
+	f.order = binary.LittleEndian
+	if buf[1] == 1 {
+		f.order = binary.BigEndian
+	}
+	r := &reader{buf: buf, pos: 4, order: f.order}
+	nvar := int(r.uint(2))
+	f.nobs = int(r.uint(4))
+	f.label = r.str(81)
+	r.bytes(18) // time stamp
+	if r.err != nil || nvar == 0 {
+		return nil, grate.ErrNotInFormat
+	}
+
+	for _, t := range r.bytes(nvar) {
+		switch {
+		case t >= 1 && t <= 244:
+			f.types = append(f.types, uint16(t))
+		case t >= 251:
+			// byte, int, long, float and double
+			f.types = append(f.types, typeByte-uint16(t-251))
+		default:
+			return nil, grate.ErrNotInFormat
+		}
+	}
+	f.names = strs(r, nvar, 33)
+	r.bytes(2 * (nvar + 1)) // sort order
+	f.formats = strs(r, nvar, 49)
+	f.lblnames = strs(r, nvar, 33)
+	f.varlabels = strs(r, nvar, 81)
+	for r.err == nil {
+		// expansion fields
+		t, n := r.uint(1), r.uint(4)
+		if t == 0 && n == 0 {
+			break
+		}
+		r.bytes(int(n))
+	}
+	f.records(r)
+
+	for r.err == nil && len(buf)-r.pos >= 4+33+3+8 {
+		n := int(r.uint(4))
+		name := string(r.str(33))
+		r.bytes(3)
+		if table := r.bytes(n); r.err == nil {
+			f.labels[name] = parseLabels(table, f.order)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return f, nil
+}
+
+// parseNew parses the formats of releases 117, 118 and 119, used by Stata
+// 13 and later, which mark the sections of the file with tags.
+func parseNew(buf []byte) (*file, error) {
+	f := &file{labels: make(map[string]map[int32][]byte), strls: make(map[[2]uint64][]byte)}
+	r := &reader{buf: buf}
+	r.tag("<stata_dta><header><release>")
+	f.release, _ = strconv.Atoi(string(r.bytes(3)))
+	if r.err == nil && (f.release < 117 || f.release > 119) {
+		return nil, errors.New("dta: unsupported release " + strconv.Itoa(f.release))
+	}
+	r.tag("</release><byteorder>")
+	switch string(r.bytes(3)) {
+	case "MSF":
+		f.order = binary.BigEndian
+	case "LSF":
+		f.order = binary.LittleEndian
+	default:
+		return nil, errors.New("dta: unknown byte order")
+	}
+	r.order = f.order
+
+	// the sizes of fields which vary by release
+	kLen, nLen, labelLen, sortLen := 2, 8, 2, 2
+	nameLen, fmtLen, varLabelLen, oLen := 129, 57, 321, 8
+	f.vLen = 2
+	switch f.release {
+	case 117:
+		nLen, labelLen = 4, 1
+		nameLen, fmtLen, varLabelLen, oLen = 33, 49, 81, 4
+		f.vLen = 4
+	case 119:
+		kLen, sortLen = 4, 4
+		f.vLen = 3
+	}
+
+	r.tag("</byteorder><K>")
+	nvar := int(r.uint(kLen))
+	r.tag("</K><N>")
+	f.nobs = int(r.uint(nLen))
+	r.tag("</N><label>")
+	f.label = r.bytes(int(r.uint(labelLen)))
+	r.tag("</label><timestamp>")
+	r.bytes(int(r.uint(1)))
+	r.tag("</timestamp></header><map>")
+	var offsets [14]uint64
+	for i := range offsets {
+		offsets[i] = r.uint(8)
+	}
+	r.tag("</map><variable_types>")
+	if r.err == nil && nvar > len(buf) {
+		r.err = errTruncated
+	}
+	for i := 0; i < nvar && r.err == nil; i++ {
+		f.types = append(f.types, uint16(r.uint(2)))
+	}
+	r.tag("</variable_types><varnames>")
+	f.names = strs(r, nvar, nameLen)
+	r.tag("</varnames><sortlist>")
+	r.bytes(sortLen * (nvar + 1))
+	r.tag("</sortlist><formats>")
+	f.formats = strs(r, nvar, fmtLen)
+	r.tag("</formats><value_label_names>")
+	f.lblnames = strs(r, nvar, nameLen)
+	r.tag("</value_label_names><variable_labels>")
+	f.varlabels = strs(r, nvar, varLabelLen)
+	r.tag("</variable_labels>")
+
+	r.seek(offsets[10])
+	r.tag("<strls>")
+	for r.peek("GSO") {
+		r.bytes(3)
+		v, o := r.uint(4), r.uint(oLen)
+		t := r.uint(1)
+		s := r.bytes(int(r.uint(4)))
+		if t == 130 {
+			// text, rather than binary
+			s = cstring(s)
+		}
+		f.strls[[2]uint64{v, o}] = s
+	}
+	r.tag("</strls>")
+
+	r.seek(offsets[11])
+	r.tag("<value_labels>")
+	for r.peek("<lbl>") {
+		r.bytes(5)
+		n := int(r.uint(4))
+		name := string(r.str(nameLen))
+		r.bytes(3)
+		if table := r.bytes(n); r.err == nil {
+			f.labels[name] = parseLabels(table, f.order)
+		}
+		r.tag("</lbl>")
+	}
+	r.tag("</value_labels>")
+
+	r.seek(offsets[9])
+	r.tag("<data>")
+	f.records(r)
+	r.tag("</data>")
+	if r.err != nil {
+		return nil, r.err
+	}
+	return f, nil
+}
+
+// strs reads n NUL-terminated strings in fields of a length.
+func strs(r *reader, n, length int) [][]byte {
+	if r.err == nil && n*length > len(r.buf)-r.pos {
+		r.err = errTruncated
+	}
+	res := make([][]byte, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		res = append(res, r.str(length))
+	}
+	return res
+}
+
+// parseLabels parses a value label table, which maps integers to text.
+func parseLabels(table []byte, order binary.ByteOrder) map[int32][]byte {
+	r := &reader{buf: table, order: order}
+	n := int(r.uint(4))
+	txtlen := int(r.uint(4))
+	if n > len(table)/8 {
+		return nil
+	}
+	offs := make([]int, n)
+	for i := range offs {
+		offs[i] = int(r.uint(4))
+	}
+	vals := make([]int32, n)
+	for i := range vals {
+		vals[i] = int32(r.uint(4))
+	}
+	txt := r.bytes(txtlen)
+	if r.err != nil {
+		return nil
+	}
+	res := make(map[int32][]byte, n)
+	for i, off := range offs {
+		if off < len(txt) {
+			res[vals[i]] = cstring(txt[off:])
+		}
+	}
+	return res
+}
@@ -0,0 +1,82 @@
+package dta
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errTruncated = errors.New("dta: file is truncated")
+
+// reader reads the values of a file in memory, and remembers the first
+// error so that a section can be read before checking for errors.
+type reader struct {
+	buf   []byte
+	pos   int
+	order binary.ByteOrder
+	err   error
+}
+
+// bytes returns the next n bytes.
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.buf) {
+		r.err = errTruncated
+		return nil
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// uint returns the next unsigned integer of n bytes, which need not be a
+// power of two, such as the 6-byte offsets of long strings.
+func (r *reader) uint(n int) uint64 {
+	b := r.bytes(n)
+	var v uint64
+	for i := range b {
+		if r.order == binary.BigEndian {
+			v = v<<8 | uint64(b[i])
+		} else {
+			v |= uint64(b[i]) << (8 * i)
+		}
+	}
+	return v
+}
+
+// str returns a NUL-terminated string in a field of n bytes.
+func (r *reader) str(n int) []byte {
+	return cstring(r.bytes(n))
+}
+
+// tag expects the literal text of a tag, such as "<data>".
+func (r *reader) tag(s string) {
+	if b := r.bytes(len(s)); r.err == nil && string(b) != s {
+		r.err = errors.New("dta: expected " + s)
+	}
+}
+
+// peek returns true if the next bytes are s.
+func (r *reader) peek(s string) bool {
+	return r.err == nil && r.pos+len(s) <= len(r.buf) && string(r.buf[r.pos:r.pos+len(s)]) == s
+}
+
+// seek moves to an offset of the file.
+func (r *reader) seek(pos uint64) {
+	if r.err == nil && pos > uint64(len(r.buf)) {
+		r.err = errTruncated
+		return
+	}
+	r.pos = int(pos)
+}
+
+// cstring returns the bytes before the first NUL.
+func cstring(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
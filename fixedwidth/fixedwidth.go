@@ -0,0 +1,371 @@
+// Package fixedwidth reads text files with fields in fixed columns, as
+// exported by mainframes and many government data sets.
+package fixedwidth
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commontext"
+)
+
+var _ = grate.Register("fixedwidth", 12, Open)
+
+// Column describes a field at a fixed position of each line.
+type Column struct {
+	// Name of the column, from the header line when inferred.
+	Name string
+
+	// Start is the offset of the field in characters, from 0.
+	Start int
+
+	// Width of the field in characters, or 0 for the rest of the line.
+	Width int
+}
+
+// HeaderOption selects whether the first line of a file is a header.
+type HeaderOption int
+
+// Header options.
+const (
+	HeaderDetect HeaderOption = iota
+	HeaderPresent
+	HeaderAbsent
+)
+
+// Options configure how a fixed-width file is read.
+type Options struct {
+	// Columns of the file, or nil to infer the columns from the alignment
+	// of the start of the file.
+	Columns []Column
+
+	// Header selects whether the first line is a header.
+	Header HeaderOption
+
+	// Encoding is the label of the character encoding, or empty to detect
+	// it.
+	Encoding string
+}
+
+// Document is a fixed-width text file. Lines are read from the file as
+// needed, so the file remains open until the document is closed.
+type Document struct {
+	filename  string
+	enc       *commontext.Encoding
+	columns   []Column
+	header    bool
+	separator bool // a line of dashes follows the header
+	empty     bool
+
+	f    *os.File
+	r    *bufio.Reader
+	line int
+	row  []string
+	err  error
+	done bool
+}
+
+const (
+	// sampleSize is the length of the sample used to infer the columns.
+	sampleSize = 64 << 10
+
+	// sampleLines is the number of lines used to infer the columns.
+	sampleLines = 1000
+)
+
+// Open a fixed-width text file, inferring its columns.
+func Open(filename string) (grate.Source, error) {
+	return OpenWithOptions(filename, Options{})
+}
+
+// OpenWithOptions opens a fixed-width text file with the given options.
+// Without explicit columns, the file must have at least two columns
+// separated by whitespace on every line.
+func OpenWithOptions(filename string, opts Options) (grate.Source, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sample := make([]byte, sampleSize)
+	n, err := io.ReadFull(f, sample)
+	complete := err == io.EOF || err == io.ErrUnexpectedEOF
+	if err != nil && !complete {
+		return nil, err
+	}
+	sample = sample[:n]
+
+	d := &Document{filename: filename}
+	if opts.Encoding != "" {
+		d.enc = commontext.LookupEncoding(opts.Encoding)
+		if d.enc == nil {
+			return nil, fmt.Errorf("fixedwidth: unsupported encoding %q", opts.Encoding)
+		}
+	} else {
+		d.enc = commontext.DetectEncoding(sample)
+	}
+	if enc, n := commontext.DetectBOM(sample); enc == d.enc {
+		sample = sample[n:]
+	}
+	if d.enc != commontext.UTF16LE && d.enc != commontext.UTF16BE && bytes.IndexByte(sample, 0) >= 0 {
+		return nil, grate.ErrNotInFormat
+	}
+	text := d.enc.Decode(sample)
+	if !complete {
+		if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+			text = text[:i+1]
+		}
+	}
+	lines := sampleText(text)
+
+	switch opts.Header {
+	case HeaderPresent:
+		d.header = true
+	case HeaderDetect:
+		d.header = len(lines) > 1 && isSeparator(lines[1])
+	}
+	d.separator = d.header && len(lines) > 1 && isSeparator(lines[1])
+
+	if opts.Columns != nil {
+		d.columns = append([]Column{}, opts.Columns...)
+	} else {
+		if strings.ContainsRune(text, '\t') {
+			return nil, grate.ErrNotInFormat
+		}
+		d.columns = inferColumns(lines)
+		if !plausible(lines, d.columns) {
+			return nil, grate.ErrNotInFormat
+		}
+	}
+
+	if opts.Header == HeaderDetect && !d.header {
+		d.header = detectHeader(lines, d.columns)
+	}
+	if d.header && len(lines) > 0 {
+		names := splitLine(lines[0], d.columns)
+		for i := range d.columns {
+			if d.columns[i].Name == "" {
+				d.columns[i].Name = names[i]
+			}
+		}
+	}
+	d.empty = len(lines) == 0
+	return d, nil
+}
+
+// sampleText returns the first lines of a sample which are not blank.
+func sampleText(text string) [][]rune {
+	var res [][]rune
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		res = append(res, []rune(line))
+		if len(res) == sampleLines {
+			break
+		}
+	}
+	return res
+}
+
+// isSeparator returns true for lines of dashes, such as those between the
+// header and the records of a report.
+func isSeparator(line []rune) bool {
+	dashes := 0
+	for _, r := range line {
+		switch r {
+		case '-', '=':
+			dashes++
+		case ' ', '+', '|':
+		default:
+			return false
+		}
+	}
+	return dashes > 0
+}
+
+// splitLine splits a line into the trimmed fields of the columns.
+func splitLine(line []rune, columns []Column) []string {
+	res := make([]string, len(columns))
+	for i, c := range columns {
+		if c.Start >= len(line) {
+			continue
+		}
+		end := len(line)
+		if c.Width > 0 && c.Start+c.Width < end {
+			end = c.Start + c.Width
+		}
+		res[i] = strings.TrimSpace(string(line[c.Start:end]))
+	}
+	return res
+}
+
+// Columns returns the columns of the document.
+func (d *Document) Columns() []Column {
+	return append([]Column{}, d.columns...)
+}
+
+// List returns the name of the single table in the file.
+func (d *Document) List() ([]string, error) {
+	return []string{filepath.Base(d.filename)}, nil
+}
+
+// Get the table of the file.
+func (d *Document) Get(name string) (grate.Collection, error) {
+	d.Rewind()
+	if d.err != nil {
+		return nil, d.err
+	}
+	return d, nil
+}
+
+// Close the file.
+func (d *Document) Close() error {
+	d.r = nil
+	if d.f == nil {
+		return nil
+	}
+	err := d.f.Close()
+	d.f = nil
+	return err
+}
+
+// Rewind returns to the first line by reopening the file.
+func (d *Document) Rewind() {
+	d.Close()
+	d.line, d.row, d.err, d.done = 0, nil, nil, false
+	f, err := os.Open(d.filename)
+	if err != nil {
+		d.err = err
+		return
+	}
+	br := bufio.NewReader(f)
+	bom, _ := br.Peek(3)
+	if enc, n := commontext.DetectBOM(bom); enc == d.enc {
+		br.Discard(n)
+	}
+	d.f = f
+	d.r = bufio.NewReader(d.enc.NewReader(br))
+}
+
+// Next advances to the next line, skipping blank lines and the separator
+// after the header.
+func (d *Document) Next() bool {
+	if d.r == nil {
+		if d.done || d.err != nil {
+			return false
+		}
+		d.Rewind()
+		if d.err != nil {
+			return false
+		}
+	}
+	for {
+		line, err := d.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				d.done = true
+				d.Close()
+			} else {
+				d.err = err
+			}
+			return false
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		d.line++
+		if d.line == 2 && d.separator {
+			continue
+		}
+		d.row = splitLine([]rune(line), d.columns)
+		return true
+	}
+}
+
+// Strings extracts values from the current line into a list of strings.
+func (d *Document) Strings() []string {
+	return d.row
+}
+
+// date layouts read by Scan
+var dateLayouts = []string{
+	"2006-01-02",
+	"20060102",
+	"2006-01-02 15:04:05",
+	"01/02/2006",
+	"02-Jan-2006",
+}
+
+// Scan extracts values from the current line into the provided arguments
+// Arguments must be pointers to one of 5 supported types:
+//     bool, int, float64, string, or time.Time
+// Empty fields are scanned as zero values.
+func (d *Document) Scan(args ...interface{}) error {
+	if len(d.row) != len(args) {
+		return fmt.Errorf("fixedwidth: expected %d Scan destinations, got %d", len(d.row), len(args))
+	}
+	for i, a := range args {
+		s := d.row[i]
+		var err error
+		switch v := a.(type) {
+		case *bool:
+			switch strings.ToLower(s) {
+			case "1", "t", "true", "y", "yes":
+				*v = true
+			default:
+				*v = false
+			}
+		case *int:
+			*v = 0
+			if s != "" {
+				*v, err = strconv.Atoi(strings.TrimPrefix(s, "+"))
+			}
+		case *float64:
+			*v = 0
+			if s != "" {
+				*v, err = strconv.ParseFloat(s, 64)
+			}
+		case *string:
+			*v = s
+		case *time.Time:
+			*v = time.Time{}
+			if s != "" {
+				err = errors.New("fixedwidth: unknown date format")
+				for _, layout := range dateLayouts {
+					if t, perr := time.Parse(layout, s); perr == nil {
+						*v, err = t, nil
+						break
+					}
+				}
+			}
+		default:
+			return grate.ErrInvalidScanType
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsEmpty returns true if there are no data values.
+func (d *Document) IsEmpty() bool {
+	return d.empty
+}
+
+// Err returns the last error that occured.
+func (d *Document) Err() error {
+	return d.err
+}
@@ -0,0 +1,107 @@
+package fixedwidth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbnjay/grate"
+)
+
+func writeFile(t *testing.T, data string) string {
+	fn := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(fn, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func readAll(t *testing.T, src grate.Source) [][]string {
+	c, err := src.Get("report.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res [][]string
+	for c.Next() {
+		res = append(res, c.Strings())
+	}
+	return res
+}
+
+func TestInferred(t *testing.T) {
+	var sb strings.Builder
+	line := func(a, b, c, d string) {
+		fmt.Fprintf(&sb, "%3s %-13s %6s %s\r\n", a, b, c, d)
+	}
+	line("ID", "NAME", "SCORE", "DATE")
+	line("---", "-------------", "------", "----------")
+	line("1", "Alice Smith", "12.5", "2020-01-15")
+	line("2", "Bob", "7", "2021-03-04")
+	sb.WriteString("\r\n")
+	line("10", "Carol Ann Lee", "100.25", "")
+
+	src, err := Open(writeFile(t, sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	cols := src.(*Document).Columns()
+	expectCols := []Column{{"ID", 0, 4}, {"NAME", 4, 14}, {"SCORE", 18, 7}, {"DATE", 25, 0}}
+	if !reflect.DeepEqual(cols, expectCols) {
+		t.Fatalf("got columns %+v", cols)
+	}
+	expect := [][]string{
+		{"ID", "NAME", "SCORE", "DATE"},
+		{"1", "Alice Smith", "12.5", "2020-01-15"},
+		{"2", "Bob", "7", "2021-03-04"},
+		{"10", "Carol Ann Lee", "100.25", ""},
+	}
+	if got := readAll(t, src); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got %q\nexpected %q", got, expect)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	fn := writeFile(t, "00012JOHN      20200115\n00345MARY ANNE 19991231\n")
+	src, err := OpenWithOptions(fn, Options{
+		Columns: []Column{{"id", 0, 5}, {"name", 5, 10}, {"born", 15, 0}},
+		Header:  HeaderAbsent,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := src.Get("report.txt")
+	var (
+		id   int
+		name string
+		born time.Time
+	)
+	var got []string
+	for c.Next() {
+		if err := c.Scan(&id, &name, &born); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d %s %s", id, name, born.Format("2006-01-02")))
+	}
+	if !reflect.DeepEqual(got, []string{"12 JOHN 2020-01-15", "345 MARY ANNE 1999-12-31"}) {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	for _, data := range []string{
+		"John Smith,42\nJane Smith,37\nJack Smith,50\n",
+		"a\tb\n1\t2\n3\t4\n",
+		"The quick brown fox\njumps over\nthe lazy dog.\n",
+		"a b\n1 2\n",
+	} {
+		if _, err := Open(writeFile(t, data)); !errors.Is(err, grate.ErrNotInFormat) {
+			t.Fatalf("expected ErrNotInFormat for %q, got %v", data, err)
+		}
+	}
+}
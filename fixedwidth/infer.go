@@ -0,0 +1,120 @@
+package fixedwidth
+
+import (
+	"strconv"
+	"strings"
+)
+
+// inferColumns finds the columns of sample lines, which are separated by
+// positions that are blank on every line, or by the runs of dashes of a
+// separator line after the header. Each column extends to the start of the
+// next, and the last to the end of the line.
+func inferColumns(lines [][]rune) []Column {
+	width := 0
+	for _, line := range lines {
+		if len(line) > width {
+			width = len(line)
+		}
+	}
+	used := make([]bool, width)
+	if len(lines) > 1 && isSeparator(lines[1]) {
+		for i, r := range lines[1] {
+			used[i] = r == '-' || r == '='
+		}
+	} else {
+		for _, line := range lines {
+			for i, r := range line {
+				if r != ' ' {
+					used[i] = true
+				}
+			}
+		}
+	}
+
+	var res []Column
+	for i := 0; i < width; i++ {
+		if used[i] && (i == 0 || !used[i-1]) {
+			if n := len(res); n > 0 {
+				res[n-1].Width = i - res[n-1].Start
+			}
+			res = append(res, Column{Start: i})
+		}
+	}
+	if len(res) > 0 {
+		// the first column starts at the start of the line
+		if res[0].Width > 0 {
+			res[0].Width += res[0].Start
+		}
+		res[0].Start = 0
+	}
+	return res
+}
+
+// plausible returns true if inferred columns look like a fixed-width table:
+// there are at least two columns and three lines, most lines have a value
+// in each column, and the lines are not delimited text with aligned spaces.
+func plausible(lines [][]rune, columns []Column) bool {
+	if len(columns) < 2 || len(lines) < 3 {
+		return false
+	}
+	for i := range columns {
+		n := 0
+		for _, line := range lines {
+			if splitLine(line, columns)[i] != "" {
+				n++
+			}
+		}
+		if n*2 <= len(lines) {
+			return false
+		}
+	}
+	for _, delim := range ",;|" {
+		count := strings.Count(string(lines[0]), string(delim))
+		if count == 0 {
+			continue
+		}
+		same := true
+		for _, line := range lines[1:] {
+			if strings.Count(string(line), string(delim)) != count {
+				same = false
+				break
+			}
+		}
+		if same {
+			return false
+		}
+	}
+	return true
+}
+
+// detectHeader returns true if the first line looks like a header, with
+// names above columns of numbers.
+func detectHeader(lines [][]rune, columns []Column) bool {
+	if len(lines) < 2 {
+		return false
+	}
+	names := splitLine(lines[0], columns)
+	votes := 0
+	for i, name := range names {
+		numeric, n := true, 0
+		for _, line := range lines[1:] {
+			if v := splitLine(line, columns)[i]; v != "" {
+				n++
+				numeric = numeric && isNumber(v)
+			}
+		}
+		switch {
+		case n == 0 || !numeric || name == "":
+		case isNumber(name):
+			votes--
+		default:
+			votes++
+		}
+	}
+	return votes > 0
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
@@ -0,0 +1,257 @@
+// Package html reads the tables of HTML documents, such as reports served
+// by web applications with an .xls extension, or workbooks saved as a web
+// page by Excel.
+package html
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commontext"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+var _ = grate.Register("html", 9, Open)
+
+// Document contains the tables of an HTML document.
+type Document struct {
+	filename string
+	tables   []*table
+	formats  map[string]string // class name => mso-number-format
+	fmt      commonxl.Formatter
+}
+
+var (
+	tableStart = regexp.MustCompile(`(?i)<table[\s>]`)
+	metaTag    = regexp.MustCompile(`(?i)<meta\s[^>]*>`)
+	charsetDef = regexp.MustCompile(`(?i)charset\s*=\s*["']?\s*([-\w:.]+)`)
+)
+
+// Open an HTML document, and parse all of its tables.
+func Open(filename string) (grate.Source, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	enc, n := commontext.DetectBOM(data)
+	if enc != nil {
+		data = data[n:]
+	}
+	text := ""
+	if enc == commontext.UTF16LE || enc == commontext.UTF16BE {
+		text = enc.Decode(data)
+	} else {
+		// the markup itself is ASCII, so look for it before decoding
+		trimmed := bytes.TrimLeft(data, " \t\r\n\f")
+		if len(trimmed) == 0 || trimmed[0] != '<' || !tableStart.Match(data) {
+			return nil, grate.ErrNotInFormat
+		}
+		if enc == nil {
+			enc = declaredEncoding(data)
+		}
+		text = enc.Decode(data)
+	}
+	if !tableStart.MatchString(text) {
+		return nil, grate.ErrNotInFormat
+	}
+
+	d := &Document{
+		filename: filename,
+		formats:  make(map[string]string),
+	}
+	d.parse(text)
+	return d, nil
+}
+
+// declaredEncoding returns the encoding of a meta charset declaration in
+// the document. Undeclared documents are UTF-8 when valid, or otherwise
+// Windows-1252 as used by Excel.
+func declaredEncoding(data []byte) *commontext.Encoding {
+	for _, meta := range metaTag.FindAll(data, -1) {
+		if m := charsetDef.FindSubmatch(meta); m != nil {
+			if enc := commontext.LookupEncoding(string(m[1])); enc != nil {
+				if enc == commontext.UTF16LE || enc == commontext.UTF16BE {
+					// the document was read as ASCII, so it is not UTF-16
+					return commontext.UTF8
+				}
+				return enc
+			}
+		}
+	}
+	if utf8.Valid(data) {
+		return commontext.UTF8
+	}
+	return commontext.Windows1252
+}
+
+// parse the tables of the document, including tables nested within cells.
+func (d *Document) parse(text string) {
+	var stack []*table
+	z := newTokenizer(text)
+	inStyle := false
+	for tok, ok := z.next(); ok; tok, ok = z.next() {
+		var cur *table
+		if len(stack) > 0 {
+			cur = stack[len(stack)-1]
+		}
+		switch tok.typ {
+		case textToken:
+			if inStyle {
+				parseStyleSheet(tok.text, d.formats)
+				inStyle = false
+			} else if cur != nil {
+				cur.writeText(tok.text)
+			}
+
+		case startTagToken:
+			switch tok.name {
+			case "style":
+				inStyle = true
+			case "table":
+				t := newTable(d, tok.attrs)
+				d.tables = append(d.tables, t)
+				stack = append(stack, t)
+			case "caption":
+				if cur != nil {
+					cur.endRow()
+					cur.inCaption = true
+				}
+			case "tr":
+				if cur != nil {
+					cur.startRow()
+				}
+			case "td", "th":
+				if cur != nil {
+					cur.startCell(tok.attrs)
+				}
+			case "br", "p", "div", "li":
+				if cur != nil {
+					cur.lineBreak()
+				}
+			}
+
+		case endTagToken:
+			switch tok.name {
+			case "style":
+				inStyle = false
+			case "table":
+				if cur != nil {
+					cur.finish()
+					stack = stack[:len(stack)-1]
+				}
+			case "caption":
+				if cur != nil {
+					cur.inCaption = false
+				}
+			case "tr", "thead", "tbody", "tfoot":
+				if cur != nil {
+					cur.endRow()
+				}
+			case "td", "th":
+				if cur != nil {
+					cur.endCell()
+				}
+			case "p", "div", "li":
+				if cur != nil {
+					cur.lineBreak()
+				}
+			}
+		}
+	}
+	for len(stack) > 0 {
+		stack[len(stack)-1].finish()
+		stack = stack[:len(stack)-1]
+	}
+	d.nameTables()
+}
+
+// nameTables names each table by its caption, id or position, ensuring
+// that every name is unique.
+func (d *Document) nameTables() {
+	seen := make(map[string]bool)
+	for i, t := range d.tables {
+		name := cleanText(strings.Replace(t.caption.String(), "\n", " ", -1))
+		if name == "" {
+			name = t.id
+		}
+		if name == "" {
+			name = "Table" + strconv.Itoa(i+1)
+		}
+		base := name
+		for n := 2; seen[name]; n++ {
+			name = base + " (" + strconv.Itoa(n) + ")"
+		}
+		seen[name] = true
+		t.name = name
+	}
+}
+
+// cellValue returns the value of a cell. Excel writes the underlying value
+// of numeric cells in an x:num attribute, and their number format in an
+// mso-number-format style, either inline or through a class.
+func (d *Document) cellValue(attrs map[string]string, text string) interface{} {
+	code, hasFormat := numberFormat(attrs["style"])
+	if !hasFormat {
+		for _, class := range strings.Fields(attrs["class"]) {
+			if c, ok := d.formats[class]; ok {
+				code, hasFormat = c, true
+			}
+		}
+	}
+	num, isNum := attrs["x:num"]
+	_, isStr := attrs["x:str"]
+	if !isNum || isStr || code == "@" {
+		if text == "" {
+			return nil
+		}
+		return text
+	}
+
+	if num == "" {
+		num = text
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil {
+		if text == "" {
+			return nil
+		}
+		return text
+	}
+	if hasFormat {
+		s, _ := d.fmt.Apply(d.fmt.AddCode(code), f)
+		return s
+	}
+	return f
+}
+
+// Close the document.
+func (d *Document) Close() error {
+	d.tables = nil
+	return nil
+}
+
+// List returns the names of the tables in the document.
+func (d *Document) List() ([]string, error) {
+	res := make([]string, 0, len(d.tables))
+	for _, t := range d.tables {
+		res = append(res, t.name)
+	}
+	return res, nil
+}
+
+// Get the named table.
+func (d *Document) Get(sheetName string) (grate.Collection, error) {
+	for _, t := range d.tables {
+		if t.name == sheetName {
+			t.sheet.Rewind()
+			return t.sheet, nil
+		}
+	}
+	return nil, errors.New("html: table not found")
+}
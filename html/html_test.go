@@ -0,0 +1,112 @@
+package html
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate"
+)
+
+func openString(t *testing.T, data string) (grate.Source, error) {
+	fn := filepath.Join(t.TempDir(), "report.xls")
+	if err := os.WriteFile(fn, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Open(fn)
+}
+
+func readTable(t *testing.T, src grate.Source, name string) [][]string {
+	c, err := src.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res [][]string
+	for c.Next() {
+		res = append(res, c.Strings())
+	}
+	return res
+}
+
+func TestTables(t *testing.T) {
+	src, err := openString(t, `<!DOCTYPE html>
+<html><head><meta http-equiv="Content-Type" content="text/html; charset=windows-1252">
+<style><!--
+.xl65 {mso-style-parent:style0; mso-number-format:"Short Date";}
+td.xl66, .xl67 {mso-number-format:"\#\,\#\#0\.00";}
+.xl68 {mso-number-format:"\@";}
+--></style>
+<script>document.write("<table><tr><td>nope</td></tr></table>");</script>
+</head><body>
+<TABLE id=sales border=1>
+ <caption> Quarterly <b>Sales</b> </caption>
+ <thead><tr><th>Region<th colspan=2>Q1 &amp; Q2</tr></thead>
+ <tr><td rowspan="2">North</td><td class=xl66 x:num="1234.5">1,234.50</td><td x:num>7</td></tr>
+ <tr><td class="xl65" x:num="44197">1/1/2021</td><td class=xl68 x:num>007</td></tr>
+ <tr><td>caf`+"\xe9"+`<br>bar</td><td style='mso-number-format:"0\.0%"' x:num="0.25">25.0%</td>
+   <td><table><tr><td>inner</td></tr></table></td></tr>
+ <tr><td>&nbsp;</td><td>  spaced
+   out  </td>
+</table>
+<table><tr><td>a<td>b</table>
+<table id="sales"><tr><td>dup</td></tr></table>
+</body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	names, _ := src.List()
+	if !reflect.DeepEqual(names, []string{"Quarterly Sales", "Table2", "Table3", "sales"}) {
+		t.Fatalf("got tables %q", names)
+	}
+	expect := [][]string{
+		{"Region", "Q1 & Q2", "⇥"},
+		{"North", "1,234.50", "7"},
+		{"⤓", "01-01-21", "007"},
+		{"café\nbar", "25.0%", ""},
+		{"", "spaced out", ""},
+	}
+	if got := readTable(t, src, "Quarterly Sales"); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got %q\nexpected %q", got, expect)
+	}
+	if got := readTable(t, src, "Table2"); !reflect.DeepEqual(got, [][]string{{"inner"}}) {
+		t.Fatalf("got nested table %q", got)
+	}
+	if got := readTable(t, src, "Table3"); !reflect.DeepEqual(got, [][]string{{"a", "b"}}) {
+		t.Fatalf("got unclosed table %q", got)
+	}
+}
+
+func TestSpans(t *testing.T) {
+	src, err := openString(t, `<table>
+<tr><td rowspan=2 colspan=2>A</td><td>B</td></tr>
+<tr><td>C</td></tr>
+<tr><td>D</td><td rowspan=5>E</td><td>F</td></tr>
+</table>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]string{
+		{"A", "⇥", "B"},
+		{"⤓", "⇥", "C"},
+		{"D", "E", "F"},
+	}
+	if got := readTable(t, src, "Table1"); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got %q\nexpected %q", got, expect)
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	for _, data := range []string{
+		"a,b,c\n1,2,3\n",
+		"<html><body><p>no tables here</p></body></html>",
+		`<?xml version="1.0"?><office:document><table:table/></office:document>`,
+	} {
+		if _, err := openString(t, data); !errors.Is(err, grate.ErrNotInFormat) {
+			t.Fatalf("expected ErrNotInFormat for %q, got %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package html
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	cssComments = regexp.MustCompile(`(?s)/\*.*?\*/|<!--|-->`)
+	cssRules    = regexp.MustCompile(`([^{}]+)\{([^{}]*)\}`)
+)
+
+// parseStyleSheet extracts the mso-number-format of class selectors, as
+// written by Excel when saving as HTML, e.g.
+//
+//	.xl65 {mso-style-parent:style0; mso-number-format:"Short Date";}
+func parseStyleSheet(css string, formats map[string]string) {
+	css = cssComments.ReplaceAllString(css, "")
+	for _, m := range cssRules.FindAllStringSubmatch(css, -1) {
+		code, ok := numberFormat(m[2])
+		if !ok {
+			continue
+		}
+		for _, sel := range strings.Split(m[1], ",") {
+			sel = strings.TrimSpace(sel)
+			i := strings.LastIndexByte(sel, '.')
+			if i < 0 || strings.ContainsAny(sel[i:], " >+~:[") {
+				continue
+			}
+			formats[sel[i+1:]] = code
+		}
+	}
+}
+
+// numberFormat returns the unescaped mso-number-format within a list of
+// style declarations.
+func numberFormat(decls string) (string, bool) {
+	for _, decl := range splitDeclarations(decls) {
+		i := strings.IndexByte(decl, ':')
+		if i < 0 {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(decl[:i]), "mso-number-format") {
+			return cssUnescape(strings.TrimSpace(decl[i+1:])), true
+		}
+	}
+	return "", false
+}
+
+// splitDeclarations splits style declarations on semicolons, except
+// within quotes or when escaped.
+func splitDeclarations(s string) []string {
+	var res []string
+	var quote byte
+	last := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ';':
+			res = append(res, s[last:i])
+			last = i + 1
+		}
+	}
+	return append(res, s[last:])
+}
+
+// cssUnescape removes the quotes and escapes from a CSS value, such as
+// "\#\,\#\#0\.00" or "\0022$\0022\#\,\#\#0".
+func cssUnescape(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		s = s[1 : len(s)-1]
+	}
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		j := i
+		for j < len(s) && j < i+6 && isHex(s[j]) {
+			j++
+		}
+		if j == i {
+			// escaped character
+			r, n := utf8.DecodeRuneInString(s[i:])
+			sb.WriteRune(r)
+			i += n - 1
+			continue
+		}
+		cp, _ := strconv.ParseUint(s[i:j], 16, 32)
+		sb.WriteRune(rune(cp))
+		if j < len(s) && s[j] == ' ' {
+			j++
+		}
+		i = j - 1
+	}
+	return sb.String()
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,166 @@
+package html
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// limits on spans, as in web browsers
+const (
+	maxColSpan = 1000
+	maxRowSpan = 65534
+)
+
+type table struct {
+	d       *Document
+	name    string
+	id      string
+	caption strings.Builder
+	sheet   *commonxl.Sheet
+
+	row, col  int
+	rowOpen   bool
+	numRows   int
+	covered   map[int]int // column => last row covered by a rowspan
+	merges    []commonxl.CellRange
+	inCaption bool
+
+	// current cell
+	inCell    bool
+	cellAttrs map[string]string
+	text      strings.Builder
+}
+
+func newTable(d *Document, attrs map[string]string) *table {
+	return &table{
+		d:       d,
+		id:      strings.TrimSpace(attrs["id"]),
+		sheet:   &commonxl.Sheet{},
+		row:     -1,
+		covered: make(map[int]int),
+	}
+}
+
+func (t *table) startRow() {
+	t.endCell()
+	t.row++
+	t.col = 0
+	t.rowOpen = true
+	t.numRows = t.row + 1
+}
+
+func (t *table) endRow() {
+	t.endCell()
+	t.rowOpen = false
+}
+
+func (t *table) startCell(attrs map[string]string) {
+	t.endCell()
+	if !t.rowOpen {
+		t.startRow()
+	}
+	t.inCell = true
+	t.cellAttrs = attrs
+	t.text.Reset()
+}
+
+// writeText adds text to the current cell or caption.
+func (t *table) writeText(s string) {
+	switch {
+	case t.inCell:
+		t.text.WriteString(strings.Map(newlineToSpace, s))
+	case t.inCaption:
+		t.caption.WriteString(strings.Map(newlineToSpace, s))
+	}
+}
+
+// newlineToSpace maps line breaks in the markup to spaces, as only <br>
+// and block elements start new lines in the rendered text.
+func newlineToSpace(r rune) rune {
+	if r == '\n' || r == '\r' {
+		return ' '
+	}
+	return r
+}
+
+// lineBreak adds a line break to the current cell.
+func (t *table) lineBreak() {
+	if t.inCell {
+		t.text.WriteByte('\n')
+	}
+}
+
+func (t *table) endCell() {
+	if !t.inCell {
+		return
+	}
+	t.inCell = false
+
+	for {
+		last, ok := t.covered[t.col]
+		if !ok || last < t.row {
+			break
+		}
+		t.col++
+	}
+	colspan := spanValue(t.cellAttrs["colspan"], maxColSpan)
+	rowspan := spanValue(t.cellAttrs["rowspan"], maxRowSpan)
+	if rowspan > 1 {
+		for c := t.col; c < t.col+colspan; c++ {
+			t.covered[c] = t.row + rowspan - 1
+		}
+	}
+	if colspan > 1 || rowspan > 1 {
+		t.merges = append(t.merges, commonxl.CellRange{
+			FirstRow: t.row, LastRow: t.row + rowspan - 1,
+			FirstCol: t.col, LastCol: t.col + colspan - 1,
+		})
+	}
+
+	if val := t.d.cellValue(t.cellAttrs, cleanText(t.text.String())); val != nil {
+		t.sheet.Put(t.row, t.col, val)
+	}
+	t.col += colspan
+}
+
+// finish closes any open elements and applies the merged cells, clipped to
+// the rows of the table.
+func (t *table) finish() {
+	t.endRow()
+	t.inCaption = false
+	for _, m := range t.merges {
+		if m.LastRow >= t.numRows {
+			m.LastRow = t.numRows - 1
+		}
+		t.sheet.Merge(m)
+	}
+	t.merges = nil
+	t.covered = nil
+}
+
+func spanValue(s string, max int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 {
+		return 1
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// cleanText collapses runs of whitespace within each line of text to a
+// single space, and removes blank lines.
+func cleanText(s string) string {
+	lines := strings.Split(s, "\n")
+	res := lines[:0]
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			res = append(res, line)
+		}
+	}
+	return strings.Join(res, "\n")
+}
@@ -0,0 +1,198 @@
+package html
+
+import (
+	stdhtml "html"
+	"strings"
+)
+
+type tokenType int
+
+const (
+	textToken tokenType = iota
+	startTagToken
+	endTagToken
+)
+
+type token struct {
+	typ   tokenType
+	name  string // lowercase tag name
+	attrs map[string]string
+	text  string // decoded text, or the raw contents of a script or style
+}
+
+// tokenizer splits an HTML document into text and tags. It is tolerant of
+// malformed markup: unterminated tags and comments extend to the end of
+// the document, and stray '<' characters are treated as text.
+type tokenizer struct {
+	s   string
+	pos int
+
+	// name of a raw text element (script or style) whose contents are next
+	rawTag string
+}
+
+func newTokenizer(s string) *tokenizer {
+	return &tokenizer{s: s}
+}
+
+// next returns the next token, or false at the end of the document.
+func (z *tokenizer) next() (token, bool) {
+	if z.rawTag != "" {
+		return z.rawText(), true
+	}
+	for z.pos < len(z.s) {
+		if z.s[z.pos] != '<' {
+			end := strings.IndexByte(z.s[z.pos:], '<')
+			if end < 0 {
+				end = len(z.s) - z.pos
+			}
+			text := z.s[z.pos : z.pos+end]
+			z.pos += end
+			return token{typ: textToken, text: stdhtml.UnescapeString(text)}, true
+		}
+
+		rest := z.s[z.pos:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				z.pos = len(z.s)
+			} else {
+				z.pos += 4 + end + 3
+			}
+			continue
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			// doctype, CDATA, conditional comments and processing instructions
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				z.pos = len(z.s)
+			} else {
+				z.pos += end + 1
+			}
+			continue
+		}
+
+		isEnd := len(rest) > 1 && rest[1] == '/'
+		start := 1
+		if isEnd {
+			start = 2
+		}
+		if start >= len(rest) || !isLetter(rest[start]) {
+			// not a tag
+			z.pos++
+			return token{typ: textToken, text: "<"}, true
+		}
+		tok := z.tag(start)
+		if isEnd {
+			tok.typ = endTagToken
+		} else if tok.name == "script" || tok.name == "style" {
+			z.rawTag = tok.name
+		}
+		return tok, true
+	}
+	return token{}, false
+}
+
+// tag reads a tag name and its attributes, starting at offset from the
+// current position.
+func (z *tokenizer) tag(offset int) token {
+	i := z.pos + offset
+	j := i
+	for j < len(z.s) && !isSpace(z.s[j]) && z.s[j] != '>' && z.s[j] != '/' {
+		j++
+	}
+	tok := token{typ: startTagToken, name: strings.ToLower(z.s[i:j])}
+
+	for j < len(z.s) {
+		for j < len(z.s) && (isSpace(z.s[j]) || z.s[j] == '/') {
+			j++
+		}
+		if j >= len(z.s) {
+			break
+		}
+		if z.s[j] == '>' {
+			j++
+			break
+		}
+
+		// attribute name
+		k := j
+		for k < len(z.s) && !isSpace(z.s[k]) && z.s[k] != '=' && z.s[k] != '>' && z.s[k] != '/' {
+			k++
+		}
+		if k == j {
+			// a lone '=' or similar junk
+			j++
+			continue
+		}
+		name := strings.ToLower(z.s[j:k])
+		for k < len(z.s) && isSpace(z.s[k]) {
+			k++
+		}
+		val := ""
+		if k < len(z.s) && z.s[k] == '=' {
+			k++
+			for k < len(z.s) && isSpace(z.s[k]) {
+				k++
+			}
+			if k < len(z.s) && (z.s[k] == '"' || z.s[k] == '\'') {
+				q := z.s[k]
+				end := strings.IndexByte(z.s[k+1:], q)
+				if end < 0 {
+					end = len(z.s) - k - 1
+				}
+				val = z.s[k+1 : k+1+end]
+				k += end + 2
+			} else {
+				v := k
+				for k < len(z.s) && !isSpace(z.s[k]) && z.s[k] != '>' {
+					k++
+				}
+				val = z.s[v:k]
+			}
+		}
+		if tok.attrs == nil {
+			tok.attrs = make(map[string]string)
+		}
+		if _, dup := tok.attrs[name]; !dup {
+			tok.attrs[name] = stdhtml.UnescapeString(val)
+		}
+		j = k
+	}
+	if j > len(z.s) {
+		j = len(z.s)
+	}
+	z.pos = j
+	return tok
+}
+
+// rawText returns the contents of a script or style element, up to its
+// closing tag.
+func (z *tokenizer) rawText() token {
+	closing := "</" + z.rawTag
+	z.rawTag = ""
+	end := len(z.s) - z.pos
+	for i := z.pos; i < len(z.s); {
+		k := strings.Index(z.s[i:], "</")
+		if k < 0 {
+			break
+		}
+		i += k
+		if i+len(closing) <= len(z.s) && strings.EqualFold(z.s[i:i+len(closing)], closing) {
+			end = i - z.pos
+			break
+		}
+		i += 2
+	}
+	tok := token{typ: textToken, text: z.s[z.pos : z.pos+end]}
+	z.pos += end
+	return tok
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
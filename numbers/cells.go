@@ -0,0 +1,140 @@
+package numbers
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// cell types of cell storage buffers
+const (
+	cellEmpty    = 0
+	cellNumber   = 2
+	cellText     = 3
+	cellDate     = 5
+	cellBool     = 6
+	cellDuration = 7
+	cellError    = 8
+	cellRichText = 9
+	cellCurrency = 10
+)
+
+// flags of the fields which follow the header of a cell storage buffer,
+// in the order of the fields
+const (
+	hasDecimal  = 0x1
+	hasDouble   = 0x2
+	hasSeconds  = 0x4
+	hasStringID = 0x8
+	hasRichID   = 0x10
+)
+
+// epoch of dates in Numbers documents
+var epoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// storedCell is the decoded contents of a cell storage buffer.
+type storedCell struct {
+	typ      byte
+	value    float64
+	hasValue bool
+	seconds  float64
+	stringID uint32
+	richID   uint32
+}
+
+// parseCell decodes a cell storage buffer of storage version 5. After an
+// 8-byte header with the version and cell type, and 4 bytes of flags, it
+// has the fields indicated by the flags.
+func parseCell(buf []byte) (storedCell, bool) {
+	var c storedCell
+	if len(buf) < 12 || buf[0] != 5 {
+		return c, false
+	}
+	c.typ = buf[1]
+	flags := binary.LittleEndian.Uint32(buf[8:])
+	buf = buf[12:]
+	if flags&hasDecimal != 0 {
+		if len(buf) < 16 {
+			return c, false
+		}
+		c.value, c.hasValue = decimal128(buf[:16]), true
+		buf = buf[16:]
+	}
+	if flags&hasDouble != 0 {
+		if len(buf) < 8 {
+			return c, false
+		}
+		if !c.hasValue {
+			c.value, c.hasValue = math.Float64frombits(binary.LittleEndian.Uint64(buf)), true
+		}
+		buf = buf[8:]
+	}
+	if flags&hasSeconds != 0 {
+		if len(buf) < 8 {
+			return c, false
+		}
+		c.seconds = math.Float64frombits(binary.LittleEndian.Uint64(buf))
+		buf = buf[8:]
+	}
+	if flags&hasStringID != 0 {
+		if len(buf) < 4 {
+			return c, false
+		}
+		c.stringID = binary.LittleEndian.Uint32(buf)
+		buf = buf[4:]
+	}
+	if flags&hasRichID != 0 {
+		if len(buf) < 4 {
+			return c, false
+		}
+		c.richID = binary.LittleEndian.Uint32(buf)
+	}
+	return c, true
+}
+
+// decimal128 converts an IEEE 754 decimal128 value in the binary integer
+// decimal encoding, as used by Numbers, to a float64.
+func decimal128(b []byte) float64 {
+	exp := (int(b[15]&0x7F)<<7 | int(b[14])>>1) - 0x1820
+	mant := new(big.Int).SetUint64(uint64(b[14] & 1))
+	for i := 13; i >= 0; i-- {
+		mant.Lsh(mant, 8)
+		mant.Or(mant, big.NewInt(int64(b[i])))
+	}
+	s := mant.String() + "e" + strconv.Itoa(exp)
+	if b[15]&0x80 != 0 {
+		s = "-" + s
+	}
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// cellValue returns the value of a cell, looking up its text in the
+// string and rich text tables of the table.
+func (t *tableData) cellValue(c storedCell) interface{} {
+	switch c.typ {
+	case cellNumber, cellCurrency, cellDuration:
+		// durations are in seconds
+		if c.hasValue {
+			return c.value
+		}
+	case cellText:
+		if s, ok := t.strings[c.stringID]; ok && s != "" {
+			return s
+		}
+	case cellDate:
+		ms := math.Round(c.seconds * 1000)
+		return epoch.Add(time.Duration(ms) * time.Millisecond)
+	case cellBool:
+		return c.value != 0
+	case cellError:
+		return "#ERROR"
+	case cellRichText:
+		if s, ok := t.richText[c.richID]; ok && s != "" {
+			return s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package numbers
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// maxChunkSize limits the uncompressed size of a chunk of an IWA file.
+const maxChunkSize = 64 << 20
+
+var errCorrupt = errors.New("numbers: corrupt IWA data")
+
+// decodeIWA decompresses an IWA file, which is a series of chunks each with
+// a type byte of zero, a 3-byte little-endian length and a snappy block.
+// Unlike the snappy framing format, there are no checksums.
+func decodeIWA(data []byte) ([]byte, error) {
+	var res []byte
+	for len(data) > 0 {
+		if len(data) < 4 || data[0] != 0 {
+			return nil, errCorrupt
+		}
+		n := int(data[1]) | int(data[2])<<8 | int(data[3])<<16
+		if len(data) < 4+n {
+			return nil, errCorrupt
+		}
+		var err error
+		res, err = decodeSnappy(res, data[4:4+n])
+		if err != nil {
+			return nil, err
+		}
+		data = data[4+n:]
+	}
+	return res, nil
+}
+
+// decodeSnappy appends the decompressed snappy block src to dst.
+func decodeSnappy(dst, src []byte) ([]byte, error) {
+	size, n := binary.Uvarint(src)
+	if n <= 0 || size > maxChunkSize {
+		return nil, errCorrupt
+	}
+	src = src[n:]
+	start := len(dst)
+	for len(src) > 0 {
+		tag := src[0]
+		var length, offset int
+		switch tag & 3 {
+		case 0:
+			// literal, with the length in the tag or the following bytes
+			length = int(tag>>2) + 1
+			src = src[1:]
+			if length > 60 {
+				nb := length - 60
+				if len(src) < nb {
+					return nil, errCorrupt
+				}
+				length = 0
+				for i := nb - 1; i >= 0; i-- {
+					length = length<<8 | int(src[i])
+				}
+				length++
+				src = src[nb:]
+			}
+			if length <= 0 || len(src) < length || uint64(len(dst)-start+length) > size {
+				return nil, errCorrupt
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+		case 1:
+			if len(src) < 2 {
+				return nil, errCorrupt
+			}
+			length = 4 + int(tag>>2)&7
+			offset = int(tag&0xE0)<<3 | int(src[1])
+			src = src[2:]
+		case 2:
+			if len(src) < 3 {
+				return nil, errCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+		case 3:
+			if len(src) < 5 {
+				return nil, errCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+		}
+		if offset <= 0 || offset > len(dst)-start || uint64(len(dst)-start+length) > size {
+			return nil, errCorrupt
+		}
+		// copies may overlap the bytes they produce
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	if uint64(len(dst)-start) != size {
+		return nil, errCorrupt
+	}
+	return dst, nil
+}
+
+// object is a message stored in an IWA archive.
+type object struct {
+	typ  uint32
+	data []byte
+}
+
+// readArchives adds the objects of decompressed IWA data to objects. Each
+// object is an ArchiveInfo message, prefixed by its length, describing the
+// messages which follow it. Only the first message of an object is kept,
+// and only if keep reports that its type is needed.
+func readArchives(data []byte, objects map[uint64]object, keep func(uint32) bool) error {
+	for len(data) > 0 {
+		n, k := binary.Uvarint(data)
+		if k <= 0 || n > uint64(len(data)-k) {
+			return errCorrupt
+		}
+		info, err := parseMessage(data[k : k+int(n)])
+		if err != nil {
+			return err
+		}
+		data = data[k+int(n):]
+
+		id := info.uint(1)
+		for i, mi := range info.subs(2) {
+			length := mi.uint(3)
+			if length > uint64(len(data)) {
+				return errCorrupt
+			}
+			typ := uint32(mi.uint(1))
+			if i == 0 && keep(typ) {
+				objects[id] = object{typ: typ, data: data[:length]}
+			}
+			data = data[length:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+// Package numbers reads the tables of Apple Numbers (.numbers) documents.
+// A document is a zip package of IWA archives, which are snappy-compressed
+// streams of protobuf messages. Each table of each sheet is a separate
+// collection.
+package numbers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+var _ = grate.Register("numbers", 16, Open)
+
+// types of the archived messages which are read
+const (
+	typeDocument   = 1    // TN.DocumentArchive
+	typeSheet      = 2    // TN.SheetArchive
+	typeStorage    = 2001 // TSWP.StorageArchive
+	typeTableInfo  = 6000 // TST.TableInfoArchive
+	typeTableModel = 6001 // TST.TableModelArchive
+	typeTile       = 6002 // TST.Tile
+	typeDataList   = 6005 // TST.TableDataList
+	typeRichText   = 6218 // TST.RichTextPayloadArchive
+)
+
+// limits on the cells of a table
+const (
+	maxRows = 1 << 20
+	maxCols = 1 << 14
+)
+
+// defaultTileSize is the number of rows of a tile.
+const defaultTileSize = 256
+
+// Document contains the tables of a Numbers document.
+type Document struct {
+	tables []*table
+}
+
+type table struct {
+	name  string
+	sheet *commonxl.Sheet
+}
+
+// tableData holds the shared strings of a table while its cells are read.
+type tableData struct {
+	strings  map[uint32]string
+	richText map[uint32]string
+}
+
+// Open a Numbers document, and read all of its tables.
+func Open(filename string) (grate.Source, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	z, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+	}
+	objects, err := readObjects(z)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pages and Keynote documents also use IWA archives
+	for id, obj := range objects {
+		if obj.typ == typeDocument {
+			d := &Document{}
+			d.readDocument(objects, id)
+			d.nameTables()
+			return d, nil
+		}
+	}
+	return nil, grate.ErrNotInFormat
+}
+
+// readObjects reads the needed objects of the IWA archives in a package.
+// Documents from Numbers 2013 have their archives in a nested Index.zip.
+func readObjects(z *zip.Reader) (map[uint64]object, error) {
+	var archives []*zip.File
+	for _, zf := range z.File {
+		if path.Ext(zf.Name) == ".iwa" {
+			archives = append(archives, zf)
+		}
+	}
+	if len(archives) == 0 {
+		for _, zf := range z.File {
+			if path.Base(zf.Name) == "Index.zip" {
+				data, err := readFile(zf)
+				if err != nil {
+					return nil, err
+				}
+				nz, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+				if err != nil {
+					return nil, err
+				}
+				return readObjects(nz)
+			}
+		}
+		return nil, grate.ErrNotInFormat
+	}
+
+	objects := make(map[uint64]object)
+	keep := func(typ uint32) bool {
+		switch typ {
+		case typeDocument, typeSheet, typeStorage, typeTableInfo, typeTableModel,
+			typeTile, typeDataList, typeRichText:
+			return true
+		}
+		return false
+	}
+	for _, zf := range archives {
+		data, err := readFile(zf)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = decodeIWA(data); err != nil {
+			return nil, err
+		}
+		if err = readArchives(data, objects, keep); err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+func readFile(zf *zip.File) ([]byte, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// get returns the message of an object of the given type.
+func get(objects map[uint64]object, id uint64, typ uint32) (message, bool) {
+	obj, ok := objects[id]
+	if !ok || obj.typ != typ {
+		return nil, false
+	}
+	m, err := parseMessage(obj.data)
+	return m, err == nil
+}
+
+// readDocument reads the tables of each sheet of the document, in order.
+// Other drawables, such as charts and text boxes, are ignored.
+func (d *Document) readDocument(objects map[uint64]object, id uint64) {
+	doc, _ := get(objects, id, typeDocument)
+	for _, sheetID := range doc.refs(1) {
+		sheet, ok := get(objects, sheetID, typeSheet)
+		if !ok {
+			continue
+		}
+		var tables []*table
+		for _, drawableID := range sheet.refs(2) {
+			info, ok := get(objects, drawableID, typeTableInfo)
+			if !ok {
+				continue
+			}
+			model, ok := get(objects, info.ref(2), typeTableModel)
+			if !ok {
+				continue
+			}
+			tables = append(tables, readTable(objects, model))
+		}
+		for _, t := range tables {
+			if len(tables) > 1 {
+				t.name = sheet.str(1) + " - " + t.name
+			} else {
+				t.name = sheet.str(1)
+			}
+		}
+		d.tables = append(d.tables, tables...)
+	}
+}
+
+// readTable reads the cells of a table from the tiles of its data store.
+func readTable(objects map[uint64]object, model message) *table {
+	t := &table{name: model.str(8), sheet: &commonxl.Sheet{}}
+	rows, cols := int(model.uint(6)), int(model.uint(7))
+	if rows > maxRows {
+		rows = maxRows
+	}
+	if cols > maxCols {
+		cols = maxCols
+	}
+	// rows are added as cells are placed, so empty rows at the end of the
+	// table are dropped
+	t.sheet.Resize(0, cols)
+
+	store := model.sub(4)
+	td := &tableData{
+		strings:  readStrings(objects, store.ref(4)),
+		richText: readRichText(objects, store.ref(17)),
+	}
+	tiles := store.sub(3)
+	tileSize := int(tiles.uint(2))
+	if tileSize == 0 {
+		tileSize = defaultTileSize
+	}
+	for _, tr := range tiles.subs(1) {
+		tile, ok := get(objects, tr.ref(2), typeTile)
+		if !ok {
+			continue
+		}
+		start := int(tr.uint(1)) * tileSize
+		for _, ri := range tile.subs(5) {
+			row := start + int(ri.uint(1))
+			if row >= rows {
+				continue
+			}
+			buf, offsets := ri.bytes(6), ri.bytes(7)
+			wide := ri.uint(8) != 0
+			for col := 0; col < cols && 2*col+2 <= len(offsets); col++ {
+				off := int(binary.LittleEndian.Uint16(offsets[2*col:]))
+				if off == 0xFFFF {
+					continue
+				}
+				if wide {
+					off *= 4
+				}
+				if off >= len(buf) {
+					continue
+				}
+				if c, ok := parseCell(buf[off:]); ok {
+					if v := td.cellValue(c); v != nil {
+						t.sheet.Put(row, col, v)
+					}
+				}
+			}
+		}
+	}
+	return t
+}
+
+// readStrings returns the strings of a TableDataList by key.
+func readStrings(objects map[uint64]object, id uint64) map[uint32]string {
+	res := make(map[uint32]string)
+	list, _ := get(objects, id, typeDataList)
+	for _, e := range list.subs(3) {
+		res[uint32(e.uint(1))] = e.str(3)
+	}
+	return res
+}
+
+// readRichText returns the text of the rich text payloads of a
+// TableDataList by key.
+func readRichText(objects map[uint64]object, id uint64) map[uint32]string {
+	res := make(map[uint32]string)
+	list, _ := get(objects, id, typeDataList)
+	for _, e := range list.subs(3) {
+		payload, ok := get(objects, e.ref(9), typeRichText)
+		if !ok {
+			continue
+		}
+		storage, ok := get(objects, payload.ref(1), typeStorage)
+		if !ok {
+			continue
+		}
+		var text []string
+		for _, f := range storage {
+			if f.num == 3 && f.wire == wireBytes {
+				text = append(text, string(f.data))
+			}
+		}
+		res[uint32(e.uint(1))] = strings.TrimSpace(strings.Join(text, ""))
+	}
+	return res
+}
+
+// nameTables ensures that the name of every table is unique.
+func (d *Document) nameTables() {
+	seen := make(map[string]bool)
+	for i, t := range d.tables {
+		name := strings.TrimSpace(t.name)
+		if name == "" {
+			name = "Table" + strconv.Itoa(i+1)
+		}
+		base := name
+		for n := 2; seen[name]; n++ {
+			name = base + " (" + strconv.Itoa(n) + ")"
+		}
+		seen[name] = true
+		t.name = name
+	}
+}
+
+// Close the document.
+func (d *Document) Close() error {
+	d.tables = nil
+	return nil
+}
+
+// List returns the names of the tables in the document.
+func (d *Document) List() ([]string, error) {
+	res := make([]string, 0, len(d.tables))
+	for _, t := range d.tables {
+		res = append(res, t.name)
+	}
+	return res, nil
+}
+
+// Get the named table.
+func (d *Document) Get(name string) (grate.Collection, error) {
+	for _, t := range d.tables {
+		if t.name == name {
+			t.sheet.Rewind()
+			return t.sheet, nil
+		}
+	}
+	return nil, errors.New("numbers: table not found")
+}
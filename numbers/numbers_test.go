@@ -0,0 +1,287 @@
+package numbers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate"
+)
+
+// protobuf encoding helpers
+
+func uvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func pv(num int, v uint64) []byte {
+	b := uvarint(nil, uint64(num)<<3|wireVarint)
+	return uvarint(b, v)
+}
+
+func pb(num int, parts ...[]byte) []byte {
+	data := bytes.Join(parts, nil)
+	b := uvarint(nil, uint64(num)<<3|wireBytes)
+	b = uvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func ps(num int, s string) []byte {
+	return pb(num, []byte(s))
+}
+
+func ref(num int, id uint64) []byte {
+	return pb(num, pv(1, id))
+}
+
+type testObject struct {
+	id   uint64
+	typ  uint32
+	data []byte
+}
+
+// iwa encodes objects as an IWA archive, compressed in chunks of snappy
+// literals.
+func iwa(objs ...testObject) []byte {
+	var stream []byte
+	for _, o := range objs {
+		info := append(pv(1, o.id), pb(2, pv(1, uint64(o.typ)), pv(3, uint64(len(o.data))))...)
+		stream = uvarint(stream, uint64(len(info)))
+		stream = append(stream, info...)
+		stream = append(stream, o.data...)
+	}
+	var res []byte
+	for len(stream) > 0 {
+		n := len(stream)
+		if n > 1000 {
+			n = 1000
+		}
+		block := uvarint(nil, uint64(n))
+		switch {
+		case n <= 60:
+			block = append(block, byte(n-1)<<2)
+		case n <= 256:
+			block = append(block, 60<<2, byte(n-1))
+		default:
+			block = append(block, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		block = append(block, stream[:n]...)
+		stream = stream[n:]
+		res = append(res, 0, byte(len(block)), byte(len(block)>>8), byte(len(block)>>16))
+		res = append(res, block...)
+	}
+	return res
+}
+
+// cell returns a cell storage buffer.
+func cell(typ byte, flags uint32, fields ...[]byte) []byte {
+	b := []byte{5, typ, 0, 0, 0, 0, 0, 0}
+	b = append(b, u32(flags)...)
+	return append(b, bytes.Join(fields, nil)...)
+}
+
+func dec128(mant uint64, exp int) []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint64(b, mant)
+	e := exp + 0x1820
+	b[14] = byte(e&0x7F) << 1
+	b[15] = byte(e>>7) & 0x7F
+	return b
+}
+
+func double(f float64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, math.Float64bits(f))
+	return b
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+// rowInfo returns a TileRowInfo with the cells of a row, where nil cells
+// are empty.
+func rowInfo(index int, wide bool, cells ...[]byte) []byte {
+	var buf, offsets []byte
+	for _, c := range cells {
+		off := uint16(0xFFFF)
+		if c != nil {
+			off = uint16(len(buf))
+			if wide {
+				off /= 4
+			}
+			buf = append(buf, c...)
+		}
+		offsets = append(offsets, byte(off), byte(off>>8))
+	}
+	res := bytes.Join([][]byte{pv(1, uint64(index)), pv(2, uint64(len(cells))), pv(5, 5),
+		pb(6, buf), pb(7, offsets)}, nil)
+	if wide {
+		res = append(res, pv(8, 1)...)
+	}
+	return res
+}
+
+func entry(key uint64, parts ...[]byte) []byte {
+	return pb(3, pv(1, key), pv(2, 1), bytes.Join(parts, nil))
+}
+
+func writeTestFile(t *testing.T) string {
+	store := bytes.Join([][]byte{
+		pb(3, pb(1, pv(1, 0), ref(2, 5)), pb(1, pv(1, 1), ref(2, 8)), pv(2, 2)),
+		ref(4, 6),
+		ref(17, 7),
+	}, nil)
+	doc := iwa(
+		testObject{1, typeDocument, append(ref(1, 2), ref(1, 20)...)},
+		testObject{2, typeSheet, bytes.Join([][]byte{ps(1, "Sheet 1"), ref(2, 30), ref(2, 3)}, nil)},
+		testObject{3, typeTableInfo, ref(2, 4)},
+		testObject{4, typeTableModel, bytes.Join([][]byte{ps(1, "T1"), ps(8, "Table 1"),
+			pv(6, 5), pv(7, 3), pb(4, store)}, nil)},
+		testObject{6, typeDataList, bytes.Join([][]byte{pv(1, 1), pv(2, 5),
+			entry(1, ps(3, "Item")), entry(2, ps(3, "Price")), entry(3, ps(3, "When")),
+			entry(4, ps(3, "Widget"))}, nil)},
+		testObject{7, typeDataList, bytes.Join([][]byte{pv(1, 8), pv(2, 2), entry(1, ref(9, 10))}, nil)},
+		testObject{10, typeRichText, ref(1, 11)},
+		testObject{11, typeStorage, bytes.Join([][]byte{ps(3, "Bold "), ps(3, "note\n")}, nil)},
+		testObject{20, typeSheet, bytes.Join([][]byte{ps(1, "Summary"), ref(2, 21), ref(2, 22)}, nil)},
+		testObject{21, typeTableInfo, ref(2, 23)},
+		testObject{22, typeTableInfo, ref(2, 24)},
+		testObject{23, typeTableModel, append(ps(8, "Totals"), pv(7, 1)...)},
+		testObject{24, typeTableModel, append(ps(8, "Totals"), pv(7, 1)...)},
+		testObject{30, 3002, ps(1, "a chart")},
+	)
+	tiles := iwa(
+		testObject{5, typeTile, bytes.Join([][]byte{pv(1, 2), pv(2, 1), pv(3, 6), pv(4, 2),
+			pb(5, rowInfo(0, false,
+				cell(cellText, hasStringID, u32(1)),
+				cell(cellText, hasStringID, u32(2)),
+				cell(cellText, hasStringID, u32(3)))),
+			pb(5, rowInfo(1, false,
+				cell(cellText, hasStringID, u32(4)),
+				cell(cellNumber, hasDecimal|hasDouble, dec128(12505, -1), double(1250.5)),
+				cell(cellDate, hasSeconds, double(86400)))),
+		}, nil)},
+		testObject{8, typeTile, bytes.Join([][]byte{pv(1, 2), pv(2, 1), pv(3, 5), pv(4, 2),
+			pb(5, rowInfo(0, true,
+				cell(cellRichText, hasRichID, u32(1)),
+				cell(cellCurrency, hasDouble, double(-3.25)),
+				cell(cellBool, hasDouble, double(1)))),
+			pb(5, rowInfo(1, true,
+				cell(cellDuration, hasDouble, double(5400)),
+				cell(cellError, 0),
+				nil)),
+		}, nil)},
+	)
+
+	fn := filepath.Join(t.TempDir(), "budget.numbers")
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	for name, data := range map[string][]byte{
+		"Index/Document.iwa":          doc,
+		"Index/Tables/Tile-1.iwa":     tiles,
+		"Metadata/DocumentIdentifier": []byte("8C4E1A2B"),
+	} {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err = z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return fn
+}
+
+func readAll(t *testing.T, src grate.Source, name string) [][]string {
+	t.Helper()
+	c, err := src.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res [][]string
+	for c.Next() {
+		res = append(res, c.Strings())
+	}
+	return res
+}
+
+func TestTables(t *testing.T) {
+	src, err := Open(writeTestFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	names, _ := src.List()
+	if want := []string{"Sheet 1", "Summary - Totals", "Summary - Totals (2)"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got tables %q, want %q", names, want)
+	}
+
+	want := [][]string{
+		{"Item", "Price", "When"},
+		{"Widget", "1250.5", "2001-01-02"},
+		{"Bold note", "-3.25", "true"},
+		{"5400", "#ERROR", ""},
+	}
+	if got := readAll(t, src, "Sheet 1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := readAll(t, src, "Summary - Totals"); len(got) != 0 {
+		t.Errorf("got %q, want no rows", got)
+	}
+}
+
+func TestSnappy(t *testing.T) {
+	// a literal, an overlapping copy with a 1-byte offset and a copy with a
+	// 2-byte offset
+	block := []byte{14, 3 << 2, 'a', 'b', 'c', 'd', (8-4)<<2 | 1, 4, (2-1)<<2 | 2, 4, 0}
+	got, err := decodeSnappy(nil, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "abcdabcdabcdab" {
+		t.Errorf("got %q", got)
+	}
+	if _, err := decodeSnappy(nil, []byte{4, 0, 'a', 1<<2 | 1, 2}); err == nil {
+		t.Error("expected an error for an offset before the start")
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "text.numbers")
+	if err := os.WriteFile(fn, []byte("not a zip file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(fn); !errors.Is(err, grate.ErrNotInFormat) {
+		t.Errorf("got error %v, want ErrNotInFormat", err)
+	}
+
+	fn = filepath.Join(dir, "other.zip")
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	w, _ := z.Create("readme.txt")
+	w.Write([]byte("hello"))
+	z.Close()
+	f.Close()
+	if _, err := Open(fn); !errors.Is(err, grate.ErrNotInFormat) {
+		t.Errorf("got error %v, want ErrNotInFormat", err)
+	}
+}
@@ -0,0 +1,132 @@
+package numbers
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errProto = errors.New("numbers: invalid protobuf message")
+
+// protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// field is a field of a protobuf message.
+type field struct {
+	num  int
+	wire int
+	val  uint64 // value of varint and fixed fields
+	data []byte // value of length-delimited fields
+}
+
+// message is a decoded protobuf message. Only the wire format is decoded,
+// so fields are read by number.
+type message []field
+
+func parseMessage(b []byte) (message, error) {
+	var m message
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errProto
+		}
+		b = b[n:]
+		f := field{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			f.val, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, errProto
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, errProto
+			}
+			f.val = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return nil, errProto
+			}
+			f.data = b[n : n+int(size)]
+			b = b[n+int(size):]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, errProto
+			}
+			f.val = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		default:
+			return nil, errProto
+		}
+		m = append(m, f)
+	}
+	return m, nil
+}
+
+// uint returns the value of the last numeric field with the number, or 0.
+func (m message) uint(num int) uint64 {
+	var res uint64
+	for _, f := range m {
+		if f.num == num && f.wire != wireBytes {
+			res = f.val
+		}
+	}
+	return res
+}
+
+// bytes returns the value of the last length-delimited field with the
+// number, or nil.
+func (m message) bytes(num int) []byte {
+	var res []byte
+	for _, f := range m {
+		if f.num == num && f.wire == wireBytes {
+			res = f.data
+		}
+	}
+	return res
+}
+
+func (m message) str(num int) string {
+	return string(m.bytes(num))
+}
+
+// sub returns the embedded message with the number, which is empty if
+// the field is missing or invalid.
+func (m message) sub(num int) message {
+	res, _ := parseMessage(m.bytes(num))
+	return res
+}
+
+// subs returns the valid embedded messages of a repeated field.
+func (m message) subs(num int) []message {
+	var res []message
+	for _, f := range m {
+		if f.num == num && f.wire == wireBytes {
+			if sub, err := parseMessage(f.data); err == nil {
+				res = append(res, sub)
+			}
+		}
+	}
+	return res
+}
+
+// ref returns the object identifier of an embedded TSP.Reference.
+func (m message) ref(num int) uint64 {
+	return m.sub(num).uint(1)
+}
+
+// refs returns the object identifiers of a repeated TSP.Reference field.
+func (m message) refs(num int) []uint64 {
+	var res []uint64
+	for _, r := range m.subs(num) {
+		res = append(res, r.uint(1))
+	}
+	return res
+}
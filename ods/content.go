@@ -0,0 +1,377 @@
+package ods
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// limits of the cell grid, used to bound repeated rows and columns
+const (
+	maxRows = 1 << 20
+	maxCols = 1 << 14
+)
+
+// cellValue is a decoded cell waiting to be placed, once the number of
+// times its row is repeated is known.
+type cellValue struct {
+	col int
+	val interface{}
+}
+
+// contentParser holds the state while decoding the body of a document.
+type contentParser struct {
+	d *Document
+
+	hiddenStyles map[string]bool
+	styleName    string // current table style
+
+	cur        *table
+	tableDepth int
+	row, col   int
+	rowRepeat  int
+	rowCells   []cellValue
+	rowMerges  []commonxl.CellRange
+	merges     []commonxl.CellRange
+
+	// current cell
+	inCell    bool
+	cellAttrs []string
+	text      strings.Builder
+	paras     int
+	textDepth int
+	annDepth  int
+}
+
+func (d *Document) parseContent(dec *xml.Decoder) error {
+	p := &contentParser{
+		d:            d,
+		hiddenStyles: make(map[string]bool),
+	}
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			p.start(v)
+		case xml.EndElement:
+			p.end(v)
+		case xml.CharData:
+			if p.inCell && p.textDepth > 0 && p.annDepth == 0 {
+				p.text.Write(v)
+			}
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+
+	for _, t := range d.tables {
+		t.hidden = p.hiddenStyles[t.styleName]
+	}
+	return err
+}
+
+func (p *contentParser) start(v xml.StartElement) {
+	if p.annDepth > 0 {
+		// comments attached to a cell are not part of its content
+		if v.Name.Local == "annotation" {
+			p.annDepth++
+		}
+		return
+	}
+	if p.tableDepth > 1 {
+		// sub-tables nested within a cell are not supported
+		if v.Name.Local == "table" {
+			p.tableDepth++
+		}
+		return
+	}
+
+	switch v.Name.Local {
+	case "style":
+		ax := getAttrs(v.Attr, "name", "family")
+		p.styleName = ""
+		if ax[1] == "table" {
+			p.styleName = ax[0]
+		}
+	case "table-properties":
+		ax := getAttrs(v.Attr, "display")
+		if p.styleName != "" && ax[0] == "false" {
+			p.hiddenStyles[p.styleName] = true
+		}
+
+	case "named-range":
+		ax := getAttrs(v.Attr, "name", "cell-range-address")
+		if nr, ok := parseNamedRange(ax[0], ax[1]); ok {
+			p.d.names = append(p.d.names, nr)
+		}
+
+	case "table":
+		p.tableDepth++
+		if p.tableDepth > 1 {
+			return
+		}
+		ax := getAttrs(v.Attr, "name", "style-name")
+		p.cur = &table{
+			name:      ax[0],
+			styleName: ax[1],
+			sheet:     &commonxl.Sheet{},
+		}
+		p.row = 0
+		p.merges = p.merges[:0]
+	case "table-row":
+		if p.cur == nil {
+			return
+		}
+		ax := getAttrs(v.Attr, "number-rows-repeated")
+		p.col = 0
+		p.rowRepeat = repeatCount(ax[0])
+		p.rowCells = p.rowCells[:0]
+		p.rowMerges = p.rowMerges[:0]
+	case "table-cell", "covered-table-cell":
+		if p.cur == nil {
+			return
+		}
+		p.inCell = true
+		p.cellAttrs = getAttrs(v.Attr, "number-columns-repeated",
+			"number-columns-spanned", "number-rows-spanned",
+			"value-type", "value", "date-value", "time-value",
+			"boolean-value", "string-value")
+		p.text.Reset()
+		p.paras = 0
+
+	case "p", "h":
+		if !p.inCell {
+			return
+		}
+		if p.textDepth == 0 {
+			if p.paras > 0 {
+				p.text.WriteByte('\n')
+			}
+			p.paras++
+		}
+		p.textDepth++
+	case "s":
+		if p.inCell && p.textDepth > 0 {
+			n := repeatCount(getAttrs(v.Attr, "c")[0])
+			p.text.WriteString(strings.Repeat(" ", n))
+		}
+	case "tab":
+		if p.inCell && p.textDepth > 0 {
+			p.text.WriteByte('\t')
+		}
+	case "line-break":
+		if p.inCell && p.textDepth > 0 {
+			p.text.WriteByte('\n')
+		}
+	case "annotation":
+		p.annDepth++
+
+	case "document", "document-content", "body", "spreadsheet", "automatic-styles",
+		"table-column", "table-columns", "table-header-columns", "table-column-group",
+		"table-rows", "table-header-rows", "table-row-group", "named-expressions":
+		// containers, or not needed
+	default:
+		if grate.Debug && p.inCell {
+			log.Println("      Unhandled cell xml tag", v.Name.Local, v.Attr)
+		}
+	}
+}
+
+func (p *contentParser) end(v xml.EndElement) {
+	if p.annDepth > 0 {
+		if v.Name.Local == "annotation" {
+			p.annDepth--
+		}
+		return
+	}
+	if p.tableDepth > 1 {
+		if v.Name.Local == "table" {
+			p.tableDepth--
+		}
+		return
+	}
+
+	switch v.Name.Local {
+	case "style":
+		p.styleName = ""
+	case "p", "h":
+		if p.textDepth > 0 {
+			p.textDepth--
+		}
+	case "table-cell", "covered-table-cell":
+		if p.inCell {
+			p.endCell()
+		}
+	case "table-row":
+		if p.cur != nil {
+			p.endRow()
+		}
+	case "table":
+		if p.tableDepth == 0 {
+			return
+		}
+		p.tableDepth--
+		for _, m := range p.merges {
+			p.cur.sheet.Merge(m)
+		}
+		p.d.tables = append(p.d.tables, p.cur)
+		p.cur = nil
+	}
+}
+
+func (p *contentParser) endCell() {
+	p.inCell = false
+	ax := p.cellAttrs
+	repeat := repeatCount(ax[0])
+	val := cellContent(ax[3:], p.text.String())
+	if val != nil {
+		for i := 0; i < repeat && p.col+i < maxCols; i++ {
+			p.rowCells = append(p.rowCells, cellValue{p.col + i, val})
+		}
+	}
+
+	cols, rows := repeatCount(ax[1]), repeatCount(ax[2])
+	if cols > 1 || rows > 1 {
+		p.rowMerges = append(p.rowMerges, commonxl.CellRange{
+			FirstCol: p.col, LastCol: p.col + cols - 1,
+			LastRow: rows - 1,
+		})
+	}
+	p.col += repeat
+}
+
+func (p *contentParser) endRow() {
+	if len(p.rowCells) > 0 || len(p.rowMerges) > 0 {
+		for r := p.row; r < p.row+p.rowRepeat && r < maxRows; r++ {
+			for _, c := range p.rowCells {
+				p.cur.sheet.Put(r, c.col, c.val)
+			}
+			for _, m := range p.rowMerges {
+				m.FirstRow += r
+				m.LastRow += r
+				if m.LastRow < maxRows && m.LastCol < maxCols {
+					p.merges = append(p.merges, m)
+				}
+			}
+		}
+	}
+	p.row += p.rowRepeat
+}
+
+// repeatCount parses a repetition or span attribute, which defaults to 1.
+func repeatCount(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// cellContent decodes a cell value from its value-type, value, date-value,
+// time-value, boolean-value and string-value attributes, falling back to
+// the text content of the cell.
+func cellContent(ax []string, text string) interface{} {
+	switch ax[0] {
+	case "float", "percentage", "currency":
+		if f, err := strconv.ParseFloat(ax[1], 64); err == nil {
+			return f
+		}
+	case "date":
+		if t, ok := parseDate(ax[2]); ok {
+			return t
+		}
+	case "time":
+		if dur, ok := parseDuration(ax[3]); ok {
+			// time values are placed on the spreadsheet epoch, like
+			// fractional day values in the Excel formats
+			return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).Add(dur)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(ax[4]); err == nil {
+			return b
+		}
+	case "string":
+		if ax[5] != "" {
+			return ax[5]
+		}
+	}
+	if text == "" {
+		return nil
+	}
+	return text
+}
+
+var dateLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	time.RFC3339Nano,
+}
+
+func parseDate(s string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseDuration parses an ISO 8601 duration such as "PT12H30M05.5S".
+func parseDuration(s string) (time.Duration, bool) {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, false
+	}
+	s = s[1:]
+
+	var dur time.Duration
+	inTime := false
+	for len(s) > 0 {
+		if s[0] == 'T' {
+			inTime = true
+			s = s[1:]
+			continue
+		}
+		i := strings.IndexAny(s, "YMWDHS")
+		if i <= 0 {
+			return 0, false
+		}
+		n, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, false
+		}
+		var unit time.Duration
+		switch s[i] {
+		case 'D':
+			unit = 24 * time.Hour
+		case 'W':
+			unit = 7 * 24 * time.Hour
+		case 'H':
+			unit = time.Hour
+		case 'M':
+			if !inTime {
+				// months have no fixed duration
+				return 0, false
+			}
+			unit = time.Minute
+		case 'S':
+			unit = time.Second
+		default:
+			return 0, false
+		}
+		dur += time.Duration(n * float64(unit))
+		s = s[i+1:]
+	}
+	if neg {
+		dur = -dur
+	}
+	return dur, true
+}
@@ -0,0 +1,144 @@
+package ods
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// NamedRange is a named block of cells defined in the document.
+type NamedRange struct {
+	Name  string
+	Sheet string
+	Range commonxl.CellRange
+}
+
+// NamedRanges returns the named ranges defined in the document.
+func (d *Document) NamedRanges() []*NamedRange {
+	return d.names
+}
+
+// GetNamedRange returns the cells within the named range.
+func (d *Document) GetNamedRange(name string) (grate.Collection, error) {
+	var nr *NamedRange
+	for _, n := range d.names {
+		if n.Name == name {
+			nr = n
+			break
+		}
+	}
+	if nr == nil {
+		return nil, errors.New("ods: named range not found")
+	}
+	var src *commonxl.Sheet
+	for _, t := range d.tables {
+		if t.name == nr.Sheet {
+			src = t.sheet
+			break
+		}
+	}
+	if src == nil {
+		return nil, errors.New("ods: sheet not found")
+	}
+
+	// ranges often span entire rows or columns, so only copy what exists
+	r := nr.Range
+	if r.LastRow >= src.NumRows() {
+		r.LastRow = src.NumRows() - 1
+	}
+	if r.LastCol >= src.NumCols() {
+		r.LastCol = src.NumCols() - 1
+	}
+	res := &commonxl.Sheet{}
+	for row := r.FirstRow; row <= r.LastRow; row++ {
+		vals := make([]interface{}, r.LastCol-r.FirstCol+1)
+		for col := range vals {
+			vals[col] = src.Get(row, r.FirstCol+col)
+		}
+		res.Append(vals...)
+	}
+	return res, nil
+}
+
+// parseNamedRange decodes a cell range address such as "$Sheet1.$A$1:.$C$10"
+// or "'My Sheet'.A1".
+func parseNamedRange(name, addr string) (*NamedRange, bool) {
+	parts := splitUnquoted(addr, ':')
+	if name == "" || len(parts) == 0 || len(parts) > 2 {
+		return nil, false
+	}
+	sheet, row, col, ok := parseCellAddress(parts[0])
+	if !ok || sheet == "" {
+		return nil, false
+	}
+	nr := &NamedRange{
+		Name:  name,
+		Sheet: sheet,
+		Range: commonxl.CellRange{FirstRow: row, LastRow: row, FirstCol: col, LastCol: col},
+	}
+	if len(parts) == 2 {
+		_, row, col, ok = parseCellAddress(parts[1])
+		if !ok {
+			return nil, false
+		}
+		nr.Range.LastRow, nr.Range.LastCol = row, col
+	}
+	if nr.Range.LastRow < nr.Range.FirstRow || nr.Range.LastCol < nr.Range.FirstCol {
+		return nil, false
+	}
+	return nr, true
+}
+
+// parseCellAddress decodes a cell address such as "$Sheet1.$B$2", returning
+// the (possibly empty) sheet name and the 0-based row and column.
+func parseCellAddress(s string) (sheet string, row, col int, ok bool) {
+	parts := splitUnquoted(s, '.')
+	ref := parts[len(parts)-1]
+	if len(parts) > 1 {
+		sheet = strings.Join(parts[:len(parts)-1], ".")
+		sheet = strings.TrimPrefix(sheet, "$")
+		if len(sheet) >= 2 && sheet[0] == '\'' && sheet[len(sheet)-1] == '\'' {
+			sheet = strings.Replace(sheet[1:len(sheet)-1], "''", "'", -1)
+		}
+	}
+
+	ref = strings.Replace(ref, "$", "", -1)
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 || i == len(ref) || col > maxCols {
+		return "", 0, 0, false
+	}
+	for _, c := range ref[i:] {
+		if c < '0' || c > '9' || row > maxRows {
+			return "", 0, 0, false
+		}
+		row = row*10 + int(c-'0')
+	}
+	if row == 0 {
+		return "", 0, 0, false
+	}
+	return sheet, row - 1, col - 1, true
+}
+
+// splitUnquoted splits s around sep, ignoring separators within quotes.
+func splitUnquoted(s string, sep byte) []string {
+	var res []string
+	quoted := false
+	last := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				res = append(res, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(res, s[last:])
+}
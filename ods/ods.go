@@ -0,0 +1,190 @@
+// Package ods reads OpenDocument Spreadsheets, both as zipped packages
+// (.ods, .ots) and as single flat XML documents (.fods).
+package ods
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+var _ = grate.Register("ods", 7, Open)
+
+const mimeTypeSpreadsheet = "application/vnd.oasis.opendocument.spreadsheet"
+
+// Document contains an OpenDocument Spreadsheet.
+type Document struct {
+	filename string
+	tables   []*table
+	names    []*NamedRange
+}
+
+type table struct {
+	name      string
+	styleName string
+	hidden    bool
+	sheet     *commonxl.Sheet
+}
+
+// Open an OpenDocument Spreadsheet package or flat XML document.
+func Open(filename string) (grate.Source, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(4)
+	if bytes.Equal(magic, []byte("PK\x03\x04")) {
+		return openPackage(filename, f, info.Size())
+	}
+	return openFlat(filename, br)
+}
+
+// openPackage reads the content.xml part of a zipped document.
+func openPackage(filename string, f *os.File, size int64) (grate.Source, error) {
+	z, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+	}
+	var content *zip.File
+	mimeType := ""
+	for _, zf := range z.File {
+		switch zf.Name {
+		case "mimetype":
+			rc, err := zf.Open()
+			if err != nil {
+				return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+			}
+			b, err := ioutil.ReadAll(io.LimitReader(rc, 256))
+			rc.Close()
+			if err != nil {
+				return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+			}
+			mimeType = strings.TrimSpace(string(b))
+		case "content.xml":
+			content = zf
+		}
+	}
+	if !isSpreadsheet(mimeType) {
+		return nil, grate.ErrNotInFormat
+	}
+	if content == nil {
+		return nil, errors.New("ods: content.xml not found")
+	}
+
+	rc, err := content.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	d := &Document{filename: filename}
+	if err = d.parseContent(xml.NewDecoder(rc)); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// openFlat reads a flat XML document, which has an office:document root
+// element declaring the mime type of the document.
+func openFlat(filename string, r io.Reader) (grate.Source, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+		}
+		switch v := tok.(type) {
+		case xml.ProcInst, xml.Comment, xml.Directive:
+			continue
+		case xml.CharData:
+			if len(bytes.TrimSpace(v)) == 0 {
+				continue
+			}
+			return nil, grate.ErrNotInFormat
+		case xml.StartElement:
+			if v.Name.Local != "document" || !isSpreadsheet(getAttrs(v.Attr, "mimetype")[0]) {
+				return nil, grate.ErrNotInFormat
+			}
+			d := &Document{filename: filename}
+			if err = d.parseContent(dec); err != nil {
+				return nil, err
+			}
+			return d, nil
+		default:
+			return nil, grate.ErrNotInFormat
+		}
+	}
+}
+
+// isSpreadsheet returns true for the mime types of spreadsheets and
+// spreadsheet templates.
+func isSpreadsheet(mimeType string) bool {
+	return mimeType == mimeTypeSpreadsheet || mimeType == mimeTypeSpreadsheet+"-template"
+}
+
+// Close the document.
+func (d *Document) Close() error {
+	d.tables = nil
+	d.names = nil
+	return nil
+}
+
+// List returns the names of the visible tables in the document.
+func (d *Document) List() ([]string, error) {
+	res := make([]string, 0, len(d.tables))
+	for _, t := range d.tables {
+		if !t.hidden {
+			res = append(res, t.name)
+		}
+	}
+	return res, nil
+}
+
+// ListHidden returns the names of the hidden tables in the document.
+func (d *Document) ListHidden() ([]string, error) {
+	res := []string{}
+	for _, t := range d.tables {
+		if t.hidden {
+			res = append(res, t.name)
+		}
+	}
+	return res, nil
+}
+
+// Get the named table, which may be hidden.
+func (d *Document) Get(sheetName string) (grate.Collection, error) {
+	for _, t := range d.tables {
+		if t.name == sheetName {
+			t.sheet.Rewind()
+			return t.sheet, nil
+		}
+	}
+	return nil, errors.New("ods: sheet not found")
+}
+
+func getAttrs(attrs []xml.Attr, keys ...string) []string {
+	res := make([]string, len(keys))
+	for _, a := range attrs {
+		for i, k := range keys {
+			if a.Name.Local == k {
+				res[i] = a.Value
+			}
+		}
+	}
+	return res
+}
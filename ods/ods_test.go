@@ -0,0 +1,186 @@
+package ods
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate"
+)
+
+const testBody = `<office:automatic-styles>
+  <style:style style:name="ta1" style:family="table"><style:table-properties table:display="true"/></style:style>
+  <style:style style:name="ta2" style:family="table"><style:table-properties table:display="false"/></style:style>
+ </office:automatic-styles>
+ <office:body><office:spreadsheet>
+  <table:table table:name="Data" table:style-name="ta1">
+   <table:table-column table:number-columns-repeated="3"/>
+   <table:table-row>
+    <table:table-cell office:value-type="string"><text:p>Name</text:p></table:table-cell>
+    <table:table-cell office:value-type="string"><text:p>a<text:s text:c="2"/>b</text:p><text:p>c</text:p></table:table-cell>
+    <table:table-cell office:value-type="float" office:value="1.5"><text:p>1.50</text:p>
+     <office:annotation><text:p>a comment</text:p></office:annotation></table:table-cell>
+    <table:table-cell table:number-columns-repeated="1021"/>
+   </table:table-row>
+   <table:table-row table:number-rows-repeated="2">
+    <table:table-cell office:value-type="percentage" office:value="0.25"><text:p>25%</text:p></table:table-cell>
+    <table:table-cell office:value-type="boolean" office:boolean-value="true"><text:p>TRUE</text:p></table:table-cell>
+    <table:table-cell office:value-type="currency" office:currency="USD" office:value="-3"><text:p>-$3.00</text:p></table:table-cell>
+   </table:table-row>
+   <table:table-row>
+    <table:table-cell office:value-type="date" office:date-value="2021-03-04"><text:p>03/04/21</text:p></table:table-cell>
+    <table:table-cell office:value-type="time" office:time-value="PT13H45M30S"><text:p>13:45:30</text:p></table:table-cell>
+    <table:table-cell office:value-type="date" office:date-value="2021-03-04T05:06:07"><text:p>x</text:p></table:table-cell>
+   </table:table-row>
+   <table:table-row>
+    <table:table-cell table:number-columns-spanned="2" table:number-rows-spanned="2" office:value-type="string"><text:p>merged</text:p></table:table-cell>
+    <table:covered-table-cell/>
+    <table:table-cell table:number-columns-repeated="2" office:value-type="float" office:value="7"><text:p>7</text:p></table:table-cell>
+   </table:table-row>
+   <table:table-row>
+    <table:covered-table-cell table:number-columns-repeated="2"/>
+    <table:table-cell/>
+   </table:table-row>
+   <table:table-row table:number-rows-repeated="1048570"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+  </table:table>
+  <table:table table:name="Secret" table:style-name="ta2">
+   <table:table-row><table:table-cell office:value-type="string"><text:p>hidden</text:p></table:table-cell></table:table-row>
+  </table:table>
+  <table:named-expressions>
+   <table:named-range table:name="Values" table:base-cell-address="$Data.$A$1" table:cell-range-address="$Data.$B$2:.$C$3"/>
+   <table:named-range table:name="Whole" table:base-cell-address="$Data.$A$1" table:cell-range-address="$Data.$A$1:.$AMJ$1048576"/>
+  </table:named-expressions>
+ </office:spreadsheet></office:body>`
+
+const testNamespaces = `xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"`
+
+func writeFlat(t *testing.T) string {
+	fn := filepath.Join(t.TempDir(), "test.fods")
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<office:document ` + testNamespaces + ` office:version="1.2" office:mimetype="application/vnd.oasis.opendocument.spreadsheet">
+ ` + testBody + `
+</office:document>`
+	if err := os.WriteFile(fn, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func writePackage(t *testing.T, mimeType string) string {
+	fn := filepath.Join(t.TempDir(), "test.ods")
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	w, _ := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	w.Write([]byte(mimeType))
+	w, _ = z.Create("content.xml")
+	w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content ` + testNamespaces + ` office:version="1.2">` + testBody + `</office:document-content>`))
+	if err = z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return fn
+}
+
+func readAll(t *testing.T, c grate.Collection) [][]string {
+	var res [][]string
+	for c.Next() {
+		res = append(res, c.Strings())
+	}
+	if err := c.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestOpen(t *testing.T) {
+	expect := [][]string{
+		{"Name", "a  b\nc", "1.5", ""},
+		{"0.25", "true", "-3", ""},
+		{"0.25", "true", "-3", ""},
+		{"2021-03-04", "1899-12-30 13:45:30", "2021-03-04 05:06:07", ""},
+		{"merged", "⇥", "7", "7"},
+		{"⤓", "⇥", "", ""},
+	}
+
+	for _, fn := range []string{writeFlat(t), writePackage(t, mimeTypeSpreadsheet)} {
+		wb, err := Open(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names, _ := wb.List()
+		if !reflect.DeepEqual(names, []string{"Data"}) {
+			t.Fatalf("%s: got sheets %v", fn, names)
+		}
+		hidden, _ := wb.(*Document).ListHidden()
+		if !reflect.DeepEqual(hidden, []string{"Secret"}) {
+			t.Fatalf("%s: got hidden sheets %v", fn, hidden)
+		}
+
+		sheet, err := wb.Get("Data")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readAll(t, sheet); !reflect.DeepEqual(got, expect) {
+			t.Fatalf("%s: got %q\nexpected %q", fn, got, expect)
+		}
+
+		sheet, _ = wb.Get("Data")
+		sheet.Next()
+		sheet.Next()
+		var pct, cur float64
+		var b bool
+		if err = sheet.Scan(&pct, &b, &cur); err != nil {
+			t.Fatal(err)
+		}
+		if pct != 0.25 || !b || cur != -3 {
+			t.Fatalf("%s: scanned %v %v %v", fn, pct, b, cur)
+		}
+
+		nr, err := wb.(*Document).GetNamedRange("Values")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readAll(t, nr); !reflect.DeepEqual(got, [][]string{{"true", "-3"}, {"true", "-3"}}) {
+			t.Fatalf("%s: got named range %q", fn, got)
+		}
+		nr, err = wb.(*Document).GetNamedRange("Whole")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readAll(t, nr); !reflect.DeepEqual(got, expect) {
+			t.Fatalf("%s: got named range %q", fn, got)
+		}
+		wb.Close()
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	fn := writePackage(t, "application/vnd.oasis.opendocument.text")
+	if _, err := Open(fn); err != grate.ErrNotInFormat {
+		t.Fatalf("expected ErrNotInFormat, got %v", err)
+	}
+
+	fn = filepath.Join(t.TempDir(), "test.csv")
+	os.WriteFile(fn, []byte("a,b,c\n1,2,3\n"), 0644)
+	if _, err := Open(fn); !errors.Is(err, grate.ErrNotInFormat) {
+		t.Fatalf("expected ErrNotInFormat, got %v", err)
+	}
+}
+
+func TestParseNamedRange(t *testing.T) {
+	nr, ok := parseNamedRange("x", "$'It''s.here'.$B$2:'It''s.here'.D10")
+	if !ok || nr.Sheet != "It's.here" || nr.Range.FirstRow != 1 || nr.Range.FirstCol != 1 ||
+		nr.Range.LastRow != 9 || nr.Range.LastCol != 3 {
+		t.Fatalf("got %+v %v", nr, ok)
+	}
+	if _, ok = parseNamedRange("x", "A1"); ok {
+		t.Fatal("expected a range without a sheet to fail")
+	}
+}
@@ -0,0 +1,133 @@
+package sav
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io/ioutil"
+	"math"
+)
+
+// caseReader reads the 8-byte slots of each case.
+type caseReader interface {
+	// readCase fills buf with the next case, returning false at the end.
+	readCase(buf []byte) (bool, error)
+}
+
+// rawCases reads uncompressed cases.
+type rawCases struct {
+	r *reader
+}
+
+func (c *rawCases) readCase(buf []byte) (bool, error) {
+	if len(c.r.buf)-c.r.pos < len(buf) {
+		return false, nil
+	}
+	copy(buf, c.r.bytes(len(buf)))
+	return true, nil
+}
+
+// bytecodeCases reads compressed cases. Each block of 8 command bytes
+// describes the following slots: 0 is ignored, 1 to 251 are numbers plus
+// the bias, 252 ends the data, 253 is followed by an uncompressed slot,
+// 254 is a slot of spaces and 255 is the system-missing value.
+type bytecodeCases struct {
+	r      *reader
+	d      *dictionary
+	cmds   []byte
+	end    bool
+	sysmis [8]byte
+}
+
+func newBytecodeCases(r *reader, d *dictionary) *bytecodeCases {
+	c := &bytecodeCases{r: r, d: d}
+	d.order.PutUint64(c.sysmis[:], math.Float64bits(d.sysmis))
+	return c
+}
+
+// next returns the next command which is not ignored.
+func (c *bytecodeCases) next() (byte, bool) {
+	for !c.end {
+		if len(c.cmds) == 0 {
+			if len(c.r.buf)-c.r.pos < 8 {
+				c.end = true
+				break
+			}
+			c.cmds = c.r.bytes(8)
+		}
+		cmd := c.cmds[0]
+		c.cmds = c.cmds[1:]
+		switch cmd {
+		case 0:
+		case 252:
+			c.end = true
+		default:
+			return cmd, true
+		}
+	}
+	return 0, false
+}
+
+func (c *bytecodeCases) readCase(buf []byte) (bool, error) {
+	for i := 0; i < len(buf); i += 8 {
+		cmd, ok := c.next()
+		if !ok {
+			if i > 0 {
+				return false, errTruncated
+			}
+			return false, nil
+		}
+		switch cmd {
+		case 253:
+			raw := c.r.bytes(8)
+			if raw == nil {
+				return false, errTruncated
+			}
+			copy(buf[i:], raw)
+		case 254:
+			copy(buf[i:], "        ")
+		case 255:
+			copy(buf[i:], c.sysmis[:])
+		default:
+			c.d.order.PutUint64(buf[i:], math.Float64bits(float64(cmd)-c.d.bias))
+		}
+	}
+	return true, nil
+}
+
+// inflate returns the bytecode of a zlib compressed file, which is stored
+// as a series of zlib streams listed by a trailer at the end of the file.
+func inflate(buf []byte, d *dictionary) ([]byte, error) {
+	r := &reader{buf: buf, pos: d.data, order: d.order}
+	r.int64() // offset of the zlib header
+	trailer := r.int64()
+	if r.err != nil || trailer < 0 || trailer > int64(len(buf)) {
+		return nil, errTruncated
+	}
+	r.pos = int(trailer)
+	r.bytes(8 + 8 + 4) // bias, zero and block size
+	n := int(r.int32())
+	if n < 0 || n > len(buf)/24 {
+		return nil, errTruncated
+	}
+	var res []byte
+	for i := 0; i < n && r.err == nil; i++ {
+		r.int64() // uncompressed offset
+		off := r.int64()
+		r.int32() // uncompressed size
+		size := int64(r.int32())
+		if off < 0 || size < 0 || off+size > int64(len(buf)) {
+			return nil, errTruncated
+		}
+		z, err := zlib.NewReader(bytes.NewReader(buf[off : off+size]))
+		if err != nil {
+			return nil, errors.New("sav: invalid zlib block")
+		}
+		block, err := ioutil.ReadAll(z)
+		if err != nil {
+			return nil, errors.New("sav: invalid zlib block")
+		}
+		res = append(res, block...)
+	}
+	return res, r.err
+}
@@ -0,0 +1,335 @@
+package sav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commontext"
+)
+
+var errTruncated = errors.New("sav: file is truncated")
+
+// reader reads the values of a file in memory, and remembers the first
+// error so that a record can be read before checking for errors.
+type reader struct {
+	buf   []byte
+	pos   int
+	order binary.ByteOrder
+	err   error
+}
+
+// bytes returns the next n bytes.
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.buf) {
+		r.err = errTruncated
+		return nil
+	}
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) int32() int32 {
+	if b := r.bytes(4); b != nil {
+		return int32(r.order.Uint32(b))
+	}
+	return 0
+}
+
+func (r *reader) int64() int64 {
+	if b := r.bytes(8); b != nil {
+		return int64(r.order.Uint64(b))
+	}
+	return 0
+}
+
+func (r *reader) float64() float64 {
+	return math.Float64frombits(uint64(r.int64()))
+}
+
+// variable is a variable record of the dictionary. Strings longer than 8
+// bytes continue into the following records, and strings longer than 255
+// bytes into the following variables.
+type variable struct {
+	name     string // short name
+	width    int    // of strings, or 0 for numbers
+	print    int32  // print format
+	label    []byte
+	nMissing int32 // count of discrete missing values, or -2 or -3 for a range
+	missing  []float64
+	slot     int // index of the first 8-byte slot in a case
+}
+
+// labelSet is a set of value labels and the variables which use it.
+type labelSet struct {
+	values [][]byte
+	labels [][]byte
+	slots  []int // 0-based indexes of the first slots of the variables
+}
+
+// longLabels are the value labels of a string variable longer than 8 bytes.
+type longLabels struct {
+	name   string
+	values [][]byte
+	labels [][]byte
+}
+
+// dictionary holds the header and dictionary records of a system file.
+type dictionary struct {
+	order       binary.ByteOrder
+	zlib        bool
+	compression int32
+	ncases      int32
+	bias        float64
+	label       []byte
+
+	vars       []*variable
+	slots      int // 8-byte slots in a case
+	labelSets  []labelSet
+	longLabels []longLabels
+
+	sysmis    float64
+	codepage  int32
+	encoding  string
+	longNames map[string]string // by short name
+	veryLong  map[string]int    // widths by short name
+
+	data int // offset of the cases
+}
+
+// parseDictionary reads the header and dictionary of a system file.
+func parseDictionary(buf []byte) (*dictionary, error) {
+	if len(buf) < 176 {
+		return nil, grate.ErrNotInFormat
+	}
+	d := &dictionary{
+		zlib:      string(buf[:4]) == "$FL3",
+		sysmis:    -math.MaxFloat64,
+		longNames: make(map[string]string),
+		veryLong:  make(map[string]int),
+	}
+	switch layout := binary.LittleEndian.Uint32(buf[64:]); {
+	case layout == 2 || layout == 3:
+		d.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(buf[64:]) == 2 || binary.BigEndian.Uint32(buf[64:]) == 3:
+		d.order = binary.BigEndian
+	default:
+		return nil, grate.ErrNotInFormat
+	}
+	r := &reader{buf: buf, pos: 72, order: d.order}
+	d.compression = r.int32()
+	r.int32() // weight index
+	d.ncases = r.int32()
+	d.bias = r.float64()
+	r.bytes(9 + 8) // creation date and time
+	d.label = bytes.TrimRight(r.bytes(64), " ")
+	r.bytes(3)
+
+	for r.err == nil {
+		switch rec := r.int32(); rec {
+		case 2:
+			d.readVariable(r)
+		case 3:
+			d.readLabels(r)
+		case 6:
+			// documents
+			r.bytes(80 * int(r.int32()))
+		case 7:
+			subtype, size, count := r.int32(), r.int32(), r.int32()
+			if size < 0 || count < 0 || int64(size)*int64(count) > int64(len(buf)) {
+				return nil, errTruncated
+			}
+			d.readExtension(subtype, r.bytes(int(size*count)))
+		case 999:
+			r.int32()
+			d.data = r.pos
+			if len(d.vars) == 0 {
+				return nil, errors.New("sav: no variables")
+			}
+			if err := d.checkContinuations(); err != nil {
+				return nil, err
+			}
+			return d, r.err
+		default:
+			if r.err == nil {
+				return nil, errors.New("sav: unknown record type " + strconv.Itoa(int(rec)))
+			}
+		}
+	}
+	return nil, r.err
+}
+
+func (d *dictionary) readVariable(r *reader) {
+	typ, hasLabel, nMissing := r.int32(), r.int32(), r.int32()
+	print := r.int32()
+	r.int32() // write format
+	name := strings.TrimRight(string(r.bytes(8)), " ")
+	var label []byte
+	if hasLabel == 1 {
+		n := int(r.int32())
+		label = r.bytes(n)
+		r.bytes((n+3)&^3 - n)
+	}
+	// a range is -2, or -3 with a discrete value
+	n := nMissing
+	if n < 0 {
+		n = -n
+	}
+	if n > 3 || nMissing == -1 {
+		r.err = errors.New("sav: invalid missing values")
+		return
+	}
+	missing := make([]float64, n)
+	for i := range missing {
+		missing[i] = r.float64()
+	}
+	slot := d.slots
+	d.slots++
+	if typ == -1 {
+		// the continuation of a string
+		return
+	}
+	if typ < 0 || typ > 255 {
+		r.err = errors.New("sav: invalid variable type")
+		return
+	}
+	d.vars = append(d.vars, &variable{
+		name:     name,
+		width:    int(typ),
+		print:    print,
+		label:    label,
+		nMissing: nMissing,
+		missing:  missing,
+		slot:     slot,
+	})
+}
+
+// checkContinuations ensures that each string variable is followed by the
+// continuation records for the rest of its slots.
+func (d *dictionary) checkContinuations() error {
+	for i, v := range d.vars {
+		next := d.slots
+		if i+1 < len(d.vars) {
+			next = d.vars[i+1].slot
+		}
+		if v.width > 0 && next-v.slot != (v.width+7)/8 {
+			return errors.New("sav: missing string continuation records")
+		}
+	}
+	return nil
+}
+
+// readLabels reads a value label record, and the record listing the
+// variables which use the labels.
+func (d *dictionary) readLabels(r *reader) {
+	var set labelSet
+	n := int(r.int32())
+	if n < 0 || n > len(r.buf)/8 {
+		r.err = errTruncated
+		return
+	}
+	for i := 0; i < n && r.err == nil; i++ {
+		set.values = append(set.values, r.bytes(8))
+		b := r.bytes(1)
+		if b == nil {
+			return
+		}
+		size := int(b[0])
+		set.labels = append(set.labels, r.bytes(size))
+		r.bytes((size+8)&^7 - size - 1)
+	}
+	if r.int32() != 4 {
+		if r.err == nil {
+			r.err = errors.New("sav: expected the variables of value labels")
+		}
+		return
+	}
+	n = int(r.int32())
+	if n < 0 || n > len(r.buf)/4 {
+		r.err = errTruncated
+		return
+	}
+	for i := 0; i < n && r.err == nil; i++ {
+		set.slots = append(set.slots, int(r.int32())-1)
+	}
+	d.labelSets = append(d.labelSets, set)
+}
+
+// readExtension reads the extension records which are used, and ignores
+// the others.
+func (d *dictionary) readExtension(subtype int32, data []byte) {
+	switch subtype {
+	case 3:
+		// integer information, including the character code
+		if len(data) >= 32 {
+			d.codepage = int32(d.order.Uint32(data[28:]))
+		}
+	case 4:
+		// floating point information, starting with the system-missing value
+		if len(data) >= 8 {
+			d.sysmis = math.Float64frombits(d.order.Uint64(data))
+		}
+	case 13:
+		// long variable names, as SHORT=Long pairs separated by tabs
+		for _, pair := range strings.Split(string(data), "\t") {
+			if i := strings.IndexByte(pair, '='); i > 0 {
+				d.longNames[pair[:i]] = pair[i+1:]
+			}
+		}
+	case 14:
+		// very long strings, as SHORT=width pairs
+		for _, pair := range strings.Split(string(data), "\t") {
+			pair = strings.Trim(pair, "\x00")
+			if i := strings.IndexByte(pair, '='); i > 0 {
+				if w, err := strconv.Atoi(strings.TrimSpace(pair[i+1:])); err == nil {
+					d.veryLong[pair[:i]] = w
+				}
+			}
+		}
+	case 20:
+		d.encoding = string(data)
+	case 21:
+		r := &reader{buf: data, order: d.order}
+		for r.err == nil && r.pos < len(data) {
+			var ll longLabels
+			ll.name = string(r.bytes(int(r.int32())))
+			r.int32() // width
+			n := int(r.int32())
+			if n < 0 || n > len(data)/8 {
+				return
+			}
+			for i := 0; i < n && r.err == nil; i++ {
+				ll.values = append(ll.values, r.bytes(int(r.int32())))
+				ll.labels = append(ll.labels, r.bytes(int(r.int32())))
+			}
+			if r.err == nil {
+				d.longLabels = append(d.longLabels, ll)
+			}
+		}
+	}
+}
+
+// textEncoding returns the encoding of the text of the file, from its
+// name or character code, or Windows-1252.
+func (d *dictionary) textEncoding() *commontext.Encoding {
+	if enc := commontext.LookupEncoding(d.encoding); d.encoding != "" && enc != nil {
+		return enc
+	}
+	switch {
+	case d.codepage == 65001 || d.codepage == 20127:
+		return commontext.UTF8
+	case d.codepage >= 1250 && d.codepage <= 1258:
+		if enc := commontext.LookupEncoding("windows-" + strconv.Itoa(int(d.codepage))); enc != nil {
+			return enc
+		}
+	}
+	return commontext.Windows1252
+}
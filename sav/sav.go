@@ -0,0 +1,323 @@
+// Package sav reads SPSS .sav system files, including files with
+// bytecode and zlib compressed data.
+package sav
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+var _ = grate.Register("sav", 3, Open)
+
+// Options configure how a system file is read.
+type Options struct {
+	// ValueLabels replaces the values of variables which have value labels
+	// with their labels.
+	ValueLabels bool
+
+	// UserMissing returns the numbers which are declared as missing values
+	// of a variable, instead of treating them as empty.
+	UserMissing bool
+}
+
+// Variable describes a column of a system file.
+type Variable struct {
+	Name  string
+	Label string
+
+	// Format is the print format, such as "F8.2", "A20" or "DATE11".
+	Format string
+
+	// Labels maps the values of the variable, as returned by Strings, to
+	// their value labels, or is nil when the variable has none.
+	Labels map[string]string
+}
+
+// Document is an SPSS system file, with the names of the variables in the
+// first row and a case in each following row.
+type Document struct {
+	filename string
+	label    string
+	vars     []Variable
+	sheet    *commonxl.Sheet
+}
+
+// column is a variable and the variable records which hold its values.
+type column struct {
+	segments []*variable
+	date     bool
+}
+
+// Open an SPSS system file.
+func Open(filename string) (grate.Source, error) {
+	return OpenWithOptions(filename, Options{})
+}
+
+// OpenWithOptions opens an SPSS system file with the given options.
+func OpenWithOptions(filename string, opts Options) (grate.Source, error) {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	var magic [4]byte
+	if _, err := io.ReadFull(fh, magic[:]); err != nil {
+		return nil, grate.ErrNotInFormat
+	}
+	if string(magic[:]) != "$FL2" && string(magic[:]) != "$FL3" {
+		return nil, grate.ErrNotInFormat
+	}
+	if _, err := fh.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf, err := ioutil.ReadAll(fh)
+	if err != nil {
+		return nil, err
+	}
+
+	dict, err := parseDictionary(buf)
+	if err != nil {
+		return nil, err
+	}
+	d := &Document{filename: filename}
+	if err := d.load(buf, dict, opts); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// load decodes the variables and cases of a system file.
+func (d *Document) load(buf []byte, dict *dictionary, opts Options) error {
+	enc := dict.textEncoding()
+	d.label = enc.Decode(dict.label)
+
+	var cols []column
+	bySlot := make(map[int]int)
+	for i := 0; i < len(dict.vars); i++ {
+		v := dict.vars[i]
+		c := column{segments: dict.vars[i : i+1]}
+		if w := dict.veryLong[v.name]; w > 255 {
+			// the following variables hold the rest of the string
+			if n := (w + 251) / 252; i+n <= len(dict.vars) {
+				c.segments = dict.vars[i : i+n]
+				i += n - 1
+			}
+		}
+		name := v.name
+		if long, ok := dict.longNames[v.name]; ok {
+			name = long
+		}
+		c.date = v.width == 0 && dateFormats[byte(v.print>>16)]
+		bySlot[v.slot] = len(cols)
+		cols = append(cols, c)
+		d.vars = append(d.vars, Variable{
+			Name:   name,
+			Label:  enc.Decode(v.label),
+			Format: formatString(v.print),
+		})
+	}
+
+	addLabel := func(i int, value, label []byte) {
+		var key string
+		if cols[i].segments[0].width == 0 {
+			key = strconv.FormatFloat(math.Float64frombits(dict.order.Uint64(value)), 'f', -1, 64)
+		} else {
+			key = enc.Decode(bytes.TrimRight(value, " "))
+		}
+		if d.vars[i].Labels == nil {
+			d.vars[i].Labels = make(map[string]string)
+		}
+		d.vars[i].Labels[key] = enc.Decode(label)
+	}
+	for _, set := range dict.labelSets {
+		for _, slot := range set.slots {
+			if i, ok := bySlot[slot]; ok {
+				for j, value := range set.values {
+					addLabel(i, value, set.labels[j])
+				}
+			}
+		}
+	}
+	for _, ll := range dict.longLabels {
+		for i, c := range cols {
+			if strings.EqualFold(ll.name, d.vars[i].Name) || strings.EqualFold(ll.name, c.segments[0].name) {
+				for j, value := range ll.values {
+					addLabel(i, value, ll.labels[j])
+				}
+			}
+		}
+	}
+
+	var cases caseReader
+	r := &reader{buf: buf, pos: dict.data, order: dict.order}
+	switch {
+	case dict.zlib || dict.compression == 2:
+		data, err := inflate(buf, dict)
+		if err != nil {
+			return err
+		}
+		cases = newBytecodeCases(&reader{buf: data, order: dict.order}, dict)
+	case dict.compression == 1:
+		cases = newBytecodeCases(r, dict)
+	case dict.compression == 0:
+		cases = &rawCases{r: r}
+	default:
+		return fmt.Errorf("sav: unsupported compression %d", dict.compression)
+	}
+
+	names := make([]interface{}, len(d.vars))
+	for i, v := range d.vars {
+		names[i] = v.Name
+	}
+	d.sheet = &commonxl.Sheet{}
+	d.sheet.Append(names...)
+	slots := make([]byte, 8*dict.slots)
+	for n := 0; dict.ncases < 0 || n < int(dict.ncases); n++ {
+		ok, err := cases.readCase(slots)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		row := make([]interface{}, len(cols))
+		for i, c := range cols {
+			v := c.segments[0]
+			if v.width > 0 {
+				var s []byte
+				for j, seg := range c.segments {
+					b := slots[8*seg.slot : 8*seg.slot+seg.width]
+					if j < len(c.segments)-1 && len(b) > 252 {
+						b = b[:252]
+					}
+					s = append(s, b...)
+				}
+				if s = bytes.TrimRight(s, " "); len(s) > 0 {
+					row[i] = enc.Decode(s)
+				}
+				if opts.ValueLabels && row[i] != nil {
+					if label, ok := d.vars[i].Labels[row[i].(string)]; ok {
+						row[i] = label
+					}
+				}
+				continue
+			}
+
+			num := math.Float64frombits(dict.order.Uint64(slots[8*v.slot:]))
+			if num == dict.sysmis || (!opts.UserMissing && v.isMissing(num)) {
+				continue
+			}
+			if opts.ValueLabels {
+				if label, ok := d.vars[i].Labels[strconv.FormatFloat(num, 'f', -1, 64)]; ok {
+					row[i] = label
+					continue
+				}
+			}
+			row[i] = num
+			if c.date {
+				row[i] = spssTime(num)
+			}
+		}
+		d.sheet.Append(row...)
+	}
+	return nil
+}
+
+// isMissing returns true if a number is a missing value of the variable,
+// which declares up to three values, or a range and up to one value.
+func (v *variable) isMissing(num float64) bool {
+	values := v.missing
+	if v.nMissing < 0 {
+		if num >= values[0] && num <= values[1] {
+			return true
+		}
+		values = values[2:]
+	}
+	for _, m := range values {
+		if num == m {
+			return true
+		}
+	}
+	return false
+}
+
+// formats names the types of print formats.
+var formats = map[byte]string{
+	1: "A", 2: "AHEX", 3: "COMMA", 4: "DOLLAR", 5: "F", 6: "IB", 7: "PIBHEX",
+	8: "P", 9: "PIB", 10: "PK", 11: "RB", 12: "RBHEX", 15: "Z", 16: "N",
+	17: "E", 20: "DATE", 21: "TIME", 22: "DATETIME", 23: "ADATE", 24: "JDATE",
+	25: "DTIME", 26: "WKDAY", 27: "MONTH", 28: "MOYR", 29: "QYR", 30: "WKYR",
+	31: "PCT", 32: "DOT", 33: "CCA", 34: "CCB", 35: "CCC", 36: "CCD",
+	37: "CCE", 38: "EDATE", 39: "SDATE", 40: "MTIME", 41: "YMDHMS",
+}
+
+// dateFormats are the types of print formats of dates and times, rather
+// than durations.
+var dateFormats = map[byte]bool{
+	20: true, 22: true, 23: true, 24: true, 28: true, 29: true, 30: true,
+	38: true, 39: true, 41: true,
+}
+
+// formatString returns a print format, which packs the type, width and
+// decimals into bytes, in the syntax of SPSS.
+func formatString(print int32) string {
+	name, ok := formats[byte(print>>16)]
+	if !ok {
+		return ""
+	}
+	width, decimals := byte(print>>8), byte(print)
+	if decimals > 0 {
+		return fmt.Sprintf("%s%d.%d", name, width, decimals)
+	}
+	return fmt.Sprintf("%s%d", name, width)
+}
+
+// spssEpoch is 1582-10-14, from which SPSS counts dates in seconds.
+var spssEpoch = time.Date(1582, 10, 14, 0, 0, 0, 0, time.UTC)
+
+func spssTime(secs float64) time.Time {
+	whole := math.Floor(secs)
+	ns := int64(math.Round((secs - whole) * 1e9))
+	return time.Unix(spssEpoch.Unix()+int64(whole), ns).UTC()
+}
+
+// Label returns the label of the system file.
+func (d *Document) Label() string {
+	return d.label
+}
+
+// Variables returns the variables of the system file.
+func (d *Document) Variables() []Variable {
+	return append([]Variable{}, d.vars...)
+}
+
+// List returns the name of the single table in the file.
+func (d *Document) List() ([]string, error) {
+	return []string{filepath.Base(d.filename)}, nil
+}
+
+// Get the table of the file.
+func (d *Document) Get(name string) (grate.Collection, error) {
+	if name != filepath.Base(d.filename) {
+		return nil, errors.New("sav: table not found")
+	}
+	d.sheet.Rewind()
+	return d.sheet, nil
+}
+
+// Close the document.
+func (d *Document) Close() error {
+	return nil
+}
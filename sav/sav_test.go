@@ -0,0 +1,267 @@
+package sav
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pbnjay/grate"
+)
+
+// the cases of the test file: id (labelled, 9 is missing), born (DATE11)
+// and city (A12, long name "home_city")
+var testCases = []struct {
+	id   float64
+	born float64
+	city string
+}{
+	{1, 12_515_385_600, "Zürich"}, // 1979-05-20
+	{9, -math.MaxFloat64, ""},
+	{2, 13_166_064_000, "Genève"}, // 2000-01-01
+}
+
+// buildSAV returns a little-endian system file with the given compression.
+func buildSAV(compression int32) []byte {
+	le := binary.LittleEndian
+	var b bytes.Buffer
+	w := func(v ...interface{}) {
+		for _, x := range v {
+			binary.Write(&b, le, x)
+		}
+	}
+	pad := func(s string, n int) {
+		b.WriteString(s)
+		b.Write(bytes.Repeat([]byte{' '}, n-len(s)))
+	}
+
+	b.WriteString("$FL2")
+	pad("@(#) SPSS DATA FILE test", 60)
+	w(int32(2), int32(4), compression, int32(0), int32(len(testCases)), float64(100))
+	pad("15 Oct 26", 9)
+	pad("09:30:00", 8)
+	pad("Survey", 64)
+	b.Write(make([]byte, 3))
+
+	// id: F8.0 with a missing value
+	w(int32(2), int32(0), int32(1), int32(1), int32(5<<16|8<<8), int32(5<<16|8<<8))
+	pad("ID", 8)
+	w(int32(10))
+	pad("Identifier", 12)
+	w(float64(9))
+	// born: DATE11
+	w(int32(2), int32(0), int32(0), int32(0), int32(20<<16|11<<8), int32(20<<16|11<<8))
+	pad("BORN", 8)
+	// city: A12 in two slots
+	w(int32(2), int32(12), int32(0), int32(0), int32(1<<16|12<<8), int32(1<<16|12<<8))
+	pad("HOME_CIT", 8)
+	w(int32(2), int32(-1), int32(0), int32(0), int32(0), int32(0))
+	pad("", 8)
+
+	// value labels of id
+	w(int32(3), int32(2))
+	w(float64(1))
+	b.WriteByte(3)
+	pad("yes", 7)
+	w(float64(2))
+	b.WriteByte(2)
+	pad("no", 7)
+	w(int32(4), int32(1), int32(1))
+
+	names := "ID=id\tBORN=born\tHOME_CIT=home_city"
+	w(int32(7), int32(13), int32(1), int32(len(names)))
+	b.WriteString(names)
+	w(int32(7), int32(20), int32(1), int32(5))
+	b.WriteString("UTF-8")
+	w(int32(999), int32(0))
+
+	var raw bytes.Buffer
+	for _, c := range testCases {
+		binary.Write(&raw, le, c.id)
+		binary.Write(&raw, le, c.born)
+		raw.WriteString(c.city)
+		raw.Write(bytes.Repeat([]byte{' '}, 16-len(c.city)))
+	}
+	if compression == 0 {
+		b.Write(raw.Bytes())
+		return b.Bytes()
+	}
+
+	// bytecode compress the slots
+	var code bytes.Buffer
+	slots := raw.Bytes()
+	for len(slots) > 0 {
+		var cmds [8]byte
+		var data bytes.Buffer
+		for i := range cmds {
+			if len(slots) == 0 {
+				cmds[i] = 252
+				break
+			}
+			slot := slots[:8]
+			slots = slots[8:]
+			f := math.Float64frombits(le.Uint64(slot))
+			switch {
+			case string(slot) == "        ":
+				cmds[i] = 254
+			case f == -math.MaxFloat64:
+				cmds[i] = 255
+			case f == math.Trunc(f) && f > -100 && f < 152:
+				cmds[i] = byte(f + 100)
+			default:
+				cmds[i] = 253
+				data.Write(slot)
+			}
+		}
+		code.Write(cmds[:])
+		code.Write(data.Bytes())
+	}
+	if compression == 1 {
+		b.Write(code.Bytes())
+		return b.Bytes()
+	}
+
+	// zlib compress the bytecode as a single block
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(code.Bytes())
+	zw.Close()
+	res := append([]byte{}, b.Bytes()...)
+	res[3] = '3'
+	zheader := int64(len(res))
+	block := zheader + 24
+	trailer := block + int64(z.Len())
+	b.Reset()
+	w(zheader, trailer, int64(24+24))
+	b.Write(z.Bytes())
+	w(int64(-100), int64(0), int32(0x3ff000), int32(1))
+	w(zheader, block, int32(code.Len()), int32(z.Len()))
+	return append(res, b.Bytes()...)
+}
+
+func readAll(t *testing.T, fn string, opts Options) [][]string {
+	t.Helper()
+	src, err := OpenWithOptions(fn, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	c, err := src.Get(filepath.Base(fn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res [][]string
+	for c.Next() {
+		res = append(res, c.Strings())
+	}
+	return res
+}
+
+func TestCompression(t *testing.T) {
+	want := [][]string{
+		{"id", "born", "home_city"},
+		{"1", "1979-05-20", "Zürich"},
+		{"", "", ""},
+		{"2", "2000-01-01", "Genève"},
+	}
+	dir := t.TempDir()
+	for _, compression := range []int32{0, 1, 2} {
+		fn := filepath.Join(dir, "survey.sav")
+		if err := os.WriteFile(fn, buildSAV(compression), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := readAll(t, fn, Options{}); !reflect.DeepEqual(got, want) {
+			t.Errorf("compression %d: got %q, want %q", compression, got, want)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "survey.sav")
+	if err := os.WriteFile(fn, buildSAV(1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := readAll(t, fn, Options{ValueLabels: true, UserMissing: true})
+	want := [][]string{
+		{"id", "born", "home_city"},
+		{"yes", "1979-05-20", "Zürich"},
+		{"9", "", ""},
+		{"no", "2000-01-01", "Genève"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	src, _ := Open(fn)
+	d := src.(*Document)
+	if d.Label() != "Survey" {
+		t.Errorf("got label %q", d.Label())
+	}
+	vars := d.Variables()
+	if vars[0].Label != "Identifier" || vars[0].Format != "F8" || vars[1].Format != "DATE11" || vars[2].Format != "A12" {
+		t.Errorf("got variables %+v", vars)
+	}
+	if !reflect.DeepEqual(vars[0].Labels, map[string]string{"1": "yes", "2": "no"}) {
+		t.Errorf("got value labels %q", vars[0].Labels)
+	}
+
+	c, _ := src.Get("survey.sav")
+	c.Next()
+	c.Next()
+	var id int
+	var born time.Time
+	var city string
+	if err := c.Scan(&id, &born, &city); err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 || !born.Equal(time.Date(1979, 5, 20, 0, 0, 0, 0, time.UTC)) || city != "Zürich" {
+		t.Errorf("scanned %v %v %q", id, born, city)
+	}
+}
+
+func TestInvalidDictionary(t *testing.T) {
+	dir := t.TempDir()
+	le := binary.LittleEndian
+
+	// id with a missing value count of -1
+	badMissing := buildSAV(0)
+	le.PutUint32(badMissing[176+12:], uint32(0xFFFFFFFF))
+	// city without its continuation record
+	sav := buildSAV(0)
+	noContinuation := append(append([]byte{}, sav[:296]...), sav[328:]...)
+
+	for name, data := range map[string][]byte{
+		"missing.sav":      badMissing,
+		"continuation.sav": noContinuation,
+	} {
+		fn := filepath.Join(dir, name)
+		if err := os.WriteFile(fn, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open(fn); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"text.sav":  "id,name\n1,Ann\n",
+		"short.sav": "$FL2",
+	} {
+		fn := filepath.Join(dir, name)
+		if err := os.WriteFile(fn, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open(fn); !errors.Is(err, grate.ErrNotInFormat) {
+			t.Errorf("%s: got error %v, want ErrNotInFormat", name, err)
+		}
+	}
+}
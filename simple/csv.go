@@ -1,8 +1,9 @@
 package simple
 
 import (
-	"encoding/csv"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/pbnjay/grate"
 )
@@ -11,39 +12,94 @@ var _ = grate.Register("csv", 15, OpenCSV)
 
 // OpenCSV defines a Source's instantiation function.
 // It should return ErrNotInFormat immediately if filename is not of the correct file type.
+//
+// The delimiter, quote character, header and encoding of the file are
+// detected from its start.
 func OpenCSV(filename string) (grate.Source, error) {
+	return OpenWithOptions(filename, Options{})
+}
+
+// OpenWithOptions opens a delimited text file, detecting only the parts of
+// its dialect which are not set by the options. The detected dialect is
+// available from the Dialect method of the returned Source.
+func OpenWithOptions(filename string, opts Options) (grate.Source, error) {
+	t, err := openDelimited(filename, opts)
+	if t == nil {
+		return nil, err
+	}
+	return t, err
+}
+
+// openDelimited detects the dialect of a delimited text file from a sample
+// of its start, and checks that the sample looks like delimited text.
+// Records are read from the file as needed when iterated.
+func openDelimited(filename string, opts Options) (*simpleFile, error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
+
+	sample := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, sample)
+	complete := err == io.EOF || err == io.ErrUnexpectedEOF
+	if err != nil && !complete {
+		return nil, err
+	}
+	dialect, text, err := sniff(sample[:n], complete, opts)
+	if err != nil {
+		return nil, err
+	}
 	t := &simpleFile{
 		filename: filename,
-		iterRow:  -1,
+		dialect:  dialect,
+		footer:   opts.Footer,
 	}
 
-	s := csv.NewReader(f)
-	s.FieldsPerRecord = -1
+	s := newDelimReader(strings.NewReader(text), dialect)
+	var preamble []string
+	for i := 0; i < dialect.Preamble; i++ {
+		line, err := s.readLine()
+		if err != nil {
+			break
+		}
+		preamble = append(preamble, line)
+	}
+	t.metadata = parseMetadata(preamble, dialect)
 
-	total := 0
-	ncols := make(map[int]int)
+	var sampled [][]string
 	rec, err := s.Read()
 	for ; err == nil; rec, err = s.Read() {
+		sampled = append(sampled, rec)
+	}
+	if err != io.EOF && complete {
+		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+	}
+	t.width = tableWidth(sampled)
+	if complete {
+		sampled = sampled[:footerStart(sampled, t.width, t.footer)]
+	}
+	t.empty = len(sampled) == 0
+
+	total := len(sampled)
+	ncols := make(map[int]int)
+	for _, rec := range sampled {
 		ncols[len(rec)]++
-		total++
-		t.rows = append(t.rows, rec)
 	}
-	if err != nil {
-		switch perr := err.(type) {
-		case *csv.ParseError:
-			return nil, grate.WrapErr(perr, grate.ErrNotInFormat)
-		}
-		if total < 10 {
-			// probably? not in this format
-			return nil, grate.WrapErr(err, grate.ErrNotInFormat)
-		}
-		return nil, err
+	recs := sampled
+	if len(recs) > inferRecords {
+		recs = recs[:inferRecords]
+	}
+
+	if dialect.Header && len(recs) > 0 {
+		recs = recs[1:]
+	}
+	if opts.Locale != nil {
+		t.locale = *opts.Locale
+	} else {
+		t.locale = detectLocale(recs)
 	}
+	t.columns = inferColumns(recs, t.locale)
 
 	// kinda arbitrary metrics for detecting CSV
 	looksGood := 0
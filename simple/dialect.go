@@ -0,0 +1,344 @@
+package simple
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commontext"
+)
+
+// Dialect describes the format of a delimited text file.
+type Dialect struct {
+	// Delimiter separates the fields of a record, e.g. ',' or '\t'.
+	Delimiter rune
+
+	// Quote encloses fields containing delimiters, quotes or line breaks,
+	// and is doubled within them. It is 0 if fields are not quoted.
+	Quote rune
+
+	// Escape precedes escaped characters, such as \t and \n in the text
+	// format of PostgreSQL COPY and MySQL. It is 0 if there are no escapes.
+	Escape rune
+
+	// Comment starts lines which are skipped. It is 0 if there are no
+	// comments.
+	Comment rune
+
+	// Header is true if the first record names the columns.
+	Header bool
+
+	// Preamble is the number of lines before the table, such as the
+	// account details at the top of a bank statement. Detected preambles
+	// are lines with at most half the fields of the table.
+	Preamble int
+
+	// Encoding is the character encoding of the file.
+	Encoding *commontext.Encoding
+}
+
+// HeaderOption selects whether the first record of a file is a header.
+type HeaderOption int
+
+// Header options.
+const (
+	HeaderDetect HeaderOption = iota
+	HeaderPresent
+	HeaderAbsent
+)
+
+// NoQuote, NoEscape and NoComment disable quoting, escapes and comments
+// when used as Options.Quote, Options.Escape and Options.Comment.
+const (
+	NoQuote   rune = -1
+	NoEscape  rune = -1
+	NoComment rune = -1
+)
+
+// NoPreamble and NoFooter disable the detection of preambles and footers
+// when used as Options.Preamble and Options.Footer.
+const (
+	NoPreamble = -1
+	NoFooter   = -1
+)
+
+// Options override the detected dialect of a delimited text file. Zero
+// values are detected from the start of the file.
+type Options struct {
+	// Delimiter separates the fields of a record.
+	Delimiter rune
+
+	// Quote encloses fields, or is NoQuote if fields are not quoted.
+	Quote rune
+
+	// Escape precedes escaped characters, or is NoEscape if there are no
+	// escapes. Escapes are only detected in tab delimited files.
+	Escape rune
+
+	// Encoding is the label of the character encoding, e.g. "utf-16le"
+	// or "windows-1252".
+	Encoding string
+
+	// Comment starts lines which are skipped, or is NoComment if there
+	// are no comments. Only '#' is detected.
+	Comment rune
+
+	// Header selects whether the first record is a header.
+	Header HeaderOption
+
+	// Preamble is the number of lines before the table, or NoPreamble if
+	// the table starts on the first line.
+	Preamble int
+
+	// Footer is the number of records after the table, such as totals,
+	// or NoFooter if the table continues to the end of the file. Detected
+	// footers are records with at most half the fields of the table, so
+	// records are read ahead until a longer record or the end of the file.
+	Footer int
+
+	// Locale is used to read numbers and dates, or nil to detect it.
+	Locale *Locale
+}
+
+const (
+	// sniffSize is the length of the sample used to detect the dialect.
+	sniffSize = 64 << 10
+
+	// sniffRecords is the number of records used to detect the dialect.
+	sniffRecords = 20
+
+	// inferRecords is the number of records used to infer column types.
+	inferRecords = 1000
+)
+
+// delimiters which are detected, in order of preference
+var sniffDelimiters = []rune{',', '\t', ';', '|'}
+
+// sniff detects the dialect of a delimited text file from a sample of its
+// start, and returns the decoded sample. If the sample does not contain the
+// whole file, the incomplete last line is ignored.
+func sniff(sample []byte, complete bool, opts Options) (Dialect, string, error) {
+	var d Dialect
+	if opts.Encoding != "" {
+		d.Encoding = commontext.LookupEncoding(opts.Encoding)
+		if d.Encoding == nil {
+			return d, "", fmt.Errorf("grate/simple: unsupported encoding %q", opts.Encoding)
+		}
+	} else {
+		d.Encoding = commontext.DetectEncoding(sample)
+	}
+	if enc, n := commontext.DetectBOM(sample); enc == d.Encoding {
+		sample = sample[n:]
+	}
+	if bytes.IndexByte(sample, 0) >= 0 && d.Encoding != commontext.UTF16LE && d.Encoding != commontext.UTF16BE {
+		// binary data
+		return d, "", grate.ErrNotInFormat
+	}
+	text := d.Encoding.Decode(sample)
+	if !complete {
+		if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+			text = text[:i+1]
+		}
+	}
+
+	quoted := 0
+	switch opts.Quote {
+	case 0:
+		d.Quote, quoted = detectQuote(text)
+	case NoQuote:
+		d.Quote = 0
+	default:
+		d.Quote = opts.Quote
+	}
+	d.Delimiter = opts.Delimiter
+	if d.Delimiter == 0 {
+		d.Delimiter = detectDelimiter(text, d.Quote)
+	}
+	if opts.Escape > 0 {
+		d.Escape = opts.Escape
+	} else if opts.Escape == 0 && d.Delimiter == '\t' && detectEscape(text) {
+		d.Escape = '\\'
+	}
+	if opts.Quote == 0 && d.Delimiter == '\t' {
+		// unlike CSV, TSV is usually unquoted, so only use quotes when
+		// they start fields and are balanced
+		if quoted == 0 || (complete && !parses(text, d)) {
+			d.Quote = 0
+		}
+	}
+
+	switch {
+	case opts.Comment > 0:
+		d.Comment = opts.Comment
+	case opts.Comment == 0:
+		d.Comment = detectComment(text)
+	}
+	switch {
+	case opts.Preamble > 0:
+		d.Preamble = opts.Preamble
+	case opts.Preamble == 0:
+		d.Preamble = detectPreamble(text, d)
+	}
+
+	switch opts.Header {
+	case HeaderDetect:
+		d.Header = detectHeader(sampleRecords(skipLines(text, d.Preamble), d, sniffRecords))
+	case HeaderPresent:
+		d.Header = true
+	}
+	return d, text, nil
+}
+
+// sampleRecords parses the first n records of a sample.
+func sampleRecords(text string, d Dialect, n int) [][]string {
+	r := newDelimReader(strings.NewReader(text), d)
+	var res [][]string
+	for len(res) < n {
+		rec, err := r.Read()
+		if err != nil {
+			break
+		}
+		res = append(res, rec)
+	}
+	return res
+}
+
+// parses returns true if all of a sample can be parsed in a dialect.
+func parses(text string, d Dialect) bool {
+	r := newDelimReader(strings.NewReader(text), d)
+	for {
+		_, err := r.Read()
+		if err != nil {
+			return err == io.EOF
+		}
+	}
+}
+
+// detectQuote returns the quote character which starts the most fields,
+// preferring double quotes, and the number of fields it starts.
+func detectQuote(text string) (rune, int) {
+	var count [2]int
+	prev := '\n'
+	for _, r := range text {
+		if strings.ContainsRune("\n,\t;|", prev) {
+			switch r {
+			case '"':
+				count[0]++
+			case '\'':
+				count[1]++
+			}
+		}
+		prev = r
+	}
+	if count[1] > count[0] {
+		return '\'', count[1]
+	}
+	return '"', count[0]
+}
+
+// detectEscape returns true if the text contains backslash escapes, and
+// every backslash is followed by an escaped character. This excludes text
+// such as Windows paths.
+func detectEscape(text string) bool {
+	found := false
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' {
+			continue
+		}
+		i++
+		if i == len(text) || !strings.ContainsRune(`tnrbfv0ZN\"'`+"\t\n", rune(text[i])) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// detectDelimiter returns the delimiter which splits the most records into
+// the same number of fields.
+func detectDelimiter(text string, quote rune) rune {
+	best, bestScore := sniffDelimiters[0], 0.0
+	for _, delim := range sniffDelimiters {
+		recs := sampleRecords(text, Dialect{Delimiter: delim, Quote: quote}, sniffRecords)
+		counts := make(map[int]int)
+		mode := 0
+		for _, rec := range recs {
+			if len(rec) < 2 {
+				continue
+			}
+			counts[len(rec)]++
+			if counts[len(rec)] > mode {
+				mode = counts[len(rec)]
+			}
+		}
+		if mode == 0 {
+			continue
+		}
+		if score := float64(mode) / float64(len(recs)); score > bestScore {
+			best, bestScore = delim, score
+		}
+	}
+	return best
+}
+
+// detectHeader returns true if the first record looks like a header. Each
+// column with values that are all numbers, dates or booleans, or all of the
+// same length, votes on whether its first value is different.
+func detectHeader(recs [][]string) bool {
+	if len(recs) < 2 {
+		return false
+	}
+	votes := 0
+	for col, name := range recs[0] {
+		typed, length := true, -1
+		n := 0
+		for _, rec := range recs[1:] {
+			if col >= len(rec) || rec[col] == "" {
+				continue
+			}
+			n++
+			if typed && !isTyped(rec[col]) {
+				typed = false
+			}
+			switch l := len([]rune(rec[col])); {
+			case length == -1:
+				length = l
+			case length != l:
+				length = -2
+			}
+		}
+		switch {
+		case n == 0:
+		case typed:
+			if isTyped(name) {
+				votes--
+			} else {
+				votes++
+			}
+		case length >= 0:
+			if len([]rune(name)) == length {
+				votes--
+			} else {
+				votes++
+			}
+		}
+	}
+	return votes > 0
+}
+
+// isTyped returns true if s can be read as a number, date or boolean.
+func isTyped(s string) bool {
+	if _, ok := LocaleUS.parseNumber(s); ok {
+		return true
+	}
+	if _, ok := LocaleEU.parseNumber(s); ok {
+		return true
+	}
+	if _, ok := parseBool(s); ok {
+		return true
+	}
+	_, ok := LocaleUS.parseDate(s, "")
+	return ok
+}
@@ -0,0 +1,143 @@
+package simple
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commontext"
+)
+
+func writeFile(t *testing.T, data string) string {
+	fn := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(fn, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func readAll(t *testing.T, src grate.Source) [][]string {
+	c, err := src.Get("data.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res [][]string
+	for c.Next() {
+		res = append(res, c.Strings())
+	}
+	return res
+}
+
+func TestSniff(t *testing.T) {
+	cases := []struct {
+		data    string
+		dialect Dialect
+		rows    [][]string
+	}{
+		{
+			"name;price\r\nfoo;1,5\r\nbar;2,25\r\n",
+			Dialect{';', '"', 0, 0, true, 0, commontext.UTF8},
+			[][]string{{"name", "price"}, {"foo", "1,5"}, {"bar", "2,25"}},
+		},
+		{
+			"1|2|3\n4|5|6\n",
+			Dialect{'|', '"', 0, 0, false, 0, commontext.UTF8},
+			[][]string{{"1", "2", "3"}, {"4", "5", "6"}},
+		},
+		{
+			"id,comment\n1,\"a, \"\"quoted\"\"\nline\"\n\n2,x\"y\n",
+			Dialect{',', '"', 0, 0, true, 0, commontext.UTF8},
+			[][]string{{"id", "comment"}, {"1", "a, \"quoted\"\nline"}, {"2", "x\"y"}},
+		},
+		{
+			"'a,b',c\n'd',e\n",
+			Dialect{',', '\'', 0, 0, false, 0, commontext.UTF8},
+			[][]string{{"a,b", "c"}, {"d", "e"}},
+		},
+		{
+			"\xff\xfec\x00a\x00f\x00\xe9\x00\t\x001\x00\r\x00\n\x00",
+			Dialect{'\t', 0, 0, 0, false, 0, commontext.UTF16LE},
+			[][]string{{"café", "1"}},
+		},
+		{
+			"caf\xe9;na\xefve\nd\xe9j\xe0;vu\n",
+			Dialect{';', '"', 0, 0, false, 0, commontext.Windows1252},
+			[][]string{{"café", "naïve"}, {"déjà", "vu"}},
+		},
+	}
+	for _, c := range cases {
+		src, err := OpenCSV(writeFile(t, c.data))
+		if err != nil {
+			t.Fatalf("%q: %v", c.data, err)
+		}
+		if d := src.(*simpleFile).Dialect(); d != c.dialect {
+			t.Fatalf("%q: got dialect %+v, expected %+v", c.data, d, c.dialect)
+		}
+		if got := readAll(t, src); !reflect.DeepEqual(got, c.rows) {
+			t.Fatalf("%q: got %q, expected %q", c.data, got, c.rows)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	fn := writeFile(t, "a;\"b\";c\xe9\n1;2;3\n")
+	src, err := OpenWithOptions(fn, Options{
+		Delimiter: ',',
+		Quote:     NoQuote,
+		Encoding:  "latin1",
+		Header:    HeaderPresent,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := src.(*simpleFile).Dialect(); d != (Dialect{',', 0, 0, 0, true, 0, commontext.Windows1252}) {
+		t.Fatalf("got dialect %+v", d)
+	}
+	expect := [][]string{{"a;\"b\";cé"}, {"1;2;3"}}
+	if got := readAll(t, src); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got %q, expected %q", got, expect)
+	}
+
+	if _, err := OpenWithOptions(fn, Options{Encoding: "ebcdic"}); err == nil {
+		t.Fatal("expected an error for an unsupported encoding")
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	if _, err := OpenTSV(writeFile(t, "a,b\n1,2\n")); !errors.Is(err, grate.ErrNotInFormat) {
+		t.Fatalf("expected ErrNotInFormat for a CSV file as TSV, got %v", err)
+	}
+	if _, err := OpenCSV(writeFile(t, "a,\"b\n1,2\n")); !errors.Is(err, grate.ErrNotInFormat) {
+		t.Fatalf("expected ErrNotInFormat for an unterminated quote, got %v", err)
+	}
+	if _, err := OpenCSV(writeFile(t, "PK\x03\x04\x00\x00binary")); !errors.Is(err, grate.ErrNotInFormat) {
+		t.Fatalf("expected ErrNotInFormat for binary data, got %v", err)
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("id,text\n")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&sb, "%d,\"line %d\nof text\"\n", i, i)
+	}
+	src, err := OpenCSV(writeFile(t, sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	for pass := 0; pass < 2; pass++ {
+		rows := readAll(t, src)
+		if len(rows) != 5001 {
+			t.Fatalf("pass %d: got %d rows", pass, len(rows))
+		}
+		if last := rows[5000]; !reflect.DeepEqual(last, []string{"4999", "line 4999\nof text"}) {
+			t.Fatalf("pass %d: got last row %q", pass, last)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package simple
+
+import (
+	"strings"
+)
+
+const (
+	// maxPreamble is the number of lines searched for the start of a table.
+	maxPreamble = 100
+
+	// maxFooter is the number of records which may be detected as a footer.
+	maxFooter = 20
+)
+
+// detectComment returns '#' if it starts some, but less than half, of the
+// lines of a sample.
+func detectComment(text string) rune {
+	lines, comments := 0, 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		lines++
+		if line[0] == '#' {
+			comments++
+		}
+	}
+	if comments > 0 && comments*2 < lines {
+		return '#'
+	}
+	return 0
+}
+
+// detectPreamble returns the number of lines before the table in a sample.
+// The lines of a preamble are short records, with at most half the fields
+// of the table.
+func detectPreamble(text string, d Dialect) int {
+	width := tableWidth(sampleRecords(text, d, inferRecords))
+	if width < 2 {
+		return 0
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > maxPreamble {
+		lines = lines[:maxPreamble]
+	}
+	for i, line := range lines {
+		rec, err := newDelimReader(strings.NewReader(line), d).Read()
+		if err != nil {
+			// blank line, comment or quoted line break
+			continue
+		}
+		if !isShort(rec, width) {
+			return i
+		}
+	}
+	return 0
+}
+
+// tableWidth returns the most common number of fields in records, or the
+// largest if there is a tie.
+func tableWidth(recs [][]string) int {
+	counts := make(map[int]int)
+	width := 0
+	for _, rec := range recs {
+		n := len(rec)
+		counts[n]++
+		if counts[n] > counts[width] || (counts[n] == counts[width] && n > width) {
+			width = n
+		}
+	}
+	return width
+}
+
+// isShort returns true if a record has at most half the fields of the
+// table, as in preambles and footers.
+func isShort(rec []string, width int) bool {
+	return len(rec)*2 <= width
+}
+
+// skipLines returns text after its first n lines.
+func skipLines(text string, n int) string {
+	for ; n > 0 && text != ""; n-- {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			return ""
+		}
+		text = text[i+1:]
+	}
+	return text
+}
+
+// parseMetadata reads the lines of a preamble as keys and values, from
+// either a record of fields, or a line such as "Key: value".
+func parseMetadata(lines []string, d Dialect) map[string]string {
+	res := make(map[string]string)
+	for _, line := range lines {
+		rec, err := newDelimReader(strings.NewReader(line), d).Read()
+		if err != nil {
+			continue
+		}
+		var vals []string
+		for _, v := range rec {
+			if v = strings.TrimSpace(v); v != "" {
+				vals = append(vals, v)
+			}
+		}
+		if len(vals) == 0 {
+			continue
+		}
+		key, val := vals[0], strings.Join(vals[1:], " ")
+		if len(vals) == 1 {
+			if i := strings.IndexByte(key, ':'); i > 0 {
+				key, val = key[:i], strings.TrimSpace(key[i+1:])
+			}
+		}
+		res[strings.TrimSpace(strings.TrimSuffix(key, ":"))] = val
+	}
+	return res
+}
+
+// footerStart returns the index of the first record of the footer of a
+// complete table. Detected footers are short records.
+func footerStart(recs [][]string, width, footer int) int {
+	switch {
+	case footer > 0:
+		if footer > len(recs) {
+			return 0
+		}
+		return len(recs) - footer
+	case footer == NoFooter || width < 2:
+		return len(recs)
+	}
+	n := len(recs)
+	for n > 0 && len(recs)-n < maxFooter && isShort(recs[n-1], width) {
+		n--
+	}
+	if n == 0 {
+		return len(recs)
+	}
+	return n
+}
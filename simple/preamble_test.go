@@ -0,0 +1,78 @@
+package simple
+
+import (
+	"reflect"
+	"testing"
+)
+
+const statement = `Account:;DE123
+Period;01.01.2021 - 31.01.2021
+Report generated: 2021-02-01
+
+Date;Payee;Amount;Balance;Memo
+01.01.2021;Shop;-12,50;100,00;x
+# pending transactions are not included
+02.01.2021;Rent;-500,00;-400,00;
+03.01.2021;Salary;1.000,00;600,00;y
+Total;487,50
+`
+
+func TestPreamble(t *testing.T) {
+	src, err := OpenCSV(writeFile(t, statement))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := src.(*simpleFile)
+	if d := f.Dialect(); d.Preamble != 4 || d.Comment != '#' || !d.Header {
+		t.Fatalf("got dialect %+v", d)
+	}
+	meta := map[string]string{
+		"Account":          "DE123",
+		"Period":           "01.01.2021 - 31.01.2021",
+		"Report generated": "2021-02-01",
+	}
+	if got := f.Metadata(); !reflect.DeepEqual(got, meta) {
+		t.Fatalf("got metadata %q", got)
+	}
+	expect := [][]string{
+		{"Date", "Payee", "Amount", "Balance", "Memo"},
+		{"01.01.2021", "Shop", "-12,50", "100,00", "x"},
+		{"02.01.2021", "Rent", "-500,00", "-400,00", ""},
+		{"03.01.2021", "Salary", "1.000,00", "600,00", "y"},
+	}
+	if got := readAll(t, src); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got %q\nexpected %q", got, expect)
+	}
+	types := []ColumnType{DateColumn, StringColumn, NumberColumn, NumberColumn, StringColumn}
+	if got := f.ColumnTypes(); !reflect.DeepEqual(got, types) {
+		t.Fatalf("got column types %v", got)
+	}
+}
+
+func TestPreambleOptions(t *testing.T) {
+	src, err := OpenWithOptions(writeFile(t, statement), Options{
+		Comment:  NoComment,
+		Preamble: 2,
+		Footer:   NoFooter,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := src.(*simpleFile)
+	if got := f.Metadata(); !reflect.DeepEqual(got, map[string]string{"Account": "DE123", "Period": "01.01.2021 - 31.01.2021"}) {
+		t.Fatalf("got metadata %q", got)
+	}
+	rows := readAll(t, src)
+	if len(rows) != 7 || rows[0][0] != "Report generated: 2021-02-01" ||
+		rows[3][0] != "# pending transactions are not included" || rows[6][0] != "Total" {
+		t.Fatalf("got %q", rows)
+	}
+
+	src, err = OpenWithOptions(writeFile(t, "a,b\n1,2\n3,4\n5,6\n"), Options{Footer: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, src); !reflect.DeepEqual(got, [][]string{{"a", "b"}, {"1", "2"}}) {
+		t.Fatalf("got %q", got)
+	}
+}
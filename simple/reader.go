@@ -0,0 +1,186 @@
+package simple
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// delimReader reads records of fields separated by a delimiter. Quoted
+// fields may contain delimiters, line breaks and doubled quotes. Lines may
+// be of any length.
+type delimReader struct {
+	r       *bufio.Reader
+	delim   rune
+	quote   rune // 0 if fields are not quoted
+	escape  rune // 0 if there are no escapes
+	comment rune // 0 if there are no comments
+	line    int
+	field   strings.Builder
+	null    bool // the field is \N
+}
+
+func newDelimReader(r io.Reader, d Dialect) *delimReader {
+	return &delimReader{
+		r:       bufio.NewReader(r),
+		delim:   d.Delimiter,
+		quote:   d.Quote,
+		escape:  d.Escape,
+		comment: d.Comment,
+	}
+}
+
+// Read the next record, skipping blank lines and comments. Quotes within unquoted fields,
+// and quotes within quoted fields which are not followed by a delimiter or
+// line break, are read as is.
+func (d *delimReader) Read() ([]string, error) {
+	r, err := d.skipIgnoredLines()
+	if err != nil {
+		return nil, err
+	}
+	d.line++
+
+	var rec []string
+	for {
+		d.field.Reset()
+		d.null = false
+		if d.quote != 0 && r == d.quote {
+			start := d.line
+			for {
+				r, _, err = d.r.ReadRune()
+				if err == io.EOF {
+					return nil, fmt.Errorf("grate/simple: unterminated quote on line %d", start)
+				}
+				if err != nil {
+					return nil, err
+				}
+				if r == '\n' {
+					d.line++
+				}
+				if d.escape != 0 && r == d.escape {
+					d.readEscape()
+					continue
+				}
+				if r != d.quote {
+					d.field.WriteRune(r)
+					continue
+				}
+				r, _, err = d.r.ReadRune()
+				if err != nil || r == d.delim || r == '\n' || r == '\r' {
+					break
+				}
+				if r != d.quote {
+					d.field.WriteRune(d.quote)
+				}
+				d.field.WriteRune(r)
+			}
+		} else {
+			for err == nil && r != d.delim && r != '\n' && r != '\r' {
+				if d.escape != 0 && r == d.escape {
+					d.readEscape()
+				} else {
+					d.field.WriteRune(r)
+				}
+				r, _, err = d.r.ReadRune()
+			}
+		}
+		if d.null && d.field.String() == "N" {
+			rec = append(rec, "")
+		} else {
+			rec = append(rec, d.field.String())
+		}
+
+		if err == io.EOF {
+			return rec, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if r != d.delim {
+			if r == '\r' {
+				d.skipNewline()
+			}
+			return rec, nil
+		}
+		r, _, err = d.r.ReadRune()
+		if err == io.EOF {
+			return append(rec, ""), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readEscape reads the character following an escape, as written by
+// PostgreSQL and MySQL. A field of \N is NULL, and read as empty.
+func (d *delimReader) readEscape() {
+	r, _, err := d.r.ReadRune()
+	if err != nil {
+		d.field.WriteRune(d.escape)
+		return
+	}
+	switch r {
+	case 't':
+		r = '\t'
+	case 'n':
+		r = '\n'
+	case 'r':
+		r = '\r'
+	case 'b':
+		r = '\b'
+	case 'f':
+		r = '\f'
+	case 'v':
+		r = '\v'
+	case '0':
+		r = 0
+	case 'Z':
+		r = 0x1A
+	case 'N':
+		d.null = d.field.Len() == 0
+	case '\n':
+		d.line++
+	}
+	d.field.WriteRune(r)
+}
+
+// skipIgnoredLines returns the first character of the next line which is
+// not blank or a comment.
+func (d *delimReader) skipIgnoredLines() (rune, error) {
+	for {
+		r, _, err := d.r.ReadRune()
+		switch {
+		case err != nil:
+			return r, err
+		case r == '\n':
+			d.line++
+		case d.comment != 0 && r == d.comment:
+			if _, err := d.r.ReadString('\n'); err != nil {
+				return 0, err
+			}
+			d.line++
+		case r != '\r':
+			return r, nil
+		}
+	}
+}
+
+// readLine reads the next line as is, without its line break.
+func (d *delimReader) readLine() (string, error) {
+	line, err := d.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	d.line++
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// skipNewline skips the line feed of a CRLF line break.
+func (d *delimReader) skipNewline() {
+	r, _, err := d.r.ReadRune()
+	if err == nil && r != '\n' {
+		d.r.UnreadRune()
+	}
+}
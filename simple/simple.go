@@ -1,21 +1,38 @@
 package simple
 
 import (
-	"errors"
+	"bufio"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commontext"
 )
 
-// represents a set of data collections.
+// represents a set of data collections. Records are read from the file
+// as needed, so the file remains open until the source is closed.
 type simpleFile struct {
 	filename string
-	rows     [][]string
-	iterRow  int
+	dialect  Dialect
+	locale   Locale
+	columns  []column
+	width    int // the usual number of fields in a record
+	footer   int // Options.Footer
+	metadata map[string]string
+	empty    bool
+
+	f      *os.File
+	r      *delimReader
+	row    []string
+	queue  [][]string // records read ahead, which may be a footer
+	ready  int        // the number of queued records which are not a footer
+	recNum int
+	err    error
+	done   bool
 }
 
 // List the individual data tables within this source.
@@ -23,56 +40,194 @@ func (t *simpleFile) List() ([]string, error) {
 	return []string{filepath.Base(t.filename)}, nil
 }
 
+// Dialect returns the dialect of the file.
+func (t *simpleFile) Dialect() Dialect {
+	return t.dialect
+}
+
+// Metadata returns the keys and values of the preamble before the table.
+func (t *simpleFile) Metadata() map[string]string {
+	return t.metadata
+}
+
 func (t *simpleFile) Close() error {
-	return nil
+	t.r = nil
+	if t.f == nil {
+		return nil
+	}
+	err := t.f.Close()
+	t.f = nil
+	return err
 }
 
 // Get a Collection from the source by name.
 func (t *simpleFile) Get(name string) (grate.Collection, error) {
+	t.Rewind()
+	if t.err != nil {
+		return nil, t.err
+	}
 	return t, nil
 }
 
+// Rewind returns to the first record by reopening the file.
+func (t *simpleFile) Rewind() {
+	t.Close()
+	t.row, t.queue, t.ready = nil, nil, 0
+	t.recNum, t.err, t.done = 0, nil, false
+	f, err := os.Open(t.filename)
+	if err != nil {
+		t.err = err
+		return
+	}
+	br := bufio.NewReader(f)
+	bom, _ := br.Peek(3)
+	if enc, n := commontext.DetectBOM(bom); enc == t.dialect.Encoding {
+		br.Discard(n)
+	}
+	t.f = f
+	t.r = newDelimReader(t.dialect.Encoding.NewReader(br), t.dialect)
+	for i := 0; i < t.dialect.Preamble; i++ {
+		if _, err := t.r.readLine(); err != nil {
+			break
+		}
+	}
+}
+
 // Next advances to the next record of content.
 // It MUST be called prior to any Scan().
 func (t *simpleFile) Next() bool {
-	t.iterRow++
-	return t.iterRow < len(t.rows)
+	if t.r == nil {
+		if t.done || t.err != nil {
+			return false
+		}
+		t.Rewind()
+		if t.err != nil {
+			return false
+		}
+	}
+	for t.ready == 0 {
+		rec, err := t.r.Read()
+		if err == io.EOF {
+			// the remaining queued records are the footer
+			t.done = true
+			t.Close()
+			return false
+		}
+		if err != nil {
+			t.err = err
+			return false
+		}
+		t.queue = append(t.queue, rec)
+		switch {
+		case t.footer > 0:
+			t.ready = len(t.queue) - t.footer
+		case t.footer == NoFooter || t.width < 2 || !isShort(rec, t.width):
+			t.ready = len(t.queue)
+		case len(t.queue) > maxFooter:
+			t.ready = len(t.queue) - maxFooter
+		}
+		if t.ready < 0 {
+			t.ready = 0
+		}
+	}
+	t.row = t.queue[0]
+	t.queue = t.queue[1:]
+	t.ready--
+	t.recNum++
+	return true
 }
 
 // Strings extracts values from the current record into a list of strings.
 func (t *simpleFile) Strings() []string {
-	return t.rows[t.iterRow]
+	return t.row
+}
+
+// Locale returns the locale used to read numbers and dates.
+func (t *simpleFile) Locale() Locale {
+	return t.locale
+}
+
+// ColumnTypes returns the types of the columns, as inferred from the start
+// of the file.
+func (t *simpleFile) ColumnTypes() []ColumnType {
+	res := make([]ColumnType, len(t.columns))
+	for i, c := range t.columns {
+		res[i] = c.typ
+	}
+	return res
+}
+
+// Values returns the current record as typed values: float64, time.Time or
+// bool for fields which can be read as the type of their column, string
+// for other fields, and nil for empty fields. The header is returned as
+// strings.
+func (t *simpleFile) Values() []interface{} {
+	res := make([]interface{}, len(t.row))
+	for i, s := range t.row {
+		if i >= len(t.columns) || (t.dialect.Header && t.recNum == 1) {
+			res[i] = s
+			continue
+		}
+		res[i] = t.locale.value(s, t.columns[i])
+	}
+	return res
 }
 
 // Scan extracts values from the current record into the provided arguments
 // Arguments must be pointers to one of 5 supported types:
 //     bool, int, float64, string, or time.Time
+// Numbers and dates are read in the locale of the file, and empty fields
+// are scanned as zero values.
 func (t *simpleFile) Scan(args ...interface{}) error {
 	var err error
-	row := t.rows[t.iterRow]
+	row := t.row
 	if len(row) != len(args) {
 		return fmt.Errorf("grate/simple: expected %d Scan destinations, got %d", len(row), len(args))
 	}
 
 	for i, a := range args {
+		empty := strings.TrimSpace(row[i]) == ""
 		switch v := a.(type) {
 		case *bool:
-			switch strings.ToLower(row[i]) {
-			case "1", "t", "true", "y", "yes":
-				*v = true
-			default:
-				*v = false
+			var ok bool
+			if *v, ok = parseBool(row[i]); !ok {
+				f, _ := t.locale.parseNumber(row[i])
+				*v = f != 0
 			}
 		case *int:
-			var n int64
-			n, err = strconv.ParseInt(row[i], 10, 64)
-			*v = int(n)
+			*v = 0
+			if !empty {
+				f, ok := t.locale.parseNumber(row[i])
+				if !ok {
+					return fmt.Errorf("grate/simple: %q is not a number", row[i])
+				}
+				*v, err = toInt(f)
+			}
 		case *float64:
-			*v, err = strconv.ParseFloat(row[i], 64)
+			*v = 0
+			if !empty {
+				var ok bool
+				if *v, ok = t.locale.parseNumber(row[i]); !ok {
+					return fmt.Errorf("grate/simple: %q is not a number", row[i])
+				}
+			}
 		case *string:
 			*v = row[i]
 		case *time.Time:
-			return errors.New("grate/simple: time.Time not supported, you must parse date strings manually")
+			*v = time.Time{}
+			if !empty {
+				layout := ""
+				if i < len(t.columns) && t.columns[i].typ == DateColumn {
+					layout = t.columns[i].layout
+				}
+				var ok bool
+				if *v, ok = t.locale.parseDate(row[i], layout); !ok && layout != "" {
+					*v, ok = t.locale.parseDate(row[i], "")
+				}
+				if !ok {
+					return fmt.Errorf("grate/simple: %q is not a date", row[i])
+				}
+			}
 		default:
 			return grate.ErrInvalidScanType
 		}
@@ -85,10 +240,10 @@ func (t *simpleFile) Scan(args ...interface{}) error {
 
 // IsEmpty returns true if there are no data values.
 func (t *simpleFile) IsEmpty() bool {
-	return len(t.rows) == 0
+	return t.empty
 }
 
 // Err returns the last error that occured.
 func (t *simpleFile) Err() error {
-	return nil
+	return t.err
 }
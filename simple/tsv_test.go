@@ -0,0 +1,62 @@
+package simple
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pbnjay/grate/commontext"
+)
+
+func TestTSV(t *testing.T) {
+	cases := []struct {
+		data    string
+		dialect Dialect
+		rows    [][]string
+	}{
+		{
+			"0\t\"b\tc\"\n1\t\"x\ny \"\"z\"\"\"\n",
+			Dialect{'\t', '"', 0, 0, false, 0, commontext.UTF8},
+			[][]string{{"0", "b\tc"}, {"1", "x\ny \"z\""}},
+		},
+		{
+			"1\tfoo\\tbar\n2\t\\N\n3\tline\\nbreak\\\\\n",
+			Dialect{'\t', 0, '\\', 0, false, 0, commontext.UTF8},
+			[][]string{{"1", "foo\tbar"}, {"2", ""}, {"3", "line\nbreak\\"}},
+		},
+		{
+			"a\tC:\\Users\\new\n",
+			Dialect{'\t', 0, 0, 0, false, 0, commontext.UTF8},
+			[][]string{{"a", "C:\\Users\\new"}},
+		},
+		{
+			"5\"\tsmall\n7\"\tlarge\n\"hello\tother\n",
+			Dialect{'\t', 0, 0, 0, false, 0, commontext.UTF8},
+			[][]string{{"5\"", "small"}, {"7\"", "large"}, {"\"hello", "other"}},
+		},
+	}
+	for _, c := range cases {
+		src, err := OpenTSV(writeFile(t, c.data))
+		if err != nil {
+			t.Fatalf("%q: %v", c.data, err)
+		}
+		if d := src.(*simpleFile).Dialect(); d != c.dialect {
+			t.Fatalf("%q: got dialect %+v, expected %+v", c.data, d, c.dialect)
+		}
+		if got := readAll(t, src); !reflect.DeepEqual(got, c.rows) {
+			t.Fatalf("%q: got %q, expected %q", c.data, got, c.rows)
+		}
+	}
+}
+
+func TestLongLines(t *testing.T) {
+	long := strings.Repeat("x", 200000)
+	src, err := OpenTSV(writeFile(t, "a\tb\n"+long+"\t"+long+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readAll(t, src)
+	if len(rows) != 2 || len(rows[1]) != 2 || rows[1][1] != long {
+		t.Fatalf("got %d rows", len(rows))
+	}
+}
@@ -0,0 +1,348 @@
+package simple
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// Locale describes how numbers and dates are written.
+type Locale struct {
+	// Decimal separates the fractional part of numbers, e.g. '.' or ','.
+	Decimal rune
+
+	// Thousands separates groups of digits, e.g. ',', '.', ' ' or '\''.
+	// It is 0 if digits are not grouped.
+	Thousands rune
+
+	// DayFirst prefers day/month/year to month/day/year when reading
+	// numeric dates which could be either.
+	DayFirst bool
+}
+
+// Common locales.
+var (
+	LocaleUS = Locale{Decimal: '.', Thousands: ','}
+	LocaleEU = Locale{Decimal: ',', Thousands: '.', DayFirst: true}
+)
+
+// ColumnType is the type of the values in a column, as inferred from the
+// start of a file.
+type ColumnType int
+
+// Column types.
+const (
+	StringColumn ColumnType = iota
+	NumberColumn
+	DateColumn
+	BoolColumn
+)
+
+func (c ColumnType) String() string {
+	switch c {
+	case NumberColumn:
+		return "number"
+	case DateColumn:
+		return "date"
+	case BoolColumn:
+		return "bool"
+	}
+	return "string"
+}
+
+// column describes the values of a column.
+type column struct {
+	typ    ColumnType
+	layout string // time layout of dates
+}
+
+// date layouts which are detected, with numeric dates in month first and
+// day first order
+var (
+	isoLayouts = []string{
+		"2006-01-02",
+		"2006-01-02T15:04:05Z07:00",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006/01/02",
+		"2006/01/02 15:04:05",
+	}
+	monthFirstLayouts = []string{
+		"1/2/2006",
+		"1/2/06",
+		"1/2/2006 15:04",
+		"1/2/2006 15:04:05",
+		"1/2/2006 3:04 PM",
+		"1/2/2006 3:04:05 PM",
+		"1-2-2006",
+	}
+	dayFirstLayouts = []string{
+		"2/1/2006",
+		"2/1/06",
+		"2/1/2006 15:04",
+		"2/1/2006 15:04:05",
+		"2.1.2006",
+		"2.1.06",
+		"2.1.2006 15:04",
+		"2.1.2006 15:04:05",
+		"2-1-2006",
+	}
+	monthNameLayouts = []string{
+		"2-Jan-2006",
+		"2-Jan-06",
+		"2 Jan 2006",
+		"2 January 2006",
+		"Jan 2, 2006",
+		"January 2, 2006",
+		"Jan 2 2006",
+		"2-Jan-2006 15:04:05",
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		"Mon Jan 2 15:04:05 2006",
+	}
+)
+
+// dateLayouts returns the date layouts to detect, in order of preference.
+func (l Locale) dateLayouts() []string {
+	res := append([]string{}, isoLayouts...)
+	if l.DayFirst {
+		res = append(res, dayFirstLayouts...)
+		res = append(res, monthFirstLayouts...)
+	} else {
+		res = append(res, monthFirstLayouts...)
+		res = append(res, dayFirstLayouts...)
+	}
+	return append(res, monthNameLayouts...)
+}
+
+// parseNumber parses a number written in the locale, which may include
+// grouped digits, currency symbols, a percent sign, and a sign or
+// parentheses for negative numbers.
+func (l Locale) parseNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if len(s) > 2 && s[0] == '(' && s[len(s)-1] == ')' {
+		neg = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	s = strings.TrimFunc(s, isCurrency)
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		neg = neg != (s[0] == '-')
+		s = strings.TrimFunc(s[1:], isCurrency)
+	} else if strings.HasSuffix(s, "-") {
+		// trailing minus, as in some accounting exports
+		neg = !neg
+		s = strings.TrimSpace(s[:len(s)-1])
+	}
+	percent := false
+	if strings.HasSuffix(s, "%") {
+		percent = true
+		s = strings.TrimSpace(s[:len(s)-1])
+	}
+
+	intPart, frac := s, ""
+	if i := strings.LastIndex(s, string(l.Decimal)); i >= 0 {
+		intPart, frac = s[:i], s[i+len(string(l.Decimal)):]
+	}
+	exp := ""
+	if i := strings.IndexAny(frac, "eE"); i >= 0 {
+		frac, exp = frac[:i], frac[i:]
+	} else if i := strings.IndexAny(intPart, "eE"); i >= 0 && frac == "" {
+		intPart, exp = intPart[:i], intPart[i:]
+	}
+	if !isDigits(frac) {
+		return 0, false
+	}
+	if l.Thousands == ' ' {
+		// also accept the no-break spaces used to group digits
+		intPart = strings.NewReplacer("\u00a0", " ", "\u202f", " ").Replace(intPart)
+	}
+	if l.Thousands != 0 && strings.ContainsRune(intPart, l.Thousands) {
+		groups := strings.Split(intPart, string(l.Thousands))
+		if len(groups[0]) == 0 || len(groups[0]) > 3 {
+			return 0, false
+		}
+		for _, g := range groups[1:] {
+			if len(g) != 3 {
+				return 0, false
+			}
+		}
+		intPart = strings.Join(groups, "")
+	}
+	if (intPart == "" && frac == "") || !isDigits(intPart) {
+		return 0, false
+	}
+	if exp != "" {
+		e := exp[1:]
+		if strings.HasPrefix(e, "-") || strings.HasPrefix(e, "+") {
+			e = e[1:]
+		}
+		if e == "" || !isDigits(e) {
+			return 0, false
+		}
+	}
+
+	f, err := strconv.ParseFloat(intPart+"."+frac+exp, 64)
+	if err != nil {
+		return 0, false
+	}
+	if neg {
+		f = -f
+	}
+	if percent {
+		f /= 100
+	}
+	return f, true
+}
+
+func isCurrency(r rune) bool {
+	return unicode.Is(unicode.Sc, r) || unicode.IsSpace(r)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseDate parses a date in the layout, or in any of the detected layouts
+// if it is empty. Numbers are read as Excel serial dates.
+func (l Locale) parseDate(s, layout string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if layout != "" {
+		t, err := time.Parse(layout, s)
+		return t, err == nil
+	}
+	for _, layout := range l.dateLayouts() {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	if f, ok := l.parseNumber(s); ok && f >= 0 && f < 2958466 {
+		return excelDates.ConvertToDate(f), true
+	}
+	return time.Time{}, false
+}
+
+var excelDates commonxl.Formatter
+
+func parseBool(s string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "yes", "t", "y":
+		return true, true
+	case "false", "no", "f", "n":
+		return false, true
+	}
+	return false, false
+}
+
+// detectLocale returns the locale used by the numbers in sample records,
+// by counting the values which can only be read as US or European numbers.
+func detectLocale(recs [][]string) Locale {
+	us, eu := 0, 0
+	for _, rec := range recs {
+		for _, v := range rec {
+			_, okUS := LocaleUS.parseNumber(v)
+			_, okEU := LocaleEU.parseNumber(v)
+			switch {
+			case okUS && !okEU:
+				us++
+			case okEU && !okUS:
+				eu++
+			}
+		}
+	}
+	if eu > us {
+		return LocaleEU
+	}
+	return LocaleUS
+}
+
+// inferColumns infers the type of each column from sample records. Dates
+// must all be in the same layout.
+func inferColumns(recs [][]string, loc Locale) []column {
+	ncols := 0
+	for _, rec := range recs {
+		if len(rec) > ncols {
+			ncols = len(rec)
+		}
+	}
+	layouts := loc.dateLayouts()
+	res := make([]column, ncols)
+	for i := range res {
+		var vals []string
+		for _, rec := range recs {
+			if i < len(rec) && strings.TrimSpace(rec[i]) != "" {
+				vals = append(vals, rec[i])
+			}
+		}
+		if len(vals) == 0 {
+			continue
+		}
+		if all(vals, func(s string) bool { _, ok := loc.parseNumber(s); return ok }) {
+			res[i].typ = NumberColumn
+			continue
+		}
+		for _, layout := range layouts {
+			if all(vals, func(s string) bool { _, ok := loc.parseDate(s, layout); return ok }) {
+				res[i] = column{typ: DateColumn, layout: layout}
+				break
+			}
+		}
+		if res[i].typ == StringColumn && all(vals, func(s string) bool { _, ok := parseBool(s); return ok }) {
+			res[i].typ = BoolColumn
+		}
+	}
+	return res
+}
+
+func all(vals []string, fn func(string) bool) bool {
+	for _, v := range vals {
+		if !fn(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// value returns the typed value of a field in a column: a float64,
+// time.Time or bool, the string if it can not be read as the type of the
+// column, or nil if the field is empty.
+func (l Locale) value(s string, col column) interface{} {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	switch col.typ {
+	case NumberColumn:
+		if f, ok := l.parseNumber(s); ok {
+			return f
+		}
+	case DateColumn:
+		if t, ok := l.parseDate(s, col.layout); ok {
+			return t
+		}
+	case BoolColumn:
+		if b, ok := parseBool(s); ok {
+			return b
+		}
+	}
+	return s
+}
+
+// errNotInteger is returned by Scan for numbers with a fractional part.
+var errNotInteger = errors.New("grate/simple: number is not an integer")
+
+func toInt(f float64) (int, error) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, errNotInteger
+	}
+	return int(f), nil
+}
@@ -0,0 +1,114 @@
+package simple
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseNumber(t *testing.T) {
+	cases := []struct {
+		loc  Locale
+		s    string
+		want float64
+		ok   bool
+	}{
+		{LocaleUS, "1,234.5", 1234.5, true},
+		{LocaleUS, "$1,234,567", 1234567, true},
+		{LocaleUS, "(12.50)", -12.5, true},
+		{LocaleUS, "-$5", -5, true},
+		{LocaleUS, "12.5%", 0.125, true},
+		{LocaleUS, "1e3", 1000, true},
+		{LocaleUS, ".5", 0.5, true},
+		{LocaleUS, "1,23", 0, false},
+		{LocaleUS, "1.234,5", 0, false},
+		{LocaleUS, "Inf", 0, false},
+		{LocaleUS, "2021-01-02", 0, false},
+		{LocaleEU, "1.234,5 €", 1234.5, true},
+		{LocaleEU, "3,14", 3.14, true},
+		{LocaleEU, "42-", -42, true},
+		{Locale{Decimal: ',', Thousands: ' '}, "1 234,5", 1234.5, true},
+		{Locale{Decimal: '.', Thousands: '\''}, "CHF 1'000.25", 0, false},
+		{Locale{Decimal: '.', Thousands: '\''}, "1'000.25", 1000.25, true},
+	}
+	for _, c := range cases {
+		got, ok := c.loc.parseNumber(c.s)
+		if ok != c.ok || got != c.want {
+			t.Fatalf("%q: got %v %v, expected %v %v", c.s, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	day := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		loc  Locale
+		s    string
+		want time.Time
+	}{
+		{LocaleUS, "2021-03-04", day},
+		{LocaleUS, "3/4/2021", day},
+		{LocaleEU, "4/3/2021", day},
+		{LocaleEU, "04.03.2021", day},
+		{LocaleUS, "4-Mar-2021", day},
+		{LocaleUS, "March 4, 2021", day},
+		{LocaleUS, "44259", day},
+		{LocaleUS, "2021-03-04T12:30:00Z", day.Add(12*time.Hour + 30*time.Minute)},
+	}
+	for _, c := range cases {
+		got, ok := c.loc.parseDate(c.s, "")
+		if !ok || !got.Equal(c.want) {
+			t.Fatalf("%q: got %v %v, expected %v", c.s, got, ok, c.want)
+		}
+	}
+}
+
+func TestTypes(t *testing.T) {
+	src, err := OpenCSV(writeFile(t, "date;amount;paid;memo\n"+
+		"31/12/2021;(1.234,50);yes;rent\n"+
+		"1/1/2022;12,00;no;\n"+
+		"2/1/2022;7;yes;food\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := src.(*simpleFile)
+	if f.Locale() != LocaleEU {
+		t.Fatalf("got locale %+v", f.Locale())
+	}
+	types := []ColumnType{DateColumn, NumberColumn, BoolColumn, StringColumn}
+	if got := f.ColumnTypes(); !reflect.DeepEqual(got, types) {
+		t.Fatalf("got column types %v, expected %v", got, types)
+	}
+
+	c, _ := src.Get("data.txt")
+	c.Next()
+	if got := f.Values(); !reflect.DeepEqual(got, []interface{}{"date", "amount", "paid", "memo"}) {
+		t.Fatalf("got header %v", got)
+	}
+	c.Next()
+	expect := []interface{}{time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC), -1234.5, true, "rent"}
+	if got := f.Values(); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got %v, expected %v", got, expect)
+	}
+
+	c.Next()
+	var (
+		date time.Time
+		amt  float64
+		n    int
+		paid bool
+		memo string
+	)
+	if err := c.Scan(&date, &amt, &paid, &memo); err != nil {
+		t.Fatal(err)
+	}
+	if !date.Equal(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)) || amt != 12 || paid || memo != "" {
+		t.Fatalf("scanned %v %v %v %q", date, amt, paid, memo)
+	}
+	if err := c.Scan(&date, &n, &paid, &memo); err != nil || n != 12 {
+		t.Fatalf("scanned %d: %v", n, err)
+	}
+	if err := c.Scan(&n, &amt, &paid, &memo); err == nil {
+		t.Fatal("expected an error scanning a date as a number")
+	}
+}
@@ -0,0 +1,314 @@
+// Package sylk reads Symbolic Link (SYLK, .slk) files, a text format for
+// spreadsheets written by Multiplan, Excel and many older applications.
+package sylk
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commontext"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+var _ = grate.Register("sylk", 13, Open)
+
+// limits on the cells of a sheet
+const (
+	maxRows = 1 << 20
+	maxCols = 1 << 14
+)
+
+// Document contains the single sheet of a SYLK file.
+type Document struct {
+	filename string
+	sheet    *commonxl.Sheet
+	fmt      commonxl.Formatter
+
+	formats    []uint16 // number formats of the P records
+	cellFmts   map[[2]int]uint16
+	rowFmts    map[int]uint16
+	colFmts    map[int]uint16
+	defaultFmt uint16
+}
+
+// cell is a value waiting for the formats of the file.
+type cell struct {
+	row, col int
+	value    interface{}
+}
+
+// Open a SYLK file.
+func Open(filename string) (grate.Source, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	head, _ := br.Peek(512)
+	if enc, n := commontext.DetectBOM(head); enc == commontext.UTF8 {
+		head = head[n:]
+		br.Discard(n)
+	}
+	if !isSYLK(head) {
+		return nil, grate.ErrNotInFormat
+	}
+	data, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Document{
+		filename: filename,
+		sheet:    &commonxl.Sheet{},
+		cellFmts: make(map[[2]int]uint16),
+		rowFmts:  make(map[int]uint16),
+		colFmts:  make(map[int]uint16),
+	}
+	d.parse(commontext.DetectEncoding(data).Decode(data))
+	return d, nil
+}
+
+// isSYLK reports whether a file starts with an ID record followed by
+// another record, rather than a CSV file with semicolons and an ID column.
+func isSYLK(head []byte) bool {
+	lines := bytes.SplitN(head, []byte("\n"), 3)
+	if !bytes.HasPrefix(lines[0], []byte("ID;P")) {
+		return false
+	}
+	if len(lines) < 2 {
+		return true
+	}
+	rec := bytes.TrimRight(lines[1], "\r")
+	if i := bytes.IndexByte(rec, ';'); i >= 0 {
+		rec = rec[:i]
+	}
+	switch string(rec) {
+	case "B", "C", "E", "F", "NE", "NN", "NU", "O", "P", "W":
+		return true
+	}
+	return false
+}
+
+// parse the records of the file. Cell values are placed after all the
+// records are read, as formats may follow the cells they apply to.
+func (d *Document) parse(text string) {
+	var cells []cell
+	row, col := 0, 0
+	for _, line := range strings.Split(text, "\n") {
+		fields := splitFields(strings.TrimRight(line, "\r"))
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "E":
+			// end of file
+			d.place(cells)
+			return
+
+		case "P":
+			for _, f := range fields[1:] {
+				if f != "" && f[0] == 'P' {
+					d.formats = append(d.formats, d.fmt.AddCode(f[1:]))
+				}
+			}
+
+		case "C", "F":
+			var value interface{}
+			hasValue := false
+			fmtID, hasFmt := uint16(0), false
+			rowFmt, colFmt := -1, -1
+			for _, f := range fields[1:] {
+				if f == "" {
+					continue
+				}
+				arg := f[1:]
+				switch f[0] {
+				case 'Y':
+					if n, err := strconv.Atoi(arg); err == nil {
+						row = n - 1
+					}
+				case 'X':
+					if n, err := strconv.Atoi(arg); err == nil {
+						col = n - 1
+					}
+				case 'K':
+					if fields[0] == "C" {
+						value, hasValue = parseValue(arg), true
+					}
+				case 'P':
+					if fields[0] == "F" {
+						if n, err := strconv.Atoi(arg); err == nil && n >= 0 && n < len(d.formats) {
+							fmtID, hasFmt = d.formats[n], true
+						}
+					}
+				case 'F':
+					// the P field takes precedence
+					if fields[0] == "F" && !hasFmt {
+						fmtID, hasFmt = d.fmt.AddCode(formatCode(arg)), true
+					}
+				case 'D':
+					if fields[0] == "F" {
+						d.defaultFmt = d.fmt.AddCode(formatCode(arg))
+					}
+				case 'R':
+					if fields[0] == "F" {
+						rowFmt, _ = strconv.Atoi(arg)
+					}
+				case 'C':
+					if fields[0] == "F" {
+						colFmt, _ = strconv.Atoi(arg)
+					}
+				}
+			}
+			switch {
+			case hasValue:
+				if row >= 0 && col >= 0 && row < maxRows && col < maxCols {
+					cells = append(cells, cell{row: row, col: col, value: value})
+				}
+			case !hasFmt:
+			case rowFmt > 0:
+				d.rowFmts[rowFmt-1] = fmtID
+			case colFmt > 0:
+				d.colFmts[colFmt-1] = fmtID
+			default:
+				d.cellFmts[[2]int{row, col}] = fmtID
+			}
+		}
+	}
+	d.place(cells)
+}
+
+// place the values of the cells, applying their number formats.
+func (d *Document) place(cells []cell) {
+	for _, c := range cells {
+		value := c.value
+		if f, ok := value.(float64); ok {
+			value, _ = d.fmt.Apply(d.formatID(c.row, c.col), f)
+		}
+		d.sheet.Put(c.row, c.col, value)
+	}
+}
+
+// formatID returns the number format of a cell, from the cell, its row,
+// its column or the default format of the sheet.
+func (d *Document) formatID(row, col int) uint16 {
+	if id, ok := d.cellFmts[[2]int{row, col}]; ok {
+		return id
+	}
+	if id, ok := d.rowFmts[row]; ok {
+		return id
+	}
+	if id, ok := d.colFmts[col]; ok {
+		return id
+	}
+	return d.defaultFmt
+}
+
+// splitFields splits a record into its fields, which are separated by
+// semicolons. A doubled semicolon is a semicolon within a field.
+func splitFields(line string) []string {
+	if line == "" {
+		return nil
+	}
+	var res []string
+	var field strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] != ';' {
+			field.WriteByte(line[i])
+			continue
+		}
+		if i+1 < len(line) && line[i+1] == ';' {
+			field.WriteByte(';')
+			i++
+			continue
+		}
+		res = append(res, field.String())
+		field.Reset()
+	}
+	return append(res, field.String())
+}
+
+// parseValue decodes the value of a K field: a quoted string, a number, a
+// boolean or an error such as #N/A.
+func parseValue(s string) interface{} {
+	if strings.HasPrefix(s, `"`) {
+		s = strings.TrimSuffix(s[1:], `"`)
+		if s == "" {
+			return nil
+		}
+		return s
+	}
+	switch s {
+	case "TRUE":
+		return true
+	case "FALSE":
+		return false
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// formatCode returns the number format code of a format field, which is
+// a format letter, a number of digits and an alignment, such as "F2R" for
+// two decimal places.
+func formatCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	n := 1
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	digits, _ := strconv.Atoi(s[1:n])
+	if digits > 15 {
+		digits = 15
+	}
+	decimals := ""
+	if digits > 0 {
+		decimals = "." + strings.Repeat("0", digits)
+	}
+	switch s[0] {
+	case 'F':
+		return "0" + decimals
+	case 'E':
+		return "0" + decimals + "E+00"
+	case '%':
+		return "0" + decimals + "%"
+	case '$':
+		return "$#,##0" + decimals
+	}
+	// general, default, continuous and graph formats
+	return ""
+}
+
+// List returns the name of the single sheet in the file.
+func (d *Document) List() ([]string, error) {
+	return []string{filepath.Base(d.filename)}, nil
+}
+
+// Get the sheet of the file.
+func (d *Document) Get(name string) (grate.Collection, error) {
+	if name != filepath.Base(d.filename) {
+		return nil, errors.New("sylk: sheet not found")
+	}
+	d.sheet.Rewind()
+	return d.sheet, nil
+}
+
+// Close the document.
+func (d *Document) Close() error {
+	return nil
+}
@@ -0,0 +1,90 @@
+package sylk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pbnjay/grate"
+)
+
+func writeTestFile(t *testing.T, name string, lines ...string) string {
+	t.Helper()
+	fn := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(fn, []byte(strings.Join(lines, "\r\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func readAll(t *testing.T, fn string) [][]string {
+	t.Helper()
+	src, err := Open(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	c, err := src.Get(filepath.Base(fn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res [][]string
+	for c.Next() {
+		res = append(res, c.Strings())
+	}
+	return res
+}
+
+func TestCells(t *testing.T) {
+	fn := writeTestFile(t, "ledger.slk",
+		"ID;PWXL;N;E",
+		"P;PGeneral",
+		"P;P0.00",
+		"P;Pyyyy\\-mm\\-dd",
+		"P;FCalibri;M220",
+		"F;P0;DG0G8;M255",
+		"B;Y4;X4;D0 0 3 3",
+		`C;Y1;X1;K"Account"`,
+		`C;X2;K"Amount"`,
+		`C;X3;K"Date"`,
+		`C;X4;K"Note;;s"`,
+		`C;Y2;X1;K"Rent"`,
+		"C;X2;K1250",
+		"C;X3;K45292",
+		"C;X4;KTRUE",
+		`C;Y3;X1;K"Fees"`,
+		"C;X2;K-12.5",
+		"C;X3;K#N/A",
+		"C;Y4;X2;K0.125;ER2C2/10",
+		"F;P1;FF2R;C2",
+		"F;P2;FG0G;Y2;X3",
+		"F;FF0R;Y4;X2",
+		"F;F%1R;R4",
+		"E",
+		`C;Y9;X9;K"after the end"`,
+	)
+	want := [][]string{
+		{"Account", "Amount", "Date", "Note;s"},
+		{"Rent", "1250.00", "2024-01-01", "true"},
+		{"Fees", "-12.50", "#N/A", ""},
+		{"", "0", "", ""},
+	}
+	if got := readAll(t, fn); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	for name, lines := range map[string][]string{
+		"text.slk":      {"Account,Amount", "Rent,1250"},
+		"semicolon.csv": {"ID;Price;Qty", "1;2.50;4"},
+	} {
+		fn := writeTestFile(t, name, lines...)
+		if _, err := Open(fn); !errors.Is(err, grate.ErrNotInFormat) {
+			t.Errorf("%s: got error %v, want ErrNotInFormat", name, err)
+		}
+	}
+}
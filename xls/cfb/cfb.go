@@ -13,6 +13,8 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"sort"
+	"sync"
 	"unicode/utf16"
 
 	"github.com/pbnjay/grate"
@@ -80,6 +82,9 @@ func (d *directory) String() string {
 	if (d.NameByteLen&1) == 1 || d.NameByteLen > 64 {
 		return "<invalid utf16 string>"
 	}
+	if d.NameByteLen < 2 {
+		return ""
+	}
 	r16 := utf16.Decode(d.Name[:int(d.NameByteLen)/2])
 	// trim off null terminator
 	return string(r16[:len(r16)-1])
@@ -87,8 +92,10 @@ func (d *directory) String() string {
 
 // Document represents a Compound File Binary Format document.
 type Document struct {
-	// the entire file, loaded into memory
-	data []byte
+	// sectors are read on demand from r
+	r      io.ReaderAt
+	size   int64
+	closer io.Closer
 
 	// pre-parsed info
 	header *header
@@ -100,18 +107,95 @@ type Document struct {
 
 	ministreamstart uint32
 	ministreamsize  uint32
+	// cached sector chain of the mini stream
+	ministream []uint32
+
+	// recovery mode, and the damaged sectors found
+	recover bool
+	badMu   sync.Mutex
+	bad     map[uint32]struct{}
 }
 
+// load a document from a stream, which is read fully into memory unless it
+// also implements io.ReaderAt.
 func (d *Document) load(rx io.ReadSeeker) error {
-	var err error
-	d.data, err = ioutil.ReadAll(rx)
+	if rx == nil {
+		return errors.New("cfb: nil reader")
+	}
+	if ra, ok := rx.(io.ReaderAt); ok {
+		size, err := rx.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		return d.init(ra, size)
+	}
+	data, err := ioutil.ReadAll(rx)
 	if err != nil {
 		return err
 	}
-	br := bytes.NewReader(d.data)
+	return d.init(bytes.NewReader(data), int64(len(data)))
+}
+
+// readSector reads the sector sid into buf.
+func (d *Document) readSector(sid uint32, buf []byte) error {
+	if sid > secMaxRegular {
+		return errors.New("ole2: invalid sector")
+	}
+	offs := int64(1+sid) << int64(d.header.SectorShift)
+	if offs >= d.size {
+		return errors.New("ole2: sector out of range")
+	}
+	n, err := d.r.ReadAt(buf, offs)
+	if err == io.EOF && n > 0 {
+		// the final sector may be truncated
+		for i := n; i < len(buf); i++ {
+			buf[i] = 0
+		}
+		err = nil
+	}
+	return err
+}
+
+// chain returns the list of sectors in the chain beginning at sid. Chains
+// which loop back on themselves, or which reference sectors outside of the
+// table or file, are invalid. In recovery mode the valid portion of the
+// chain is returned and the bad sector is recorded.
+func (d *Document) chain(sid uint32, table []uint32) ([]uint32, error) {
+	var res []uint32
+	seen := make(map[uint32]struct{})
+	for sid != secEndOfChain && sid != secFree {
+		var err error
+		if int(sid) >= len(table) {
+			err = errors.New("ole2: sector chain out of range")
+		} else if _, loop := seen[sid]; loop {
+			err = errors.New("ole2: sector chain contains a loop")
+		}
+		if err != nil {
+			if d.recover {
+				d.markBad(sid)
+				return res, nil
+			}
+			return res, err
+		}
+		seen[sid] = struct{}{}
+		res = append(res, sid)
+		sid = table[sid]
+	}
+	return res, nil
+}
+
+func (d *Document) init(r io.ReaderAt, size int64) error {
+	d.r = r
+	d.size = size
+
+	var hdr [512]byte
+	n, err := r.ReadAt(hdr[:], 0)
+	if n < len(hdr) {
+		return grate.ErrNotInFormat
+	}
 
 	h := &header{}
-	err = binary.Read(br, binary.LittleEndian, h)
+	binary.Read(bytes.NewReader(hdr[:]), binary.LittleEndian, h)
 	if h.Signature != 0xe11ab1a1e011cfd0 {
 		return grate.ErrNotInFormat // errors.New("ole2: invalid format")
 	}
@@ -135,6 +219,18 @@ func (d *Document) load(rx io.ReadSeeker) error {
 				return errors.New("ole2: reserved section is non-zero")
 			}
 		}
+		if d.recover {
+			// the sector sizes are fixed by the major version
+			h.SectorShift = 9
+			if h.MajorVersion == 4 {
+				h.SectorShift = 12
+			}
+			h.MiniSectorShift = 6
+			h.MiniStreamCutoffSize = 0x00001000
+			if h.MajorVersion == 3 {
+				h.NumDirectorySectors = 0
+			}
+		}
 		if h.MajorVersion == 3 {
 			if h.SectorShift != 9 {
 				return errors.New("ole2: invalid sector size")
@@ -155,12 +251,43 @@ func (d *Document) load(rx io.ReadSeeker) error {
 			return errors.New("ole2: invalid mini sector cutoff")
 		}
 	}
+	numSectors := (size + (1 << h.SectorShift) - 1) >> h.SectorShift
+	if h.NumFATSectors < 0 || int64(h.NumFATSectors) > numSectors ||
+		h.NumMiniFATSectors < 0 || int64(h.NumMiniFATSectors) > numSectors ||
+		h.NumDIFATSectors < 0 || int64(h.NumDIFATSectors) > numSectors {
+		if !d.recover {
+			return errors.New("ole2: invalid sector counts")
+		}
+		h.NumFATSectors = 0
+		h.NumDIFATSectors = int32(numSectors)
+		if int64(h.NumMiniFATSectors) > numSectors || h.NumMiniFATSectors < 0 {
+			h.NumMiniFATSectors = int32(numSectors)
+		}
+	}
 	d.header = h
 
 	numFATentries := (1 << (h.SectorShift - 2))
 	le := binary.LittleEndian
 	d.fat = make([]uint32, 0, numFATentries*int(1+d.header.NumFATSectors))
 	d.minifat = make([]uint32, 0, numFATentries*int(1+h.NumMiniFATSectors))
+	sector := make([]byte, 1<<h.SectorShift)
+
+	readFATSector := func(sid uint32) error {
+		if err := d.readSector(sid, sector); err != nil {
+			if !d.recover {
+				return errors.New("xls/cfb: unable to load file")
+			}
+			// keep the table aligned, all sectors described here are lost
+			d.markBad(sid)
+			for j := range sector {
+				sector[j] = 0xFF
+			}
+		}
+		for j := 0; j < numFATentries; j++ {
+			d.fat = append(d.fat, le.Uint32(sector[j*4:]))
+		}
+		return nil
+	}
 
 	// step 1: read the DIFAT sector list
 	for i := 0; i < 109; i++ {
@@ -168,196 +295,169 @@ func (d *Document) load(rx io.ReadSeeker) error {
 		if sid == secFree {
 			break
 		}
-		offs := int64(1+sid) << int32(h.SectorShift)
-		if offs >= int64(len(d.data)) {
-			return errors.New("xls/cfb: unable to load file")
-		}
-		sector := d.data[offs:]
-		for j := 0; j < numFATentries; j++ {
-			sid2 := le.Uint32(sector)
-			d.fat = append(d.fat, sid2)
-			sector = sector[4:]
+		if err = readFATSector(sid); err != nil {
+			return err
 		}
 	}
 	if h.NumDIFATSectors > 0 {
+		difatSector := make([]byte, 1<<h.SectorShift)
 		sid1 := h.FirstDIFATSectorLocation
 
-		for sid1 != secEndOfChain {
-			offs := int64(1+sid1) << int32(h.SectorShift)
-			difatSector := d.data[offs:]
+		for n := 0; sid1 != secEndOfChain && sid1 != secFree; n++ {
+			if n >= int(h.NumDIFATSectors) {
+				if d.recover {
+					d.markBad(sid1)
+					break
+				}
+				return errors.New("ole2: invalid DIFAT chain")
+			}
+			if err = d.readSector(sid1, difatSector); err != nil {
+				if d.recover {
+					d.markBad(sid1)
+					break
+				}
+				return errors.New("xls/cfb: unable to load file")
+			}
 
 			for i := 0; i < numFATentries-1; i++ {
-				sid2 := le.Uint32(difatSector)
+				sid2 := le.Uint32(difatSector[i*4:])
 				if sid2 == secFree || sid2 == secEndOfChain {
-					difatSector = difatSector[4:]
 					continue
 				}
-
-				offs := int64(1+sid2) << int32(h.SectorShift)
-				if offs >= int64(len(d.data)) {
-					return errors.New("xls/cfb: unable to load file")
-				}
-				sector := d.data[offs:]
-				for j := 0; j < numFATentries; j++ {
-					sid3 := le.Uint32(sector)
-					d.fat = append(d.fat, sid3)
-					sector = sector[4:]
+				if err = readFATSector(sid2); err != nil {
+					return err
 				}
-
-				difatSector = difatSector[4:]
 			}
 			// chain the next DIFAT sector
-			sid1 = le.Uint32(difatSector)
+			sid1 = le.Uint32(difatSector[(numFATentries-1)*4:])
 		}
 	}
 
 	// step 2: read the mini FAT
 	sid := h.FirstMiniFATSectorLocation
-	for sid != secEndOfChain {
-		offs := int64(1+sid) << int32(h.SectorShift)
-		if offs >= int64(len(d.data)) {
-			return errors.New("xls/cfb: unable to load file")
+	for n := 0; sid != secEndOfChain && sid != secFree && n < int(h.NumMiniFATSectors); n++ {
+		if err = d.readSector(sid, sector); err != nil {
+			if !d.recover {
+				return errors.New("xls/cfb: unable to load file")
+			}
+			d.markBad(sid)
+			for j := range sector {
+				sector[j] = 0xFF
+			}
 		}
-		sector := d.data[offs:]
 		for j := 0; j < numFATentries; j++ {
-			sid = le.Uint32(sector)
-			d.minifat = append(d.minifat, sid)
-			sector = sector[4:]
+			d.minifat = append(d.minifat, le.Uint32(sector[j*4:]))
 		}
-
-		if len(d.minifat) >= int(h.NumMiniFATSectors) {
+		if int(sid) >= len(d.fat) {
 			break
 		}
-
 		// chain the next mini FAT sector
-		sid = le.Uint32(sector)
+		sid = d.fat[sid]
 	}
 
 	// step 3: read the Directory Entries
-	err = d.buildDirs(br)
+	err = d.buildDirs()
+	if err != nil {
+		return err
+	}
 
+	// step 4: cache the location of the mini stream
+	d.ministream, err = d.chain(d.ministreamstart, d.fat)
 	return err
 }
 
-func (d *Document) buildDirs(br *bytes.Reader) error {
+func (d *Document) buildDirs() error {
 	h := d.header
 	le := binary.LittleEndian
 
-	// step 2: read the Directory
+	// step 2: read the Directory, following the sector chain. Unused entries
+	// are kept so that sibling/child IDs index directly into d.dir.
+	secSize := int64(1) << int64(h.SectorShift)
+	perSector := int(secSize / 128)
+	sector := make([]byte, secSize)
 	sid := h.FirstDirectorySectorLocation
-	offs := int64(1+sid) << int64(h.SectorShift)
-	br.Seek(offs, io.SeekStart)
-
-	for j := 0; j < 4; j++ {
-		dirent := &directory{}
-		binary.Read(br, le, dirent)
-		if d.header.MajorVersion == 3 {
-			// mask out upper 32bits
-			dirent.StreamSize = dirent.StreamSize & 0xFFFFFFFF
+	for n := 0; sid != secEndOfChain && sid != secFree; n++ {
+		if int(sid) >= len(d.fat) || n > len(d.fat) {
+			if d.recover && len(d.dir) > 0 {
+				// keep the entries which were read
+				d.markBad(sid)
+				break
+			}
+			return errors.New("ole2: invalid directory chain")
+		}
+		if err := d.readSector(sid, sector); err != nil {
+			if d.recover && len(d.dir) > 0 {
+				d.markBad(sid)
+				break
+			}
+			return errors.New("ole2: corrupt data format")
 		}
+		br := bytes.NewReader(sector)
+
+		for j := 0; j < perSector; j++ {
+			dirent := &directory{}
+			binary.Read(br, le, dirent)
+			if d.header.MajorVersion == 3 {
+				// mask out upper 32bits
+				dirent.StreamSize = dirent.StreamSize & 0xFFFFFFFF
+			}
 
-		switch dirent.ObjectType {
-		case typeRootStorage:
-			d.ministreamstart = uint32(dirent.StartingSectorLocation)
-			d.ministreamsize = uint32(dirent.StreamSize)
-		case typeStorage:
-			//log.Println("got a storage? what to do now?")
-		case typeStream:
-			/*
-				var freader io.Reader
-				if dirent.StreamSize < uint64(d.header.MiniStreamCutoffSize) {
-					freader = d.getMiniStreamReader(uint32(dirent.StartingSectorLocation), dirent.StreamSize)
-				} else if dirent.StreamSize != 0 {
-					freader = d.getStreamReader(uint32(dirent.StartingSectorLocation), dirent.StreamSize)
-				}
-			*/
-		case typeUnknown:
-			return nil
+			if dirent.ObjectType == typeRootStorage && len(d.dir) == 0 {
+				d.ministreamstart = uint32(dirent.StartingSectorLocation)
+				d.ministreamsize = uint32(dirent.StreamSize)
+			}
+			d.dir = append(d.dir, dirent)
 		}
-		d.dir = append(d.dir, dirent)
+		sid = d.fat[sid]
 	}
 
 	return nil
 }
 
 func (d *Document) getStreamReader(sid uint32, size uint64) (io.ReadSeeker, error) {
-	// NB streamData is a slice of slices of the raw data, so this is the
-	// only allocation - for the (much smaller) list of sector slices
-	streamData := make([][]byte, 1+(size>>d.header.SectorShift))
-
-	x := 0
-	secSize := int64(1) << int32(d.header.SectorShift)
-	for sid != secEndOfChain && sid != secFree {
-		offs := int64(1+sid) << int64(d.header.SectorShift)
-		if offs > int64(len(d.data)) {
-			return nil, errors.New("ole2: corrupt data format")
-		}
-		slice := d.data[offs : offs+secSize]
-		if size < uint64(len(slice)) {
-			slice = slice[:size]
-			size = 0
-		} else {
-			size -= uint64(len(slice))
-		}
-		streamData[x] = slice
-		if size == 0 {
-			break
-		}
-		sid = d.fat[sid]
-		x++
+	sectors, err := d.chain(sid, d.fat)
+	if err != nil {
+		return nil, err
 	}
-	if size != 0 {
+	if uint64(len(sectors))<<d.header.SectorShift < size && !d.recover {
 		return nil, errors.New("ole2: incomplete read")
 	}
-
-	return &SliceReader{Data: streamData}, nil
+	return &streamReader{d: d, sectors: sectors, size: int64(size)}, nil
 }
 
 func (d *Document) getMiniStreamReader(sid uint32, size uint64) (io.ReadSeeker, error) {
-	// TODO: move into a separate cache so we don't recalculate it each time
-	fatStreamData := make([][]byte, 1+(d.ministreamsize>>d.header.SectorShift))
-
-	// NB streamData is a slice of slices of the raw data, so this is the
-	// only allocation - for the (much smaller) list of sector slices
-	streamData := make([][]byte, 1+(size>>d.header.MiniSectorShift))
-
-	x := 0
-	fsid := d.ministreamstart
-	fsize := uint64(d.ministreamsize)
-	secSize := int64(1) << int64(d.header.SectorShift)
-	for fsid != secEndOfChain && fsid != secFree {
-		offs := int64(1+fsid) << int64(d.header.SectorShift)
-		slice := d.data[offs : offs+secSize]
-		if fsize < uint64(len(slice)) {
-			slice = slice[:fsize]
-			fsize = 0
-		} else {
-			fsize -= uint64(len(slice))
-		}
-		fatStreamData[x] = slice
-		x++
-		fsid = d.fat[fsid]
+	sectors, err := d.chain(sid, d.minifat)
+	if err != nil {
+		return nil, err
 	}
+	if uint64(len(sectors))<<d.header.MiniSectorShift < size && !d.recover {
+		return nil, errors.New("ole2: incomplete read")
+	}
+	return &streamReader{d: d, sectors: sectors, size: int64(size), mini: true}, nil
+}
 
-	x = 0
-	miniSecSize := int64(1) << int64(d.header.MiniSectorShift)
-	for sid != secEndOfChain && sid != secFree {
-		offs := int64(sid) << int64(d.header.MiniSectorShift)
-
-		so, si := offs/secSize, offs%secSize
-		data := fatStreamData[so]
-
-		slice := data[si : si+miniSecSize]
-		if size < uint64(len(slice)) {
-			slice = slice[:size]
-			size = 0
-		} else {
-			size -= uint64(len(slice))
-		}
-		streamData[x] = slice
-		x++
-		sid = d.minifat[sid]
+// BadSectors returns the damaged sectors which were skipped while reading
+// the document in recovery mode. Sectors are found as the document is
+// loaded and as streams are read, so the list may grow over time.
+func (d *Document) BadSectors() []uint32 {
+	d.badMu.Lock()
+	defer d.badMu.Unlock()
+	res := make([]uint32, 0, len(d.bad))
+	for sid := range d.bad {
+		res = append(res, sid)
 	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
 
-	return &SliceReader{Data: streamData}, nil
+func (d *Document) markBad(sid uint32) {
+	d.badMu.Lock()
+	if d.bad == nil {
+		d.bad = make(map[uint32]struct{})
+	}
+	d.bad[sid] = struct{}{}
+	d.badMu.Unlock()
+	if grate.Debug {
+		log.Printf("cfb: skipping damaged sector %d", sid)
+	}
 }
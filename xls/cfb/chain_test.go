@@ -0,0 +1,78 @@
+package cfb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestChainValidation(t *testing.T) {
+	d := &Document{
+		header: &header{SectorShift: 9, MiniSectorShift: 6},
+		fat:    []uint32{1, 2, 1, secEndOfChain, 9},
+	}
+
+	res, err := d.chain(3, d.fat)
+	if err != nil || len(res) != 1 {
+		t.Errorf("unexpected chain %v, %v", res, err)
+	}
+	if _, err = d.chain(0, d.fat); err == nil {
+		t.Error("expected loop to be detected")
+	}
+	if _, err = d.chain(4, d.fat); err == nil {
+		t.Error("expected out of range sector to be detected")
+	}
+
+	d.recover = true
+	res, err = d.chain(0, d.fat)
+	if err != nil || len(res) != 3 {
+		t.Errorf("unexpected recovered chain %v, %v", res, err)
+	}
+	res, err = d.chain(4, d.fat)
+	if err != nil || len(res) != 1 {
+		t.Errorf("unexpected recovered chain %v, %v", res, err)
+	}
+	bad := d.BadSectors()
+	if len(bad) != 2 || bad[0] != 1 || bad[1] != 9 {
+		t.Errorf("unexpected bad sectors %v", bad)
+	}
+}
+
+func TestRecoverStream(t *testing.T) {
+	// 2 sectors of data, the second is missing from the file
+	data := make([]byte, 512*2)
+	for i := range data[512:] {
+		data[512+i] = 'x'
+	}
+	d := &Document{
+		r:       bytes.NewReader(data),
+		size:    int64(len(data)),
+		header:  &header{SectorShift: 9, MiniSectorShift: 6},
+		fat:     []uint32{1, secEndOfChain},
+		recover: false,
+	}
+
+	r, err := d.getStreamReader(0, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ioutil.ReadAll(r); err == nil {
+		t.Error("expected error reading damaged stream")
+	}
+
+	d.recover = true
+	r, err = d.getStreamReader(0, 1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1500 || res[0] != 'x' || res[600] != 0 || res[1400] != 0 {
+		t.Errorf("unexpected recovered stream contents")
+	}
+	if bad := d.BadSectors(); len(bad) != 1 || bad[0] != 1 {
+		t.Errorf("unexpected bad sectors %v", bad)
+	}
+}
@@ -4,23 +4,98 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
-// Open a Compound File Binary Format document.
+// Options configures how a document is opened.
+type Options struct {
+	// Mmap maps the file into memory instead of reading sectors with
+	// individual system calls. It is ignored on unsupported platforms.
+	Mmap bool
+
+	// Recover salvages as much as possible from damaged documents. Invalid
+	// sector chains are truncated, and unreadable sectors are skipped and
+	// read as zeros. The damaged sectors are reported by BadSectors.
+	Recover bool
+}
+
+// Open a Compound File Binary Format document. Sectors are read from the
+// file as needed, so the file remains open until the Document is closed.
 func Open(filename string) (*Document, error) {
-	d := &Document{}
+	return OpenWithOptions(filename, Options{})
+}
+
+// OpenWithOptions opens a Compound File Binary Format document using the
+// given options.
+func OpenWithOptions(filename string, opts Options) (*Document, error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
-	err = d.load(f)
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	var r io.ReaderAt = f
+	var c io.Closer = f
+	if opts.Mmap {
+		if m, err := mmapFile(f, info.Size()); err == nil {
+			f.Close()
+			r, c = m, m
+		}
+	}
+
+	d, err := NewWithOptions(r, info.Size(), opts)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	d.closer = c
+	return d, nil
+}
+
+// OpenReader reads a Compound File Binary Format document from r. If r does
+// not implement io.ReaderAt, the entire stream is read into memory.
+func OpenReader(r io.ReadSeeker) (*Document, error) {
+	d := &Document{}
+	err := d.load(r)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// New reads a Compound File Binary Format document of the given size from r.
+func New(r io.ReaderAt, size int64) (*Document, error) {
+	return NewWithOptions(r, size, Options{})
+}
+
+// NewWithOptions reads a Compound File Binary Format document of the given
+// size from r using the given options. The Mmap option is ignored.
+func NewWithOptions(r io.ReaderAt, size int64, opts Options) (*Document, error) {
+	d := &Document{recover: opts.Recover}
+	err := d.init(r, size)
 	if err != nil {
 		return nil, err
 	}
 	return d, nil
 }
 
-// List the streams contained in the document.
+// Close releases the file opened by Open or OpenWithOptions. Streams opened
+// from the document cannot be read after it is closed.
+func (d *Document) Close() error {
+	if d.closer == nil {
+		return nil
+	}
+	err := d.closer.Close()
+	d.closer = nil
+	return err
+}
+
+// List the streams contained in the document. Only the stream names are
+// returned, use Walk to list the full paths of nested streams.
 func (d *Document) List() ([]string, error) {
 	var res []string
 	for _, e := range d.dir {
@@ -31,16 +106,34 @@ func (d *Document) List() ([]string, error) {
 	return res, nil
 }
 
-// Open the named stream contained in the document.
+// Open the named stream contained in the document. Nested streams are named
+// by their full path, e.g. "_VBA_PROJECT_CUR/VBA/dir". If name is not a
+// path and there is no such stream in the root storage, the first stream
+// with a matching name in any storage is opened.
 func (d *Document) Open(name string) (io.ReadSeeker, error) {
+	if id, _, err := d.resolve(name); err == nil {
+		if d.dir[id].ObjectType != typeStream {
+			return nil, fmt.Errorf("cfb: '%s' is not a stream", name)
+		}
+		return d.openStream(d.dir[id])
+	}
+	if strings.Contains(name, "/") {
+		return nil, fmt.Errorf("cfb: stream '%s' not found", name)
+	}
 	for _, e := range d.dir {
-		if e.String() == name && e.ObjectType == typeStream {
-			if e.StreamSize < uint64(d.header.MiniStreamCutoffSize) {
-				return d.getMiniStreamReader(uint32(e.StartingSectorLocation), e.StreamSize)
-			} else if e.StreamSize != 0 {
-				return d.getStreamReader(uint32(e.StartingSectorLocation), e.StreamSize)
-			}
+		if e.ObjectType == typeStream && e.String() == name {
+			return d.openStream(e)
 		}
 	}
 	return nil, fmt.Errorf("cfb: stream '%s' not found", name)
 }
+
+func (d *Document) openStream(e *directory) (io.ReadSeeker, error) {
+	if e.StreamSize == 0 {
+		return &streamReader{d: d}, nil
+	}
+	if e.StreamSize < uint64(d.header.MiniStreamCutoffSize) {
+		return d.getMiniStreamReader(uint32(e.StartingSectorLocation), e.StreamSize)
+	}
+	return d.getStreamReader(uint32(e.StartingSectorLocation), e.StreamSize)
+}
@@ -0,0 +1,24 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package cfb
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+type mmapData []byte
+
+func mmapFile(f *os.File, size int64) (mmapData, error) {
+	return nil, errors.New("cfb: memory mapping is not supported")
+}
+
+func (m mmapData) ReadAt(b []byte, off int64) (int, error) {
+	return 0, io.EOF
+}
+
+func (m mmapData) Close() error {
+	return nil
+}
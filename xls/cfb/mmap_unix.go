@@ -0,0 +1,42 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package cfb
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// mmapData is a read-only memory mapped file.
+type mmapData []byte
+
+func mmapFile(f *os.File, size int64) (mmapData, error) {
+	if size <= 0 || int64(int(size)) != size {
+		return nil, errors.New("cfb: unable to map file")
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	return mmapData(data), nil
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (m mmapData) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 || off >= int64(len(m)) {
+		return 0, io.EOF
+	}
+	n := copy(b, m[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close unmaps the file.
+func (m mmapData) Close() error {
+	return syscall.Munmap(m)
+}
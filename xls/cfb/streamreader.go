@@ -0,0 +1,143 @@
+package cfb
+
+import (
+	"errors"
+	"io"
+)
+
+// streamReader reads the sectors of a stream on demand from the underlying
+// document.
+type streamReader struct {
+	d       *Document
+	sectors []uint32
+	mini    bool
+
+	size int64
+	pos  int64
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (s *streamReader) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("cfb: invalid offset")
+	}
+	shift := s.d.header.SectorShift
+	if s.mini {
+		shift = s.d.header.MiniSectorShift
+	}
+	secSize := int64(1) << shift
+
+	n := 0
+	for n < len(b) && off < s.size {
+		idx, within := off>>shift, off&(secSize-1)
+		count := secSize - within
+		if rem := s.size - off; rem < count {
+			count = rem
+		}
+		if rem := int64(len(b) - n); rem < count {
+			count = rem
+		}
+
+		phys, sid, ok := s.locate(idx, within)
+		if !ok {
+			if !s.d.recover {
+				return n, errors.New("ole2: corrupt data format")
+			}
+			// missing sectors of damaged streams read as zeros
+			if sid != secFree {
+				s.d.markBad(sid)
+			}
+			zero(b[n : n+int(count)])
+			n += int(count)
+			off += count
+			continue
+		}
+
+		m, err := s.d.r.ReadAt(b[n:n+int(count)], phys)
+		if err != nil && !(err == io.EOF && m == int(count)) {
+			if s.d.recover {
+				s.d.markBad(sid)
+				zero(b[n+m : n+int(count)])
+				m, err = int(count), nil
+			} else if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+		}
+		n += m
+		off += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// locate returns the file offset of the given position within the stream,
+// and the regular sector containing it.
+func (s *streamReader) locate(idx, within int64) (int64, uint32, bool) {
+	if idx >= int64(len(s.sectors)) {
+		return 0, secFree, false
+	}
+	bigShift := s.d.header.SectorShift
+	sid := s.sectors[idx]
+	if s.mini {
+		// locate the mini sector within the mini stream
+		moff := int64(sid)<<s.d.header.MiniSectorShift + within
+		msec := moff >> bigShift
+		if msec >= int64(len(s.d.ministream)) {
+			return 0, secFree, false
+		}
+		sid = s.d.ministream[msec]
+		within = moff & ((1 << bigShift) - 1)
+	}
+	phys := int64(1+sid)<<bigShift + within
+	if phys >= s.d.size {
+		return 0, sid, false
+	}
+	return phys, sid, true
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// Read implements the io.Reader interface.
+func (s *streamReader) Read(b []byte) (int, error) {
+	if s.pos >= s.size {
+		return 0, io.EOF
+	}
+	n, err := s.ReadAt(b, s.pos)
+	s.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements the io.Seeker interface.
+func (s *streamReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.pos
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return -1, errors.New("cfb: invalid seek whence")
+	}
+	if offset < 0 {
+		return -1, errors.New("cfb: invalid seek offset")
+	}
+	s.pos = offset
+	return offset, nil
+}
+
+// Size returns the length of the stream in bytes.
+func (s *streamReader) Size() int64 {
+	return s.size
+}
@@ -0,0 +1,209 @@
+package cfb
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// noStream marks an empty sibling or child link (NOSTREAM).
+const noStream uint32 = 0xFFFFFFFF
+
+// Entry describes a storage or stream contained in the document.
+type Entry struct {
+	// Path is the full "/" separated path to the entry, e.g. "_VBA_PROJECT_CUR/VBA/dir".
+	// The root storage has an empty path.
+	Path string
+	Name string
+
+	IsStorage bool
+	// Size is the length of a stream in bytes, and is always zero for storages.
+	Size uint64
+
+	// CLSID is the class identifier of a storage, in its on-disk byte order.
+	CLSID [16]byte
+
+	Created  time.Time
+	Modified time.Time
+}
+
+// WalkFunc is called by Walk for each storage and stream in the document.
+// If it returns fs.SkipDir for a storage, the contents of that storage are
+// skipped. Any other error stops the walk and is returned by Walk.
+type WalkFunc func(e *Entry) error
+
+// Walk visits every storage and stream in the document in depth-first
+// order, starting with the children of the root storage. Storages are
+// visited before their contents.
+func (d *Document) Walk(fn WalkFunc) error {
+	seen := map[uint32]bool{0: true}
+	err := d.walk(0, "", seen, fn)
+	if err == fs.SkipDir {
+		err = nil
+	}
+	return err
+}
+
+func (d *Document) walk(id uint32, prefix string, seen map[uint32]bool, fn WalkFunc) error {
+	for _, cid := range d.children(id, seen) {
+		e := d.entry(cid, prefix)
+		err := fn(e)
+		if err == fs.SkipDir {
+			if e.IsStorage {
+				continue
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if e.IsStorage {
+			err = d.walk(cid, e.Path+"/", seen, fn)
+			if err != nil && err != fs.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Stat returns information about the storage or stream at path. An empty
+// path refers to the root storage.
+func (d *Document) Stat(path string) (*Entry, error) {
+	id, full, err := d.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	e := d.entry(id, "")
+	e.Path = full
+	return e, nil
+}
+
+// ReadDir lists the storages and streams directly within the storage at
+// path. An empty path lists the root storage.
+func (d *Document) ReadDir(path string) ([]*Entry, error) {
+	id, prefix, err := d.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	if d.dir[id].ObjectType == typeStream {
+		return nil, fmt.Errorf("cfb: '%s' is not a storage", path)
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+	var res []*Entry
+	for _, cid := range d.children(id, map[uint32]bool{id: true}) {
+		res = append(res, d.entry(cid, prefix))
+	}
+	return res, nil
+}
+
+// Storages returns the full paths of all storages in the document.
+func (d *Document) Storages() []string {
+	var res []string
+	d.Walk(func(e *Entry) error {
+		if e.IsStorage {
+			res = append(res, e.Path)
+		}
+		return nil
+	})
+	return res
+}
+
+func (d *Document) entry(id uint32, prefix string) *Entry {
+	de := d.dir[id]
+	e := &Entry{
+		Path:      prefix + de.String(),
+		Name:      de.String(),
+		IsStorage: de.ObjectType != typeStream,
+		Created:   filetime(de.CreationTime),
+		Modified:  filetime(de.ModifiedTime),
+	}
+	if !e.IsStorage {
+		e.Size = de.StreamSize
+	}
+	for i := 0; i < 8; i++ {
+		e.CLSID[i] = byte(de.ClassID[0] >> (8 * i))
+		e.CLSID[8+i] = byte(de.ClassID[1] >> (8 * i))
+	}
+	return e
+}
+
+// children returns the IDs of the entries within a storage, in directory
+// order. Entries already present in seen are skipped, so that a corrupted
+// sibling or child link cannot cause an infinite loop.
+func (d *Document) children(id uint32, seen map[uint32]bool) []uint32 {
+	if int(id) >= len(d.dir) {
+		return nil
+	}
+	var res []uint32
+	var visit func(sid uint32)
+	visit = func(sid uint32) {
+		if sid == noStream || int(sid) >= len(d.dir) || seen[sid] {
+			return
+		}
+		seen[sid] = true
+		de := d.dir[sid]
+		visit(de.LeftSiblingID)
+		switch de.ObjectType {
+		case typeStorage, typeStream:
+			res = append(res, sid)
+		}
+		visit(de.RightSiblingID)
+	}
+	visit(d.dir[id].ChildID)
+	return res
+}
+
+// resolve finds the directory entry at path, and returns its ID and the
+// path using the names as stored in the document.
+func (d *Document) resolve(path string) (uint32, string, error) {
+	if len(d.dir) == 0 {
+		return 0, "", errors.New("cfb: empty directory")
+	}
+	id := uint32(0)
+	seen := map[uint32]bool{0: true}
+	var full []string
+	for _, part := range splitPath(path) {
+		if d.dir[id].ObjectType == typeStream {
+			return 0, "", fmt.Errorf("cfb: '%s' not found", path)
+		}
+		found := false
+		for _, cid := range d.children(id, seen) {
+			// names are compared case-insensitively (section 2.6.4)
+			if strings.EqualFold(d.dir[cid].String(), part) {
+				id, found = cid, true
+				break
+			}
+		}
+		if !found {
+			return 0, "", fmt.Errorf("cfb: '%s' not found", path)
+		}
+		full = append(full, d.dir[id].String())
+	}
+	return id, strings.Join(full, "/"), nil
+}
+
+func splitPath(path string) []string {
+	var res []string
+	for _, p := range strings.Split(path, "/") {
+		if p != "" {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// filetime converts a Windows FILETIME to a time.Time.
+func filetime(ft int64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+	// 100-nanosecond intervals since January 1, 1601 (UTC)
+	const epochDiff = 116444736000000000
+	ft -= epochDiff
+	return time.Unix(ft/10000000, (ft%10000000)*100).UTC()
+}
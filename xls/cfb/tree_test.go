@@ -0,0 +1,85 @@
+package cfb
+
+import (
+	"io/fs"
+	"testing"
+	"unicode/utf16"
+)
+
+func testDirEntry(name string, typ objectType, left, right, child uint32) *directory {
+	d := &directory{
+		ObjectType:     typ,
+		LeftSiblingID:  left,
+		RightSiblingID: right,
+		ChildID:        child,
+	}
+	u := utf16.Encode([]rune(name))
+	copy(d.Name[:], u)
+	d.NameByteLen = int16(2 * (len(u) + 1))
+	return d
+}
+
+func TestWalk(t *testing.T) {
+	d := &Document{dir: []*directory{
+		testDirEntry("Root Entry", typeRootStorage, noStream, noStream, 2),
+		testDirEntry("Workbook", typeStream, noStream, noStream, noStream),
+		testDirEntry("_VBA_PROJECT_CUR", typeStorage, 1, noStream, 3),
+		testDirEntry("VBA", typeStorage, noStream, noStream, 4),
+		testDirEntry("dir", typeStream, noStream, 5, noStream),
+		// corrupted sibling link back to the storage
+		testDirEntry("Module1", typeStream, noStream, 3, noStream),
+	}}
+
+	var paths []string
+	err := d.Walk(func(e *Entry) error {
+		paths = append(paths, e.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"Workbook", "_VBA_PROJECT_CUR", "_VBA_PROJECT_CUR/VBA",
+		"_VBA_PROJECT_CUR/VBA/dir", "_VBA_PROJECT_CUR/VBA/Module1"}
+	if len(paths) != len(expect) {
+		t.Fatalf("got %v, expected %v", paths, expect)
+	}
+	for i, p := range expect {
+		if paths[i] != p {
+			t.Errorf("got %v, expected %v", paths, expect)
+		}
+	}
+
+	paths = paths[:0]
+	d.Walk(func(e *Entry) error {
+		paths = append(paths, e.Path)
+		if e.Name == "VBA" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if len(paths) != 3 {
+		t.Errorf("SkipDir did not skip storage contents: %v", paths)
+	}
+
+	e, err := d.Stat("_vba_project_cur/vba/DIR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Path != "_VBA_PROJECT_CUR/VBA/dir" || e.IsStorage {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if _, err = d.Stat("_VBA_PROJECT_CUR/dir"); err == nil {
+		t.Error("expected error for missing path")
+	}
+
+	ents, err := d.ReadDir("_VBA_PROJECT_CUR/VBA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ents) != 2 || ents[1].Path != "_VBA_PROJECT_CUR/VBA/Module1" {
+		t.Errorf("unexpected entries %v", ents)
+	}
+	if st := d.Storages(); len(st) != 2 {
+		t.Errorf("unexpected storages %v", st)
+	}
+}
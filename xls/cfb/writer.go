@@ -0,0 +1,401 @@
+package cfb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// Writer builds a new Compound File Binary Format document. Storages and
+// streams are added by path, and the document is laid out when WriteTo is
+// called.
+type Writer struct {
+	version uint16
+	root    *wnode
+}
+
+type wnode struct {
+	name     string
+	storage  bool
+	data     []byte
+	clsid    [16]byte
+	children []*wnode
+
+	// assigned during layout
+	id          uint32
+	left, right uint32
+	child       uint32
+	color       byte
+	start       uint32
+}
+
+// NewWriter creates a Writer for a compound file of major version 3 (512
+// byte sectors) or 4 (4096 byte sectors).
+func NewWriter(version int) (*Writer, error) {
+	if version != 3 && version != 4 {
+		return nil, errors.New("cfb: unsupported major version")
+	}
+	return &Writer{
+		version: uint16(version),
+		root:    &wnode{name: "Root Entry", storage: true},
+	}, nil
+}
+
+// CreateStorage adds a storage at path, along with any missing parent
+// storages. It is not an error if the storage already exists.
+func (w *Writer) CreateStorage(path string) error {
+	_, err := w.mkdirs(splitPath(path))
+	return err
+}
+
+// CreateStream adds a stream containing data at path, creating any missing
+// parent storages. An existing stream at path is replaced.
+func (w *Writer) CreateStream(path string, data []byte) error {
+	parts := splitPath(path)
+	if len(parts) == 0 {
+		return errors.New("cfb: invalid stream path")
+	}
+	parent, err := w.mkdirs(parts[:len(parts)-1])
+	if err != nil {
+		return err
+	}
+	name := parts[len(parts)-1]
+	if err = validName(name); err != nil {
+		return err
+	}
+	if n := parent.find(name); n != nil {
+		if n.storage {
+			return fmt.Errorf("cfb: '%s' is a storage", path)
+		}
+		n.data = data
+		return nil
+	}
+	parent.children = append(parent.children, &wnode{name: name, data: data})
+	return nil
+}
+
+// SetCLSID sets the class identifier of the storage at path. An empty path
+// refers to the root storage.
+func (w *Writer) SetCLSID(path string, clsid [16]byte) error {
+	n := w.root
+	for _, p := range splitPath(path) {
+		if n = n.find(p); n == nil {
+			return fmt.Errorf("cfb: '%s' not found", path)
+		}
+	}
+	if !n.storage {
+		return fmt.Errorf("cfb: '%s' is not a storage", path)
+	}
+	n.clsid = clsid
+	return nil
+}
+
+func (w *Writer) mkdirs(parts []string) (*wnode, error) {
+	n := w.root
+	for _, p := range parts {
+		if err := validName(p); err != nil {
+			return nil, err
+		}
+		c := n.find(p)
+		if c == nil {
+			c = &wnode{name: p, storage: true}
+			n.children = append(n.children, c)
+		} else if !c.storage {
+			return nil, fmt.Errorf("cfb: '%s' is a stream", p)
+		}
+		n = c
+	}
+	return n, nil
+}
+
+func (n *wnode) find(name string) *wnode {
+	for _, c := range n.children {
+		if strings.EqualFold(c.name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+func validName(name string) error {
+	if name == "" || len(utf16.Encode([]rune(name))) > 31 || strings.ContainsAny(name, "/\\:!") {
+		return fmt.Errorf("cfb: invalid name '%s'", name)
+	}
+	return nil
+}
+
+// compareNames orders directory entries as required by section 2.6.4:
+// shorter names first, then by uppercase code points.
+func compareNames(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	if len(ua) != len(ub) {
+		return len(ua) < len(ub)
+	}
+	return strings.ToUpper(a) < strings.ToUpper(b)
+}
+
+// WriteTo writes the compound file to out.
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	const (
+		miniShift  = 6
+		miniCutoff = 0x1000
+	)
+	shift := uint(9)
+	if w.version == 4 {
+		shift = 12
+	}
+	secSize := 1 << shift
+	perSector := secSize / 4
+
+	// assign directory IDs in breadth-first order
+	nodes := []*wnode{w.root}
+	for i := 0; i < len(nodes); i++ {
+		n := nodes[i]
+		n.id = uint32(i)
+		sort.SliceStable(n.children, func(a, b int) bool {
+			return compareNames(n.children[a].name, n.children[b].name)
+		})
+		nodes = append(nodes, n.children...)
+	}
+	for _, n := range nodes {
+		n.left, n.right, n.child, n.color = noStream, noStream, noStream, 1
+	}
+	for _, n := range nodes {
+		n.child = buildTree(n.children, 0, maxDepth(len(n.children)))
+	}
+
+	// allocate the mini stream
+	var mini []byte
+	var minifat []uint32
+	for _, n := range nodes[1:] {
+		if n.storage || len(n.data) >= miniCutoff {
+			continue
+		}
+		if len(n.data) == 0 {
+			n.start = secEndOfChain
+			continue
+		}
+		n.start = uint32(len(minifat))
+		count := (len(n.data) + (1 << miniShift) - 1) >> miniShift
+		for i := 1; i < count; i++ {
+			minifat = append(minifat, n.start+uint32(i))
+		}
+		minifat = append(minifat, secEndOfChain)
+		mini = append(mini, n.data...)
+		mini = append(mini, make([]byte, count<<miniShift-len(n.data))...)
+	}
+
+	// allocate regular sectors
+	sectors := func(size int) int { return (size + secSize - 1) >> shift }
+	var fat []uint32
+	allocate := func(count int, marker uint32) uint32 {
+		if count == 0 {
+			return secEndOfChain
+		}
+		start := uint32(len(fat))
+		for i := 0; i < count; i++ {
+			if marker != 0 {
+				fat = append(fat, marker)
+			} else if i == count-1 {
+				fat = append(fat, secEndOfChain)
+			} else {
+				fat = append(fat, start+uint32(i)+1)
+			}
+		}
+		return start
+	}
+	for _, n := range nodes[1:] {
+		if !n.storage && len(n.data) >= miniCutoff {
+			n.start = allocate(sectors(len(n.data)), 0)
+		}
+	}
+	w.root.start = allocate(sectors(len(mini)), 0)
+	miniFATStart := allocate(sectors(len(minifat)*4), 0)
+	numDirSectors := sectors(len(nodes) * 128)
+	dirStart := allocate(numDirSectors, 0)
+
+	// the FAT and DIFAT must also describe themselves
+	numFAT, numDIFAT := 0, 0
+	for {
+		total := len(fat) + numFAT + numDIFAT
+		nf := (total + perSector - 1) / perSector
+		nd := 0
+		if nf > 109 {
+			nd = (nf - 109 + perSector - 2) / (perSector - 1)
+		}
+		if nf == numFAT && nd == numDIFAT {
+			break
+		}
+		numFAT, numDIFAT = nf, nd
+	}
+	fatStart := allocate(numFAT, secFAT)
+	difatStart := allocate(numDIFAT, secDIFAT)
+	numData := len(fat)
+	for len(fat) < numFAT*perSector {
+		fat = append(fat, secFree)
+	}
+
+	// header
+	h := &header{
+		Signature:                    0xe11ab1a1e011cfd0,
+		MinorVersion:                 0x003E,
+		MajorVersion:                 w.version,
+		ByteOrder:                    0xFFFE,
+		SectorShift:                  uint16(shift),
+		MiniSectorShift:              miniShift,
+		NumFATSectors:                int32(numFAT),
+		FirstDirectorySectorLocation: dirStart,
+		MiniStreamCutoffSize:         miniCutoff,
+		FirstMiniFATSectorLocation:   miniFATStart,
+		NumMiniFATSectors:            int32(sectors(len(minifat) * 4)),
+		FirstDIFATSectorLocation:     secEndOfChain,
+		NumDIFATSectors:              int32(numDIFAT),
+	}
+	if w.version == 4 {
+		h.NumDirectorySectors = int32(numDirSectors)
+	}
+	if numDIFAT > 0 {
+		h.FirstDIFATSectorLocation = difatStart
+	}
+	for i := range h.DIFAT {
+		h.DIFAT[i] = secFree
+		if i < numFAT {
+			h.DIFAT[i] = fatStart + uint32(i)
+		}
+	}
+
+	bw := &countWriter{w: bufio.NewWriter(out)}
+	le := binary.LittleEndian
+	binary.Write(bw, le, h)
+	bw.pad(secSize)
+
+	// stream data, the mini stream and the mini FAT
+	for _, n := range nodes[1:] {
+		if !n.storage && len(n.data) >= miniCutoff {
+			bw.Write(n.data)
+			bw.pad(secSize)
+		}
+	}
+	bw.Write(mini)
+	bw.pad(secSize)
+	binary.Write(bw, le, minifat)
+	for i := len(minifat); i%perSector != 0; i++ {
+		binary.Write(bw, le, secFree)
+	}
+
+	// directory
+	for _, n := range nodes {
+		de := &directory{
+			ObjectType:     typeStream,
+			ColorFlag:      n.color,
+			LeftSiblingID:  n.left,
+			RightSiblingID: n.right,
+			ChildID:        n.child,
+		}
+		u := utf16.Encode([]rune(n.name))
+		copy(de.Name[:], u)
+		de.NameByteLen = int16(2 * (len(u) + 1))
+		de.ClassID[0] = le.Uint64(n.clsid[:8])
+		de.ClassID[1] = le.Uint64(n.clsid[8:])
+		switch {
+		case n == w.root:
+			de.ObjectType = typeRootStorage
+			de.StartingSectorLocation = int32(n.start)
+			de.StreamSize = uint64(len(mini))
+		case n.storage:
+			de.ObjectType = typeStorage
+		default:
+			de.StartingSectorLocation = int32(n.start)
+			de.StreamSize = uint64(len(n.data))
+		}
+		binary.Write(bw, le, de)
+	}
+	for i := len(nodes); i%(secSize/128) != 0; i++ {
+		binary.Write(bw, le, &directory{LeftSiblingID: noStream, RightSiblingID: noStream, ChildID: noStream})
+	}
+
+	// FAT and DIFAT
+	binary.Write(bw, le, fat)
+	for i := 0; i < numDIFAT; i++ {
+		for j := 0; j < perSector-1; j++ {
+			k := 109 + i*(perSector-1) + j
+			if k < numFAT {
+				binary.Write(bw, le, fatStart+uint32(k))
+			} else {
+				binary.Write(bw, le, secFree)
+			}
+		}
+		if i == numDIFAT-1 {
+			binary.Write(bw, le, secEndOfChain)
+		} else {
+			binary.Write(bw, le, difatStart+uint32(i)+1)
+		}
+	}
+	if bw.err == nil && bw.n != int64(secSize*(1+numData)) {
+		return bw.n, errors.New("cfb: internal layout error")
+	}
+	if bw.err == nil {
+		bw.err = bw.w.(*bufio.Writer).Flush()
+	}
+	return bw.n, bw.err
+}
+
+// buildTree arranges sorted sibling entries into a balanced binary tree and
+// returns the ID of its root. Nodes on an incomplete bottom level are red,
+// so that every path has the same number of black nodes.
+func buildTree(nodes []*wnode, depth, bottom int) uint32 {
+	if len(nodes) == 0 {
+		return noStream
+	}
+	mid := len(nodes) / 2
+	n := nodes[mid]
+	n.left = buildTree(nodes[:mid], depth+1, bottom)
+	n.right = buildTree(nodes[mid+1:], depth+1, bottom)
+	n.color = 1
+	if depth == bottom {
+		n.color = 0
+	}
+	return n.id
+}
+
+// maxDepth returns the depth of an incomplete bottom level of a balanced
+// tree of n nodes, or -1 if the tree is complete.
+func maxDepth(n int) int {
+	depth := 0
+	for full := 1; full < n; full = full*2 + 1 {
+		depth++
+	}
+	if (1<<uint(depth+1))-1 == n {
+		return -1
+	}
+	return depth
+}
+
+// countWriter tracks the bytes written and the first error.
+type countWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// pad writes zeros up to the next multiple of size.
+func (c *countWriter) pad(size int) {
+	if rem := int(c.n % int64(size)); rem != 0 {
+		c.Write(make([]byte, size-rem))
+	}
+}
@@ -0,0 +1,139 @@
+package cfb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func testData(n int, seed byte) []byte {
+	res := make([]byte, n)
+	for i := range res {
+		res[i] = seed + byte(i%251)
+	}
+	return res
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	streams := map[string][]byte{
+		"Workbook":                      testData(10000, 1),
+		"\x05SummaryInformation":        testData(300, 2),
+		"Empty":                         nil,
+		"_VBA_PROJECT_CUR/VBA/dir":      testData(700, 3),
+		"_VBA_PROJECT_CUR/VBA/Module1":  testData(4096, 4),
+		"_VBA_PROJECT_CUR/VBA/Module2":  testData(64, 5),
+		"_VBA_PROJECT_CUR/PROJECT":      testData(1, 6),
+		"_VBA_PROJECT_CUR/VBA/Sheet1":   testData(65, 7),
+		"_VBA_PROJECT_CUR/VBA/Sheet2":   testData(5000, 8),
+		"_VBA_PROJECT_CUR/VBA/Sheet3":   testData(129, 9),
+		"_VBA_PROJECT_CUR/VBA/Sheet4":   testData(12, 10),
+		"_VBA_PROJECT_CUR/VBA/Sheet5":   testData(4095, 11),
+		"_VBA_PROJECT_CUR/VBA/_VBA_PRO": testData(99, 12),
+	}
+	clsid := [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	for _, version := range []int{3, 4} {
+		w, err := NewWriter(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, data := range streams {
+			if err = w.CreateStream(name, data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = w.CreateStorage("ObjectPool/_1234"); err != nil {
+			t.Fatal(err)
+		}
+		if err = w.SetCLSID("_VBA_PROJECT_CUR", clsid); err != nil {
+			t.Fatal(err)
+		}
+
+		buf := &bytes.Buffer{}
+		if _, err = w.WriteTo(buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len()%(1<<(3*version-3)) != 0 {
+			t.Errorf("v%d: file size %d is not a multiple of the sector size", version, buf.Len())
+		}
+
+		d, err := New(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("v%d: %v", version, err)
+		}
+		for name, data := range streams {
+			r, err := d.Open(name)
+			if err != nil {
+				t.Fatalf("v%d: %s: %v", version, name, err)
+			}
+			res, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatalf("v%d: %s: %v", version, name, err)
+			}
+			if !bytes.Equal(res, data) {
+				t.Errorf("v%d: %s: stream contents differ", version, name)
+			}
+		}
+
+		e, err := d.Stat("_VBA_PROJECT_CUR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !e.IsStorage || e.CLSID != clsid {
+			t.Errorf("v%d: unexpected storage %+v", version, e)
+		}
+		if _, err = d.Stat("ObjectPool/_1234"); err != nil {
+			t.Errorf("v%d: %v", version, err)
+		}
+		ents, err := d.ReadDir("_VBA_PROJECT_CUR/VBA")
+		if err != nil || len(ents) != 9 {
+			t.Errorf("v%d: unexpected storage contents %v %v", version, ents, err)
+		}
+	}
+}
+
+func TestWriterDIFAT(t *testing.T) {
+	// more than 109 FAT sectors requires DIFAT sectors
+	data := testData(110*128*512, 1)
+	w, _ := NewWriter(3)
+	w.CreateStream("Big", data)
+	buf := &bytes.Buffer{}
+	if _, err := w.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := New(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.header.NumDIFATSectors == 0 {
+		t.Error("expected DIFAT sectors")
+	}
+	r, err := d.Open("Big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(res, data) {
+		t.Error("stream contents differ")
+	}
+}
+
+func TestWriterNames(t *testing.T) {
+	w, _ := NewWriter(3)
+	if err := w.CreateStream("a:b", nil); err == nil {
+		t.Error("expected invalid name error")
+	}
+	if err := w.CreateStream("abcdefghijklmnopqrstuvwxyz0123456789", nil); err == nil {
+		t.Error("expected name length error")
+	}
+	w.CreateStream("Stream", nil)
+	if err := w.CreateStream("Stream/Sub", nil); err == nil {
+		t.Error("expected error creating stream within a stream")
+	}
+	if _, err := NewWriter(5); err == nil {
+		t.Error("expected version error")
+	}
+}
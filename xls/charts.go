@@ -0,0 +1,248 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// Charts returns the charts embedded in the named sheet, or the chart
+// displayed by a chart sheet.
+func (b *WorkBook) Charts(sheetName string) ([]*commonxl.Chart, error) {
+	for _, s := range b.sheets {
+		if s.Name != sheetName {
+			continue
+		}
+		ss, ok := b.pos2substream[int64(s.Position)]
+		if !ok {
+			return nil, errors.New("xls: sheet not found")
+		}
+		return b.parseCharts(s.Name, b.substreams[ss]), nil
+	}
+	return nil, errors.New("xls: sheet not found")
+}
+
+// parseCharts finds the chart substreams (section 2.1.7.20.1) within a sheet.
+func (b *WorkBook) parseCharts(sheetName string, records []*rec) []*commonxl.Chart {
+	if len(records) == 0 {
+		return nil
+	}
+	if records[0].RecType == RecTypeBOF && len(records[0].Data) >= 4 &&
+		binary.LittleEndian.Uint16(records[0].Data[2:]) == 0x0020 {
+		// chart sheet
+		ch := b.parseChart(records)
+		ch.Sheet = sheetName
+		return []*commonxl.Chart{ch}
+	}
+
+	var res []*commonxl.Chart
+	var anchor *commonxl.CellRange
+	start, depth := -1, 0
+	for ridx, r := range records {
+		switch r.RecType {
+		case RecTypeMsoDrawing:
+			if depth > 0 {
+				continue
+			}
+			// the anchor of the chart object precedes the chart substream
+			for _, sp := range parseShapes(r.Data) {
+				if sp.anchor != nil {
+					anchor = sp.anchor
+				}
+			}
+		case RecTypeBOF:
+			if ridx == 0 {
+				continue
+			}
+			if depth == 0 {
+				start = ridx
+			}
+			depth++
+		case RecTypeEOF:
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 && start >= 0 {
+				ch := b.parseChart(records[start : ridx+1])
+				ch.Sheet = sheetName
+				ch.Anchor = anchor
+				res = append(res, ch)
+				anchor = nil
+				start = -1
+			}
+		}
+	}
+	return res
+}
+
+// parseChart decodes the records of a single chart substream.
+func (b *WorkBook) parseChart(records []*rec) *commonxl.Chart {
+	le := binary.LittleEndian
+	ch := &commonxl.Chart{}
+
+	var cur *commonxl.ChartSeries
+	depth := 0
+	seriesDepth, textDepth := -1, -1
+	lastText := ""
+	siIndex := 0
+	for _, r := range records {
+		data := r.Data
+		switch r.RecType {
+		case RecTypeBegin:
+			depth++
+		case RecTypeEnd:
+			depth--
+			if depth < seriesDepth {
+				seriesDepth = -1
+				cur = nil
+			}
+			if depth < textDepth {
+				textDepth = -1
+			}
+
+		case RecTypeSeries:
+			cur = &commonxl.ChartSeries{}
+			ch.Series = append(ch.Series, cur)
+			seriesDepth = depth + 1
+
+		case RecTypeBRAI:
+			if cur == nil || len(data) < 8 {
+				continue
+			}
+			id, rt := data[0], data[1]
+			cce := int(le.Uint16(data[6:]))
+			if rt != 2 || cce == 0 || len(data) < 8+cce {
+				continue
+			}
+			ref, err := b.decodeFormula(data[8:8+cce], 0, 0)
+			if err != nil {
+				continue
+			}
+			switch id {
+			case 0:
+				cur.NameRef = ref
+			case 1:
+				cur.ValuesRef = ref
+			case 2:
+				cur.CategoriesRef = ref
+			}
+
+		case RecTypeText:
+			textDepth = depth + 1
+			lastText = ""
+
+		case RecTypeSeriesText:
+			if len(data) < 4 {
+				continue
+			}
+			text := decodeShortXLUnicodeStringSafe(data[2:])
+			if textDepth < 0 && cur != nil {
+				cur.Name = text
+			} else {
+				lastText = text
+			}
+
+		case RecTypeObjectLink:
+			if len(data) >= 2 && le.Uint16(data) == 1 && textDepth >= 0 {
+				// chart title
+				ch.Title = lastText
+			}
+
+		case RecTypeBar, RecTypeLine, RecTypePie, RecTypeArea, RecTypeScatter,
+			RecTypeRadar, RecTypeRadarArea, RecTypeSurf, RecTypeBopPop:
+			if ch.Type == "" {
+				ch.Type = chartType(r.RecType, data)
+			}
+
+		case RecTypeSIIndex:
+			if len(data) >= 2 {
+				siIndex = int(le.Uint16(data))
+			}
+
+		case RecTypeNumber, RecTypeLabel, RecTypeBoolErr, RecTypeBlank:
+			// cached data points, col = series index and rw = point index
+			if len(data) < 6 || (siIndex != 1 && siIndex != 2) {
+				continue
+			}
+			rw, col := int(le.Uint16(data)), int(le.Uint16(data[2:]))
+			if col >= len(ch.Series) {
+				continue
+			}
+			var val interface{}
+			switch r.RecType {
+			case RecTypeNumber:
+				if len(data) >= 14 {
+					val = math.Float64frombits(le.Uint64(data[6:]))
+				}
+			case RecTypeLabel:
+				val, _ = decodeXLUnicodeStringSafe(data[6:])
+			case RecTypeBoolErr:
+				if len(data) >= 8 {
+					if data[7] == 1 {
+						val = berrLookup[data[6]]
+					} else {
+						val = data[6] != 0
+					}
+				}
+			}
+			ser := ch.Series[col]
+			if siIndex == 1 {
+				ser.Values = placePoint(ser.Values, rw, val)
+			} else {
+				ser.Categories = placePoint(ser.Categories, rw, val)
+			}
+		}
+	}
+	return ch
+}
+
+func placePoint(pts []interface{}, idx int, val interface{}) []interface{} {
+	for len(pts) <= idx {
+		pts = append(pts, nil)
+	}
+	pts[idx] = val
+	return pts
+}
+
+// chartType names the chart group type record.
+func chartType(rt recordType, data []byte) string {
+	switch rt {
+	case RecTypeBar:
+		if len(data) >= 6 && (data[4]&1) != 0 {
+			return "bar"
+		}
+		return "column"
+	case RecTypeLine:
+		return "line"
+	case RecTypePie:
+		if len(data) >= 4 && binary.LittleEndian.Uint16(data[2:]) > 0 {
+			return "doughnut"
+		}
+		return "pie"
+	case RecTypeBopPop:
+		return "pie"
+	case RecTypeArea:
+		return "area"
+	case RecTypeScatter:
+		if len(data) >= 6 && (data[4]&1) != 0 {
+			return "bubble"
+		}
+		return "scatter"
+	case RecTypeRadar, RecTypeRadarArea:
+		return "radar"
+	case RecTypeSurf:
+		return "surface"
+	}
+	return ""
+}
+
+// decodeShortXLUnicodeStringSafe is decodeShortXLUnicodeString with bounds checks.
+func decodeShortXLUnicodeStringSafe(raw []byte) string {
+	if len(raw) < 2 {
+		return ""
+	}
+	return decodeXLUnicodeStringNoCch(raw[1:], int(raw[0]))
+}
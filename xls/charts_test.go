@@ -0,0 +1,165 @@
+package xls
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// brai builds a BRAI record, referencing an area on the first sheet if
+// rows is set and holding literal data otherwise.
+func brai(id byte, rows ...int) *rec {
+	data := []byte{id, 1, 0, 0, 0, 0, 0, 0}
+	if len(rows) == 2 {
+		data[1] = 2
+		rgce := append([]byte{0x3B}, u16s(0, rows[0], rows[1], 0, 0)...)
+		binary.LittleEndian.PutUint16(data[6:], uint16(len(rgce)))
+		data = append(data, rgce...)
+	}
+	return sxRec(RecTypeBRAI, data)
+}
+
+func seriesText(s string) *rec {
+	return sxRec(RecTypeSeriesText, append([]byte{0, 0, byte(len(s))}, xlString(s, true)...))
+}
+
+func chartNumber(rw, col int, f float64) *rec {
+	data := append(u16s(rw, col, 0), make([]byte, 8)...)
+	binary.LittleEndian.PutUint64(data[6:], math.Float64bits(f))
+	return sxRec(RecTypeNumber, data)
+}
+
+func chartLabel(rw, col int, s string) *rec {
+	return sxRec(RecTypeLabel, append(u16s(rw, col, 0), xlString(s, false)...))
+}
+
+// testChart returns a chart substream with one bar series and a title.
+func testChart(dt int) []*rec {
+	begin, end := sxRec(RecTypeBegin, nil), sxRec(RecTypeEnd, nil)
+	return []*rec{
+		sxRec(RecTypeBOF, u16s(0x0600, dt)),
+		begin,
+		// the chart title
+		sxRec(RecTypeText, make([]byte, 32)),
+		begin,
+		seriesText("Sales by Region"),
+		sxRec(RecTypeObjectLink, u16s(1, 0, 0)),
+		end,
+		sxRec(RecTypeSeries, make([]byte, 12)),
+		begin,
+		brai(0),
+		brai(1, 1, 3),
+		brai(2, 1, 3),
+		brai(3),
+		seriesText("Units"),
+		// a data label, which is not the series name
+		sxRec(RecTypeText, make([]byte, 32)),
+		begin,
+		seriesText("label"),
+		end,
+		end,
+		sxRec(RecTypeBar, u16s(0, 0, 1)),
+		sxRec(RecTypeLine, u16s(0)),
+		end,
+		sxRec(RecTypeSIIndex, u16s(2)),
+		chartLabel(0, 0, "East"),
+		chartLabel(1, 0, "West"),
+		sxRec(RecTypeSIIndex, u16s(1)),
+		chartNumber(0, 0, 10.5),
+		sxRec(RecTypeBoolErr, append(u16s(2, 0, 0), 0x07, 1)),
+		chartNumber(0, 1, 99), // no such series
+		sxRec(RecTypeSIIndex, u16s(3)),
+		chartNumber(0, 0, 1), // bubble sizes
+		sxRec(RecTypeEOF, nil),
+	}
+}
+
+func testChartWorkBook() *WorkBook {
+	return &WorkBook{
+		sheets: []*boundSheet{{Name: "Data"}},
+		xtis:   []xti{{First: 0, Last: 0}},
+	}
+}
+
+func TestParseChart(t *testing.T) {
+	ch := testChartWorkBook().parseChart(testChart(0x0020))
+	want := &commonxl.Chart{
+		Type:  "bar",
+		Title: "Sales by Region",
+		Series: []*commonxl.ChartSeries{{
+			Name:          "Units",
+			ValuesRef:     "Data!$A$2:$A$4",
+			CategoriesRef: "Data!$A$2:$A$4",
+			Categories:    []interface{}{"East", "West"},
+			Values:        []interface{}{10.5, nil, "#DIV/0!"},
+		}},
+	}
+	if !reflect.DeepEqual(ch, want) {
+		t.Errorf("got chart %+v (series %+v), want %+v", ch, ch.Series[0], want)
+	}
+}
+
+func TestCharts(t *testing.T) {
+	anchor := &commonxl.CellRange{FirstRow: 1, LastRow: 8, FirstCol: 2, LastCol: 6}
+	b := testChartWorkBook()
+	b.sheets = append(b.sheets, &boundSheet{Position: 100, Name: "Chart1"})
+	sheet := []*rec{
+		sxRec(RecTypeBOF, u16s(0x0600, 0x0010)),
+		{RecType: RecTypeMsoDrawing, Data: testDrawing(spContainer(0, anchor))},
+	}
+	sheet = append(sheet, testChart(0x0020)...)
+	sheet = append(sheet, sxRec(RecTypeEOF, nil))
+	b.substreams = [][]*rec{nil, sheet, testChart(0x0020)}
+	b.pos2substream = map[int64]int{0: 1, 100: 2}
+
+	charts, err := b.Charts("Data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charts) != 1 || charts[0].Sheet != "Data" || !reflect.DeepEqual(charts[0].Anchor, anchor) ||
+		charts[0].Title != "Sales by Region" {
+		t.Errorf("got embedded charts %+v", charts)
+	}
+
+	charts, err = b.Charts("Chart1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charts) != 1 || charts[0].Sheet != "Chart1" || charts[0].Anchor != nil || len(charts[0].Series) != 1 {
+		t.Errorf("got chart sheet charts %+v", charts)
+	}
+
+	if _, err := b.Charts("Sheet9"); err == nil {
+		t.Error("expected an error for an unknown sheet")
+	}
+}
+
+func TestChartType(t *testing.T) {
+	tests := []struct {
+		rt   recordType
+		data []byte
+		want string
+	}{
+		{RecTypeBar, u16s(0, 0, 0), "column"},
+		{RecTypeBar, u16s(0, 0, 1), "bar"},
+		{RecTypeLine, u16s(0), "line"},
+		{RecTypePie, u16s(0, 0, 0), "pie"},
+		{RecTypePie, u16s(0, 50, 0), "doughnut"},
+		{RecTypeBopPop, nil, "pie"},
+		{RecTypeArea, u16s(0), "area"},
+		{RecTypeScatter, u16s(100, 1, 0), "scatter"},
+		{RecTypeScatter, u16s(100, 1, 1), "bubble"},
+		{RecTypeRadar, u16s(0), "radar"},
+		{RecTypeRadarArea, u16s(0), "radar"},
+		{RecTypeSurf, u16s(0), "surface"},
+		{RecTypeSeries, nil, ""},
+	}
+	for _, tc := range tests {
+		if got := chartType(tc.rt, tc.data); got != tc.want {
+			t.Errorf("chartType(%d, % x) = %q, want %q", tc.rt, tc.data, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// comparison operators used by CF and CF12 records (section 2.4.42).
+var cfOperators = []string{
+	"", "between", "notBetween", "equal", "notEqual",
+	"greaterThan", "lessThan", "greaterThanOrEqual", "lessThanOrEqual",
+}
+
+// rule types used by CF12 records (section 2.4.43).
+var cfTypes = []string{
+	"", commonxl.CondCellIs, commonxl.CondExpression, commonxl.CondColorScale,
+	commonxl.CondDataBar, commonxl.CondTop10, commonxl.CondIconSet,
+}
+
+// decodeSqRef decodes a SqRefU structure (section 2.5.245).
+func decodeSqRef(raw []byte) []commonxl.CellRange {
+	if len(raw) < 2 {
+		return nil
+	}
+	cref := int(binary.LittleEndian.Uint16(raw))
+	raw = raw[2:]
+	res := make([]commonxl.CellRange, 0, cref)
+	for i := 0; i < cref && len(raw) >= 8; i++ {
+		res = append(res, commonxl.CellRange{
+			FirstRow: int(binary.LittleEndian.Uint16(raw)),
+			LastRow:  int(binary.LittleEndian.Uint16(raw[2:])),
+			FirstCol: int(binary.LittleEndian.Uint16(raw[4:])),
+			LastCol:  int(binary.LittleEndian.Uint16(raw[6:])),
+		})
+		raw = raw[8:]
+	}
+	return res
+}
+
+// parseCF decodes a CF or CF12 record into a rule for the conditional format.
+func (b *WorkBook) parseCF(r *rec, cf *commonxl.CondFormat, priority int) (*commonxl.CondRule, error) {
+	le := binary.LittleEndian
+	raw := r.Data
+	if r.RecType == RecTypeCF12 {
+		if len(raw) < 12 {
+			return nil, errors.New("xls: invalid CF12 record")
+		}
+		raw = raw[12:] // skip FrtRefHeader
+	}
+	if len(raw) < 6 {
+		return nil, errors.New("xls: invalid CF record")
+	}
+
+	rule := &commonxl.CondRule{Priority: priority}
+	ct, cp := int(raw[0]), int(raw[1])
+	cce1 := int(le.Uint16(raw[2:]))
+	cce2 := int(le.Uint16(raw[4:]))
+	raw = raw[6:]
+
+	if r.RecType == RecTypeCF12 {
+		if ct < len(cfTypes) {
+			rule.Type = cfTypes[ct]
+		}
+		// DXFN12
+		if len(raw) < 4 {
+			return nil, errors.New("xls: invalid CF12 record")
+		}
+		cbdxf := int(le.Uint32(raw))
+		raw = raw[4:]
+		if cbdxf > len(raw) {
+			return nil, errors.New("xls: invalid CF12 record")
+		}
+		if cbdxf > 0 {
+			ds, n, err := b.parseDXFN(raw[:cbdxf])
+			if err != nil {
+				return nil, err
+			}
+			parseXFExtNoFRT(raw[n:cbdxf], ds)
+			rule.Style = ds
+			raw = raw[cbdxf:]
+		} else {
+			// an empty DXFN12 has 2 reserved bytes instead
+			if len(raw) < 2 {
+				return nil, errors.New("xls: invalid CF12 record")
+			}
+			raw = raw[2:]
+		}
+	} else {
+		switch ct {
+		case 1:
+			rule.Type = commonxl.CondCellIs
+		case 2:
+			rule.Type = commonxl.CondExpression
+		}
+		ds, n, err := b.parseDXFN(raw)
+		if err != nil {
+			return nil, err
+		}
+		rule.Style = ds
+		raw = raw[n:]
+	}
+	if rule.Type == commonxl.CondCellIs && cp < len(cfOperators) {
+		rule.Operator = cfOperators[cp]
+	}
+
+	if len(raw) < cce1+cce2 {
+		return nil, errors.New("xls: truncated CF formulas")
+	}
+	var baseRow, baseCol int
+	if len(cf.Ranges) > 0 {
+		baseRow, baseCol = cf.Ranges[0].FirstRow, cf.Ranges[0].FirstCol
+	}
+	for _, rgce := range [][]byte{raw[:cce1], raw[cce1 : cce1+cce2]} {
+		if len(rgce) == 0 {
+			continue
+		}
+		f, err := b.decodeFormula(rgce, baseRow, baseCol)
+		if err != nil {
+			return nil, err
+		}
+		rule.Formulas = append(rule.Formulas, f)
+	}
+	raw = raw[cce1+cce2:]
+
+	if r.RecType == RecTypeCF12 && len(raw) >= 2 {
+		// skip fmlaActive, then read the flags and priority
+		cce := int(le.Uint16(raw))
+		raw = raw[2:]
+		if len(raw) >= cce+3 {
+			raw = raw[cce:]
+			rule.StopIfTrue = (raw[0] & 0x02) != 0
+			if p := int(le.Uint16(raw[1:])); p > 0 {
+				rule.Priority = p
+			}
+		}
+	}
+	return rule, nil
+}
@@ -0,0 +1,102 @@
+package xls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// cf12Record builds a CF12 record with an optional DXFN, one formula and
+// the trailing flags, priority and template fields.
+func cf12Record(ct, cp byte, dxfn, rgce []byte, flags byte, priority uint16) *rec {
+	le := binary.LittleEndian
+	var b bytes.Buffer
+	b.Write(make([]byte, 12)) // FrtRefHeader
+	b.WriteByte(ct)
+	b.WriteByte(cp)
+	binary.Write(&b, le, uint16(len(rgce)))
+	binary.Write(&b, le, uint16(0))
+	binary.Write(&b, le, uint32(len(dxfn)))
+	if len(dxfn) > 0 {
+		b.Write(dxfn)
+	} else {
+		b.Write([]byte{0, 0}) // reserved
+	}
+	b.Write(rgce)
+	binary.Write(&b, le, uint16(0)) // empty fmlaActive
+	b.WriteByte(flags)
+	binary.Write(&b, le, priority)
+	binary.Write(&b, le, uint16(0x0005)) // icfTemplate
+	b.WriteByte(16)
+	b.Write(make([]byte, 16))
+	return &rec{RecType: RecTypeCF12, RecSize: uint16(b.Len()), Data: b.Bytes()}
+}
+
+// fillDXFN returns a DXFN with only a pattern fill of the indexed color.
+func fillDXFN(icv uint16) []byte {
+	raw := make([]byte, 10)
+	// ibitAtrPat, with the foreground color not included
+	binary.LittleEndian.PutUint32(raw, 1<<29|1<<17)
+	binary.LittleEndian.PutUint16(raw[8:], icv<<7)
+	return raw
+}
+
+func TestParseCF12(t *testing.T) {
+	b := &WorkBook{}
+	cf := &commonxl.CondFormat{Ranges: []commonxl.CellRange{{FirstRow: 1, LastRow: 9}}}
+
+	// cell value rule with a style
+	r := cf12Record(1, 5, fillDXFN(2), []byte{0x1E, 100, 0}, 0x02, 3)
+	rule, err := b.parseCF(r, cf, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &commonxl.CondRule{
+		Type:       commonxl.CondCellIs,
+		Operator:   "greaterThan",
+		Formulas:   []string{"100"},
+		Priority:   3,
+		StopIfTrue: true,
+		Style:      &commonxl.DiffStyle{FillColor: commonxl.IndexedColor(nil, 2)},
+	}
+	if !reflect.DeepEqual(rule, want) {
+		t.Errorf("got rule %+v (style %+v), want %+v", rule, rule.Style, want)
+	}
+
+	// data bar without a style, where the formula follows the reserved bytes
+	r = cf12Record(4, 0, nil, []byte{0x1E, 7, 0}, 0, 2)
+	rule, err = b.parseCF(r, cf, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = &commonxl.CondRule{
+		Type:     commonxl.CondDataBar,
+		Formulas: []string{"7"},
+		Priority: 2,
+	}
+	if !reflect.DeepEqual(rule, want) {
+		t.Errorf("got rule %+v, want %+v", rule, want)
+	}
+}
+
+func TestParseCF(t *testing.T) {
+	b := &WorkBook{}
+	cf := &commonxl.CondFormat{}
+	var data []byte
+	data = append(data, 1, 1, 3, 0, 3, 0) // ct, cp, cce1, cce2
+	data = append(data, fillDXFN(2)...)
+	data = append(data, 0x1E, 1, 0, 0x1E, 5, 0)
+	r := &rec{RecType: RecTypeCF, RecSize: uint16(len(data)), Data: data}
+
+	rule, err := b.parseCF(r, cf, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Type != commonxl.CondCellIs || rule.Operator != "between" || rule.Priority != 4 ||
+		!reflect.DeepEqual(rule.Formulas, []string{"1", "5"}) || rule.Style == nil {
+		t.Errorf("got rule %+v", rule)
+	}
+}
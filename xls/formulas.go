@@ -0,0 +1,264 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// decodeFormula renders the parsed formula tokens (Rgce, section 2.5.198.104)
+// back into formula text. Relative references (PtgRefN, PtgAreaN) are resolved
+// against the cell at baseRow, baseCol.
+//
+// Only the subset of tokens commonly found in conditional formats, chart
+// series and names are supported, unknown tokens return an error.
+func (b *WorkBook) decodeFormula(rgce []byte, baseRow, baseCol int) (string, error) {
+	var stack []string
+	pop := func() string {
+		if len(stack) == 0 {
+			return ""
+		}
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return s
+	}
+	le := binary.LittleEndian
+
+	for len(rgce) > 0 {
+		ptg := rgce[0]
+		rgce = rgce[1:]
+
+		if ptg >= 0x20 {
+			// class variants of the same token (reference, value, array)
+			ptg = (ptg & 0x1F) | 0x20
+		}
+		need := ptgSizes[ptg]
+		if need < 0 || len(rgce) < need {
+			return "", fmt.Errorf("xls: unsupported or truncated formula token 0x%02x", ptg)
+		}
+		data := rgce[:need]
+		rgce = rgce[need:]
+
+		switch ptg {
+		case 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10, 0x11:
+			b2 := pop()
+			a2 := pop()
+			stack = append(stack, a2+ptgOperators[ptg]+b2)
+		case 0x12:
+			stack = append(stack, "+"+pop())
+		case 0x13:
+			stack = append(stack, "-"+pop())
+		case 0x14:
+			stack = append(stack, pop()+"%")
+		case 0x15:
+			stack = append(stack, "("+pop()+")")
+		case 0x16:
+			stack = append(stack, "")
+		case 0x17: // PtgStr
+			s, n, err := decodeShortXLUnicodeString(rgce)
+			if err != nil {
+				return "", err
+			}
+			rgce = rgce[n:]
+			stack = append(stack, `"`+strings.ReplaceAll(s, `"`, `""`)+`"`)
+		case 0x19: // PtgAttr
+			if data[0]&0x04 != 0 {
+				// tAttrChoose has a jump table to skip
+				n := 2 * (int(le.Uint16(data[1:])) + 1)
+				if len(rgce) < n {
+					return "", errors.New("xls: truncated formula")
+				}
+				rgce = rgce[n:]
+			}
+			if data[0]&0x10 != 0 {
+				// tAttrSum is an optimized SUM()
+				stack = append(stack, "SUM("+pop()+")")
+			}
+		case 0x1C: // PtgErr
+			be, ok := berrLookup[data[0]]
+			if !ok {
+				be = "<unknown error>"
+			}
+			stack = append(stack, be)
+		case 0x1D: // PtgBool
+			if data[0] != 0 {
+				stack = append(stack, "TRUE")
+			} else {
+				stack = append(stack, "FALSE")
+			}
+		case 0x1E: // PtgInt
+			stack = append(stack, strconv.Itoa(int(le.Uint16(data))))
+		case 0x1F: // PtgNum
+			v := math.Float64frombits(le.Uint64(data))
+			stack = append(stack, strconv.FormatFloat(v, 'f', -1, 64))
+
+		case 0x21, 0x22: // PtgFunc, PtgFuncVar
+			var iftab uint16
+			argc := -1
+			if ptg == 0x22 {
+				argc = int(data[0] & 0x7F)
+				iftab = le.Uint16(data[1:]) & 0x7FFF
+			} else {
+				iftab = le.Uint16(data)
+			}
+			fn, ok := ptgFuncs[iftab]
+			if !ok {
+				fn = ptgFunc{fmt.Sprintf("FUNC%d", iftab), -1}
+			}
+			if argc < 0 {
+				argc = fn.argc
+			}
+			if argc < 0 || argc > len(stack) {
+				return "", fmt.Errorf("xls: unknown argument count for formula function %d", iftab)
+			}
+			args := make([]string, argc)
+			for i := argc - 1; i >= 0; i-- {
+				args[i] = pop()
+			}
+			stack = append(stack, fn.name+"("+strings.Join(args, ",")+")")
+
+		case 0x23: // PtgName
+			idx := int(le.Uint32(data)) - 1
+			if idx >= 0 && idx < len(b.names) {
+				stack = append(stack, b.names[idx])
+			} else {
+				stack = append(stack, "#NAME?")
+			}
+
+		case 0x24, 0x2C: // PtgRef, PtgRefN
+			stack = append(stack, formatRef(data, ptg == 0x2C, baseRow, baseCol))
+		case 0x25, 0x2D: // PtgArea, PtgAreaN
+			stack = append(stack, formatArea(data, ptg == 0x2D, baseRow, baseCol))
+		case 0x2A, 0x2B: // PtgRefErr, PtgAreaErr
+			stack = append(stack, "#REF!")
+		case 0x26, 0x27, 0x28: // PtgMemArea, PtgMemErr, PtgMemNoMem
+			// these wrap a sub-expression, which follows directly
+		case 0x29: // PtgMemFunc
+			// wraps a sub-expression
+		case 0x3A: // PtgRef3d
+			stack = append(stack, b.sheetPrefix(le.Uint16(data))+formatRef(data[2:], false, baseRow, baseCol))
+		case 0x3B: // PtgArea3d
+			stack = append(stack, b.sheetPrefix(le.Uint16(data))+formatArea(data[2:], false, baseRow, baseCol))
+		case 0x3C, 0x3D: // PtgRefErr3d, PtgAreaErr3d
+			stack = append(stack, b.sheetPrefix(le.Uint16(data))+"#REF!")
+		case 0x39: // PtgNameX
+			stack = append(stack, "#NAME?")
+
+		case 0x01, 0x02: // PtgExp, PtgTbl
+			return "", errors.New("xls: shared formulas are not supported")
+		default:
+			return "", fmt.Errorf("xls: unsupported formula token 0x%02x", ptg)
+		}
+	}
+
+	return strings.Join(stack, ","), nil
+}
+
+// sheetPrefix returns the quoted sheet name for a 3D reference.
+func (b *WorkBook) sheetPrefix(ixti uint16) string {
+	if int(ixti) >= len(b.xtis) {
+		return "#REF!"
+	}
+	x := b.xtis[ixti]
+	if x.First < 0 || int(x.First) >= len(b.sheets) {
+		return "#REF!"
+	}
+	name := b.sheets[x.First].Name
+	if x.Last != x.First && x.Last >= 0 && int(x.Last) < len(b.sheets) {
+		name += ":" + b.sheets[x.Last].Name
+	}
+	if strings.ContainsAny(name, " -'!()") {
+		name = "'" + strings.ReplaceAll(name, "'", "''") + "'"
+	}
+	return name + "!"
+}
+
+// formatRef renders a RgceLoc or RgceLocRel (section 2.5.198.109) cell reference.
+func formatRef(data []byte, relative bool, baseRow, baseCol int) string {
+	rw := int(binary.LittleEndian.Uint16(data))
+	col := binary.LittleEndian.Uint16(data[2:])
+	return formatLoc(rw, col, relative, baseRow, baseCol)
+}
+
+// formatArea renders a RgceArea or RgceAreaRel (section 2.5.198.105) cell range.
+func formatArea(data []byte, relative bool, baseRow, baseCol int) string {
+	rwFirst := int(binary.LittleEndian.Uint16(data))
+	rwLast := int(binary.LittleEndian.Uint16(data[2:]))
+	colFirst := binary.LittleEndian.Uint16(data[4:])
+	colLast := binary.LittleEndian.Uint16(data[6:])
+	return formatLoc(rwFirst, colFirst, relative, baseRow, baseCol) + ":" +
+		formatLoc(rwLast, colLast, relative, baseRow, baseCol)
+}
+
+func formatLoc(rw int, col uint16, relative bool, baseRow, baseCol int) string {
+	colRel := (col & 0x4000) != 0
+	rowRel := (col & 0x8000) != 0
+	c := int(col & 0x3FFF)
+	if relative {
+		// relative offsets are signed and wrap around the sheet
+		if colRel {
+			c = (baseCol + int(int8(c))) & 0xFF
+		}
+		if rowRel {
+			rw = (baseRow + int(int16(rw))) & 0xFFFF
+		}
+	}
+	res := ""
+	if !colRel {
+		res = "$"
+	}
+	res += commonxl.ColumnName(c)
+	if !rowRel {
+		res += "$"
+	}
+	return res + strconv.Itoa(rw+1)
+}
+
+// number of data bytes following each Ptg type byte (-1 = unsupported).
+var ptgSizes = [64]int{
+	0x00: -1, 0x01: 4, 0x02: 4, 0x03: 0, 0x04: 0, 0x05: 0, 0x06: 0, 0x07: 0,
+	0x08: 0, 0x09: 0, 0x0A: 0, 0x0B: 0, 0x0C: 0, 0x0D: 0, 0x0E: 0, 0x0F: 0,
+	0x10: 0, 0x11: 0, 0x12: 0, 0x13: 0, 0x14: 0, 0x15: 0, 0x16: 0, 0x17: 0,
+	0x18: -1, 0x19: 3, 0x1A: -1, 0x1B: -1, 0x1C: 1, 0x1D: 1, 0x1E: 2, 0x1F: 8,
+	0x20: -1, 0x21: 2, 0x22: 3, 0x23: 4, 0x24: 4, 0x25: 8, 0x26: 6, 0x27: 6,
+	0x28: 6, 0x29: 2, 0x2A: 4, 0x2B: 8, 0x2C: 4, 0x2D: 8, 0x2E: -1, 0x2F: -1,
+	0x30: -1, 0x31: -1, 0x32: -1, 0x33: -1, 0x34: -1, 0x35: -1, 0x36: -1, 0x37: -1,
+	0x38: -1, 0x39: 6, 0x3A: 6, 0x3B: 10, 0x3C: 6, 0x3D: 10, 0x3E: -1, 0x3F: -1,
+}
+
+var ptgOperators = map[byte]string{
+	0x03: "+", 0x04: "-", 0x05: "*", 0x06: "/", 0x07: "^", 0x08: "&",
+	0x09: "<", 0x0A: "<=", 0x0B: "=", 0x0C: ">=", 0x0D: ">", 0x0E: "<>",
+	0x0F: " ", 0x10: ",", 0x11: ":",
+}
+
+type ptgFunc struct {
+	name string
+	argc int // number of fixed arguments, -1 = variable
+}
+
+// Ftab values (section 2.5.198.17) for the most commonly used functions.
+var ptgFuncs = map[uint16]ptgFunc{
+	0: {"COUNT", -1}, 1: {"IF", -1}, 2: {"ISNA", 1}, 3: {"ISERROR", 1},
+	4: {"SUM", -1}, 5: {"AVERAGE", -1}, 6: {"MIN", -1}, 7: {"MAX", -1},
+	8: {"ROW", -1}, 9: {"COLUMN", -1}, 10: {"NA", 0}, 15: {"SIN", 1},
+	16: {"COS", 1}, 19: {"PI", 0}, 24: {"ABS", 1}, 25: {"INT", 1},
+	26: {"SIGN", 1}, 27: {"ROUND", 2}, 28: {"LOOKUP", -1}, 29: {"INDEX", -1},
+	31: {"MID", 3}, 32: {"LEN", 1}, 33: {"VALUE", 1}, 34: {"TRUE", 0},
+	35: {"FALSE", 0}, 36: {"AND", -1}, 37: {"OR", -1}, 38: {"NOT", 1},
+	39: {"MOD", 2}, 48: {"TEXT", 2}, 63: {"RAND", 0}, 65: {"DATE", 3},
+	66: {"TIME", 3}, 67: {"DAY", 1}, 68: {"MONTH", 1}, 69: {"YEAR", 1},
+	70: {"WEEKDAY", -1}, 74: {"NOW", 0}, 77: {"ROWS", 1}, 78: {"COLUMNS", 1},
+	101: {"HLOOKUP", -1}, 102: {"VLOOKUP", -1}, 111: {"CHAR", 1},
+	112: {"LOWER", 1}, 113: {"UPPER", 1}, 115: {"LEFT", -1}, 116: {"RIGHT", -1},
+	118: {"TRIM", 1}, 124: {"FIND", -1}, 127: {"ISTEXT", 1}, 128: {"ISNUMBER", 1},
+	129: {"ISBLANK", 1}, 169: {"COUNTA", -1}, 184: {"FACT", 1}, 190: {"ISNONTEXT", 1},
+	198: {"ISLOGICAL", 1}, 220: {"DAYS360", -1}, 221: {"TODAY", 0}, 336: {"CONCATENATE", -1},
+	342: {"RADIANS", 1}, 343: {"DEGREES", 1}, 344: {"SUBTOTAL", -1}, 345: {"SUMIF", -1},
+	346: {"COUNTIF", -1}, 347: {"COUNTBLANK", 1}, 359: {"HYPERLINK", -1},
+}
@@ -0,0 +1,377 @@
+package xls
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"unicode/utf16"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// Office Drawing record types used for images, per [MS-ODRAW].
+const (
+	artBStoreContainer = 0xF001
+	artSpContainer     = 0xF004
+	artFBSE            = 0xF007
+	artFOPT            = 0xF00B
+	artClientAnchor    = 0xF010
+
+	artBlipEMF  = 0xF01A
+	artBlipWMF  = 0xF01B
+	artBlipPICT = 0xF01C
+	artBlipJPEG = 0xF01D
+	artBlipPNG  = 0xF01E
+	artBlipDIB  = 0xF01F
+	artBlipTIFF = 0xF029
+	artBlipCMYK = 0xF02A // CMYK JPEG
+
+	artPropPib = 0x0104 // BLIP index property
+)
+
+// artHeader is an OfficeArtRecordHeader (section 2.2.1 of [MS-ODRAW]).
+type artHeader struct {
+	ver      uint16
+	instance uint16
+	recType  uint16
+	length   int
+}
+
+func readArtHeader(raw []byte) (artHeader, bool) {
+	if len(raw) < 8 {
+		return artHeader{}, false
+	}
+	le := binary.LittleEndian
+	vi := le.Uint16(raw)
+	h := artHeader{
+		ver:      vi & 0x0F,
+		instance: vi >> 4,
+		recType:  le.Uint16(raw[2:]),
+		length:   int(le.Uint32(raw[4:])),
+	}
+	if h.length < 0 || h.length > len(raw)-8 {
+		// truncated, use what is available
+		h.length = len(raw) - 8
+	}
+	return h, true
+}
+
+// Images returns the pictures embedded in the workbook. Pictures placed
+// on a sheet are listed once for each placement, with the sheet name and
+// anchor range, and unplaced pictures are listed without an anchor.
+func (b *WorkBook) Images() ([]*commonxl.Image, error) {
+	var group []byte
+	for _, r := range b.drawingRecords(0) {
+		group = append(group, r...)
+	}
+	blips := parseBStore(group)
+	if len(blips) == 0 {
+		return nil, nil
+	}
+
+	var res []*commonxl.Image
+	used := make(map[int]bool)
+	for _, s := range b.sheets {
+		ss, ok := b.pos2substream[int64(s.Position)]
+		if !ok || ss == 0 {
+			continue
+		}
+		var dg []byte
+		for _, r := range b.drawingRecords(ss) {
+			dg = append(dg, r...)
+		}
+		for _, sp := range parseShapes(dg) {
+			if sp.pib < 1 || sp.pib > len(blips) || blips[sp.pib-1] == nil {
+				continue
+			}
+			used[sp.pib-1] = true
+			img := *blips[sp.pib-1]
+			img.Sheet = s.Name
+			img.Anchor = sp.anchor
+			res = append(res, &img)
+		}
+	}
+	for i, img := range blips {
+		if img != nil && !used[i] {
+			res = append(res, img)
+		}
+	}
+	return res, nil
+}
+
+// drawingRecords returns the data of the MsoDrawingGroup (for the globals
+// substream) or MsoDrawing records, along with any Continue records. Embedded
+// substreams such as charts are skipped.
+func (b *WorkBook) drawingRecords(ss int) [][]byte {
+	want := RecTypeMsoDrawing
+	if ss == 0 {
+		want = RecTypeMsoDrawingGroup
+	}
+	var res [][]byte
+	inSubstream := 0
+	last := false
+	for ridx, r := range b.substreams[ss] {
+		if inSubstream > 0 {
+			if r.RecType == RecTypeEOF {
+				inSubstream--
+			}
+			continue
+		}
+		switch r.RecType {
+		case RecTypeBOF:
+			if ridx > 0 {
+				inSubstream++
+			}
+			last = false
+		case want:
+			res = append(res, r.Data)
+			last = true
+		case RecTypeContinue:
+			if last {
+				res = append(res, r.Data)
+			}
+		default:
+			last = false
+		}
+	}
+	return res
+}
+
+// parseBStore extracts the images from the OfficeArtBStoreContainer in the
+// drawing group. Entries without embedded image data are nil.
+func parseBStore(raw []byte) []*commonxl.Image {
+	var res []*commonxl.Image
+	for len(raw) >= 8 {
+		h, _ := readArtHeader(raw)
+		body := raw[8 : 8+h.length]
+		raw = raw[8+h.length:]
+
+		if h.recType == artBStoreContainer {
+			for len(body) >= 8 {
+				fh, _ := readArtHeader(body)
+				fbse := body[8 : 8+fh.length]
+				body = body[8+fh.length:]
+				if fh.recType != artFBSE {
+					continue
+				}
+				res = append(res, parseFBSE(fbse, len(res)+1))
+			}
+			continue
+		}
+		if h.ver == 0x0F {
+			// search within other containers
+			res = append(res, parseBStore(body)...)
+		}
+	}
+	return res
+}
+
+// parseFBSE decodes an OfficeArtFBSE record and its embedded BLIP.
+func parseFBSE(raw []byte, n int) *commonxl.Image {
+	if len(raw) < 36 {
+		return nil
+	}
+	cbName := int(raw[33])
+	raw = raw[36:]
+	name := ""
+	if cbName > 0 && cbName <= len(raw) {
+		name = decodeUTF16Name(raw[:cbName])
+		raw = raw[cbName:]
+	}
+	h, ok := readArtHeader(raw)
+	if !ok {
+		// stored in a delay stream, which xls files do not use
+		return nil
+	}
+	img := decodeBlip(h, raw[8:8+h.length])
+	if img == nil {
+		return nil
+	}
+	if name == "" {
+		name = fmt.Sprintf("image%d.%s", n, img.Format)
+	}
+	img.Name = name
+	return img
+}
+
+func decodeUTF16Name(raw []byte) string {
+	u := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		c := binary.LittleEndian.Uint16(raw[i:])
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u))
+}
+
+// decodeBlip extracts the image file from an OfficeArtBlip record (section 2.2.23).
+func decodeBlip(h artHeader, body []byte) *commonxl.Image {
+	img := &commonxl.Image{}
+	uids := 16
+	metafile := false
+	switch h.recType {
+	case artBlipEMF:
+		img.Format, metafile = "emf", true
+		if h.instance == 0x3D5 {
+			uids = 32
+		}
+	case artBlipWMF:
+		img.Format, metafile = "wmf", true
+		if h.instance == 0x217 {
+			uids = 32
+		}
+	case artBlipPICT:
+		img.Format, metafile = "pict", true
+		if h.instance == 0x543 {
+			uids = 32
+		}
+	case artBlipJPEG, artBlipCMYK:
+		img.Format = "jpeg"
+		if h.instance == 0x46B || h.instance == 0x6E3 {
+			uids = 32
+		}
+	case artBlipPNG:
+		img.Format = "png"
+		if h.instance == 0x6E1 {
+			uids = 32
+		}
+	case artBlipDIB:
+		img.Format = "bmp"
+		if h.instance == 0x7A9 {
+			uids = 32
+		}
+	case artBlipTIFF:
+		img.Format = "tiff"
+		if h.instance == 0x6E5 {
+			uids = 32
+		}
+	default:
+		return nil
+	}
+
+	if !metafile {
+		// bitmaps have a 1-byte tag before the data
+		if len(body) < uids+1 {
+			return nil
+		}
+		img.Data = body[uids+1:]
+		if h.recType == artBlipDIB {
+			img.Data = dibToBMP(img.Data)
+		}
+		return img
+	}
+
+	// metafiles have a 34-byte header, and are usually compressed
+	if len(body) < uids+34 {
+		return nil
+	}
+	hdr := body[uids : uids+34]
+	data := body[uids+34:]
+	if hdr[32] == 0x00 {
+		// DEFLATE compression
+		var dec []byte
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err == nil {
+			dec, err = ioutil.ReadAll(zr)
+		}
+		if err != nil {
+			dec, err = ioutil.ReadAll(flate.NewReader(bytes.NewReader(data)))
+		}
+		if err != nil {
+			return nil
+		}
+		data = dec
+	}
+	img.Data = data
+	return img
+}
+
+// dibToBMP prepends a bitmap file header to device-independent bitmap data.
+func dibToBMP(dib []byte) []byte {
+	if len(dib) < 40 {
+		return dib
+	}
+	le := binary.LittleEndian
+	hdrSize := le.Uint32(dib)
+	bitCount := le.Uint16(dib[14:])
+	compression := le.Uint32(dib[16:])
+	clrUsed := le.Uint32(dib[32:])
+	if clrUsed == 0 && bitCount <= 8 {
+		clrUsed = 1 << bitCount
+	}
+	offset := 14 + hdrSize + clrUsed*4
+	if hdrSize == 40 && compression == 3 {
+		offset += 12 // BI_BITFIELDS masks
+	}
+
+	res := make([]byte, 14, 14+len(dib))
+	res[0], res[1] = 'B', 'M'
+	le.PutUint32(res[2:], uint32(14+len(dib)))
+	le.PutUint32(res[10:], offset)
+	return append(res, dib...)
+}
+
+type drawingShape struct {
+	pib    int
+	anchor *commonxl.CellRange
+}
+
+// parseShapes finds the shapes in a sheet's drawing which display a picture
+// or are anchored to cells.
+func parseShapes(raw []byte) []drawingShape {
+	var res []drawingShape
+	for len(raw) >= 8 {
+		h, _ := readArtHeader(raw)
+		body := raw[8 : 8+h.length]
+		raw = raw[8+h.length:]
+
+		if h.recType == artSpContainer {
+			sp := drawingShape{}
+			for len(body) >= 8 {
+				ch, _ := readArtHeader(body)
+				data := body[8 : 8+ch.length]
+				body = body[8+ch.length:]
+				switch ch.recType {
+				case artFOPT:
+					sp.pib = findPib(data, int(ch.instance))
+				case artClientAnchor:
+					if len(data) >= 18 {
+						le := binary.LittleEndian
+						sp.anchor = &commonxl.CellRange{
+							FirstCol: int(le.Uint16(data[2:])),
+							FirstRow: int(le.Uint16(data[6:])),
+							LastCol:  int(le.Uint16(data[10:])),
+							LastRow:  int(le.Uint16(data[14:])),
+						}
+					}
+				}
+			}
+			if sp.pib > 0 || sp.anchor != nil {
+				res = append(res, sp)
+			}
+			continue
+		}
+		if h.ver == 0x0F {
+			res = append(res, parseShapes(body)...)
+		}
+	}
+	return res
+}
+
+// findPib returns the BLIP index property from an OfficeArtFOPT record.
+func findPib(raw []byte, nprops int) int {
+	le := binary.LittleEndian
+	for i := 0; i < nprops && len(raw) >= 6; i++ {
+		opid := le.Uint16(raw)
+		if (opid & 0x3FFF) == artPropPib {
+			return int(le.Uint32(raw[2:]))
+		}
+		raw = raw[6:]
+	}
+	return 0
+}
@@ -0,0 +1,196 @@
+package xls
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// artRecord builds an OfficeArt record with the given header fields.
+func artRecord(ver, instance, recType uint16, body ...[]byte) []byte {
+	data := bytes.Join(body, nil)
+	res := make([]byte, 8, 8+len(data))
+	binary.LittleEndian.PutUint16(res, ver|instance<<4)
+	binary.LittleEndian.PutUint16(res[2:], recType)
+	binary.LittleEndian.PutUint32(res[4:], uint32(len(data)))
+	return append(res, data...)
+}
+
+// bitmapBlip builds a bitmap BLIP with a single UID.
+func bitmapBlip(recType, instance uint16, data []byte) []byte {
+	return artRecord(0, instance, recType, make([]byte, 16), []byte{0xFF}, data)
+}
+
+// metafileBlip builds a metafile BLIP with nuid UIDs, which is compressed
+// unless raw is set.
+func metafileBlip(recType, instance uint16, nuid int, data []byte, raw bool) []byte {
+	hdr := make([]byte, 34)
+	hdr[33] = 0xFE // filter
+	if raw {
+		hdr[32] = 0xFE
+	} else {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+	}
+	return artRecord(0, instance, recType, make([]byte, 16*nuid), hdr, data)
+}
+
+// fbse builds an OfficeArtFBSE record with an optional name and BLIP.
+func fbse(name string, blip []byte) []byte {
+	hdr := make([]byte, 36)
+	var rawName []byte
+	if name != "" {
+		for _, c := range name + "\x00" {
+			rawName = append(rawName, byte(c), 0)
+		}
+		hdr[33] = byte(len(rawName))
+	}
+	return artRecord(2, 0, artFBSE, hdr, rawName, blip)
+}
+
+// spContainer builds a shape container with a BLIP index and an anchor,
+// where either may be omitted.
+func spContainer(pib int, anchor *commonxl.CellRange) []byte {
+	var body []byte
+	if pib > 0 {
+		// an unrelated property, then pib with the fBid flag set
+		props := []byte{0x7F, 0x00, 0, 0, 0, 0, 0x04, 0x41, byte(pib), 0, 0, 0}
+		body = append(body, artRecord(3, 2, artFOPT, props)...)
+	}
+	if anchor != nil {
+		data := u16s(0, anchor.FirstCol, 0, anchor.FirstRow, 0, anchor.LastCol, 0, anchor.LastRow, 0)
+		body = append(body, artRecord(0, 0, artClientAnchor, data)...)
+	}
+	return artRecord(0x0F, 0, artSpContainer, body)
+}
+
+func testDrawingGroup() []byte {
+	bstore := artRecord(0x0F, 4, artBStoreContainer,
+		fbse("logo.png", bitmapBlip(artBlipPNG, 0x6E0, []byte("png data"))),
+		fbse("", metafileBlip(artBlipEMF, 0x3D4, 1, []byte("emf data"), false)),
+		artRecord(0, 0, 0xF000, []byte{1, 2, 3}), // not an FBSE
+		fbse("", nil),
+	)
+	// OfficeArtDggContainer
+	return artRecord(0x0F, 0, 0xF000, artRecord(0, 0, 0xF006, make([]byte, 16)), bstore)
+}
+
+func testDrawing(shapes ...[]byte) []byte {
+	// OfficeArtDgContainer and OfficeArtSpgrContainer
+	return artRecord(0x0F, 0, 0xF002, artRecord(0x0F, 0, 0xF003, shapes...))
+}
+
+func TestParseBStore(t *testing.T) {
+	got := parseBStore(testDrawingGroup())
+	want := []*commonxl.Image{
+		{Name: "logo.png", Format: "png", Data: []byte("png data")},
+		{Name: "image2.emf", Format: "emf", Data: []byte("emf data")},
+		nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got images %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeBlip(t *testing.T) {
+	dib := make([]byte, 44)
+	binary.LittleEndian.PutUint32(dib, 40)
+	binary.LittleEndian.PutUint16(dib[14:], 24)
+
+	tests := []struct {
+		blip   []byte
+		format string
+		data   []byte
+	}{
+		{bitmapBlip(artBlipJPEG, 0x46A, []byte("jpeg")), "jpeg", []byte("jpeg")},
+		{artRecord(0, 0x6E1, artBlipPNG, make([]byte, 32), []byte{0xFF}, []byte("png2")), "png", []byte("png2")},
+		{metafileBlip(artBlipEMF, 0x3D5, 2, []byte("emf2"), false), "emf", []byte("emf2")},
+		{metafileBlip(artBlipWMF, 0x217, 2, []byte("wmf2"), true), "wmf", []byte("wmf2")},
+		{metafileBlip(artBlipWMF, 0x216, 1, []byte("wmf"), false), "wmf", []byte("wmf")},
+		{metafileBlip(artBlipPICT, 0x542, 1, []byte("pict"), false), "pict", []byte("pict")},
+	}
+	for _, tc := range tests {
+		h, _ := readArtHeader(tc.blip)
+		img := decodeBlip(h, tc.blip[8:])
+		if img == nil || img.Format != tc.format || !bytes.Equal(img.Data, tc.data) {
+			t.Errorf("got image %+v, want %s %q", img, tc.format, tc.data)
+		}
+	}
+
+	blip := bitmapBlip(artBlipDIB, 0x7A8, dib)
+	h, _ := readArtHeader(blip)
+	img := decodeBlip(h, blip[8:])
+	if img == nil || img.Format != "bmp" || len(img.Data) != 14+len(dib) || string(img.Data[:2]) != "BM" ||
+		binary.LittleEndian.Uint32(img.Data[10:]) != 54 {
+		t.Errorf("got bitmap %+v", img)
+	}
+
+	for _, blip := range [][]byte{
+		artRecord(0, 0, 0xF020, make([]byte, 40)),         // unknown type
+		artRecord(0, 0x6E0, artBlipPNG, make([]byte, 16)), // no tag
+		artRecord(0, 0x3D4, artBlipEMF, make([]byte, 49)), // short header
+	} {
+		h, _ := readArtHeader(blip)
+		if img := decodeBlip(h, blip[8:]); img != nil {
+			t.Errorf("got image %+v from an invalid BLIP", img)
+		}
+	}
+}
+
+func TestParseShapes(t *testing.T) {
+	anchor := &commonxl.CellRange{FirstRow: 1, LastRow: 4, FirstCol: 2, LastCol: 3}
+	got := parseShapes(testDrawing(
+		spContainer(2, anchor),
+		spContainer(0, nil),
+		spContainer(0, anchor),
+	))
+	want := []drawingShape{{pib: 2, anchor: anchor}, {anchor: anchor}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got shapes %+v, want %+v", got, want)
+	}
+}
+
+func TestImages(t *testing.T) {
+	group := testDrawingGroup()
+	drawing := testDrawing(spContainer(2, &commonxl.CellRange{LastRow: 1, LastCol: 1}))
+	b := &WorkBook{
+		sheets: []*boundSheet{{Position: 100, Name: "Sheet1"}},
+		substreams: [][]*rec{
+			{
+				{RecType: RecTypeBOF},
+				// the drawing group continues in a Continue record
+				{RecType: RecTypeMsoDrawingGroup, Data: group[:40]},
+				{RecType: RecTypeContinue, Data: group[40:]},
+			},
+			{
+				{RecType: RecTypeBOF},
+				{RecType: RecTypeMsoDrawing, Data: drawing},
+				// an embedded chart substream
+				{RecType: RecTypeBOF},
+				{RecType: RecTypeMsoDrawing, Data: testDrawing(spContainer(1, nil))},
+				{RecType: RecTypeEOF},
+				{RecType: RecTypeEOF},
+			},
+		},
+		pos2substream: map[int64]int{100: 1},
+	}
+	got, err := b.Images()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*commonxl.Image{
+		{Name: "image2.emf", Format: "emf", Data: []byte("emf data"), Sheet: "Sheet1",
+			Anchor: &commonxl.CellRange{LastRow: 1, LastCol: 1}},
+		{Name: "logo.png", Format: "png", Data: []byte("png data")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got images %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,19 @@
+package xls
+
+import (
+	"github.com/pbnjay/grate/xls/vba"
+)
+
+// HasMacros returns true if the workbook contains a VBA project.
+func (b *WorkBook) HasMacros() bool {
+	return vba.FindProject(b.doc) != ""
+}
+
+// Macros returns the VBA project stored in the workbook, or nil if the
+// workbook does not contain any macros.
+func (b *WorkBook) Macros() (*vba.Project, error) {
+	if !b.HasMacros() {
+		return nil, nil
+	}
+	return vba.Open(b.doc)
+}
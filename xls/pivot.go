@@ -0,0 +1,339 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+	"unicode/utf16"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// pivotStream identifies a pivot cache stream (within the _SX_DB_CUR storage)
+// and the source of its data, as listed in the workbook globals.
+type pivotStream struct {
+	id     uint16
+	source string
+}
+
+// PivotCaches returns the pivot caches stored in the workbook.
+func (b *WorkBook) PivotCaches() ([]*commonxl.PivotCache, error) {
+	if b.pivotCaches != nil || len(b.pivotStreams) == 0 {
+		return b.pivotCaches, nil
+	}
+	for _, ps := range b.pivotStreams {
+		pc, err := b.loadPivotCache(ps)
+		if err != nil {
+			return nil, err
+		}
+		b.pivotCaches = append(b.pivotCaches, pc)
+	}
+	return b.pivotCaches, nil
+}
+
+// GetPivotCache returns the records of the named pivot cache, with the
+// field names in the first row.
+func (b *WorkBook) GetPivotCache(name string) (grate.Collection, error) {
+	caches, err := b.PivotCaches()
+	if err != nil {
+		return nil, err
+	}
+	for _, pc := range caches {
+		if pc.Name == name {
+			pc.Records.Rewind()
+			return pc.Records, nil
+		}
+	}
+	return nil, errors.New("xls: pivot cache not found")
+}
+
+type pivotField struct {
+	name    string
+	flags   uint16
+	catm    int
+	csxoper int
+	items   []interface{}
+}
+
+func (f *pivotField) allAtoms() bool   { return (f.flags & 0x0001) != 0 }
+func (f *pivotField) shortIitms() bool { return (f.flags & 0x0100) != 0 }
+func (f *pivotField) calculated() bool { return (f.flags & 0x4000) != 0 }
+
+// loadPivotCache decodes a PivotCache stream (section 2.1.7.20.5) from the
+// _SX_DB_CUR storage.
+func (b *WorkBook) loadPivotCache(ps pivotStream) (*commonxl.PivotCache, error) {
+	rdr, err := b.doc.Open(fmt.Sprintf("_SX_DB_CUR/%04X", ps.id))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(rdr)
+	if err != nil {
+		return nil, err
+	}
+	return parsePivotCache(ps, raw)
+}
+
+// parsePivotCache decodes the records of a PivotCache stream.
+func parsePivotCache(ps pivotStream, raw []byte) (*commonxl.PivotCache, error) {
+	pc := &commonxl.PivotCache{
+		ID:      int(ps.id),
+		Name:    fmt.Sprintf("PivotCache%d", ps.id),
+		Source:  ps.source,
+		Records: &commonxl.Sheet{},
+	}
+
+	var fields []*pivotField
+	var recFields, inlineFields []int
+	numSource := -1
+	var cur *pivotField
+	inRecords := false
+
+	var record []interface{}
+	started := false
+	nInline := 0
+	flush := func() {
+		pc.Records.Append(record...)
+		record = make([]interface{}, len(recFields))
+		started = false
+		nInline = 0
+	}
+	startRecords := func() {
+		inRecords = true
+		for i, f := range fields {
+			if f.calculated() || (numSource >= 0 && i >= numSource) {
+				continue
+			}
+			recFields = append(recFields, i)
+		}
+		header := make([]interface{}, len(recFields))
+		for i, fi := range recFields {
+			header[i] = fields[fi].name
+			if !fields[fi].allAtoms() {
+				inlineFields = append(inlineFields, i)
+			}
+		}
+		pc.Records.Append(header...)
+		record = make([]interface{}, len(recFields))
+	}
+
+	le := binary.LittleEndian
+	for len(raw) >= 4 {
+		recType := recordType(le.Uint16(raw))
+		recSize := int(le.Uint16(raw[2:]))
+		if len(raw) < 4+recSize {
+			return nil, io.ErrUnexpectedEOF
+		}
+		data := raw[4 : 4+recSize]
+		raw = raw[4+recSize:]
+
+		switch recType {
+		case RecTypeSXDB:
+			if len(data) >= 12 {
+				numSource = int(le.Uint16(data[10:]))
+			}
+
+		case RecTypeSXFDB:
+			if len(data) < 14 {
+				return nil, errors.New("xls: invalid SXFDB record")
+			}
+			cur = &pivotField{
+				flags:   le.Uint16(data),
+				csxoper: int(le.Uint16(data[8:])),
+				catm:    int(le.Uint16(data[12:])),
+			}
+			if len(data) > 14 {
+				cur.name, _ = decodeXLUnicodeStringSafe(data[14:])
+			}
+			fields = append(fields, cur)
+
+		case RecTypeSXDBB:
+			if !inRecords {
+				startRecords()
+			}
+			if started {
+				flush()
+			}
+			for i, fi := range recFields {
+				f := fields[fi]
+				if !f.allAtoms() {
+					continue
+				}
+				var idx int
+				if f.shortIitms() {
+					if len(data) < 1 {
+						break
+					}
+					idx = int(data[0])
+					data = data[1:]
+				} else {
+					if len(data) < 2 {
+						break
+					}
+					idx = int(le.Uint16(data))
+					data = data[2:]
+				}
+				if idx < len(f.items) {
+					record[i] = f.items[idx]
+				}
+			}
+			started = true
+			if len(inlineFields) == 0 {
+				flush()
+			}
+
+		case RecTypeSXNum, RecTypeSxBool, RecTypeSxErr, RecTypeSXInt,
+			RecTypeSXString, RecTypeSXDtr, RecTypeSxNil:
+			val := decodeSXOper(recType, data)
+			if !inRecords && cur != nil {
+				if len(cur.items) < cur.catm {
+					cur.items = append(cur.items, val)
+					continue
+				}
+				if cur.csxoper > 0 {
+					// grouping items are not part of the source data
+					cur.csxoper--
+					continue
+				}
+			}
+			if !inRecords {
+				startRecords()
+			}
+			if nInline < len(inlineFields) {
+				record[inlineFields[nInline]] = val
+				nInline++
+				started = true
+			}
+			if nInline == len(inlineFields) {
+				flush()
+			}
+		}
+	}
+	if started {
+		flush()
+	}
+	if !inRecords {
+		startRecords()
+	}
+
+	for _, f := range fields {
+		pc.Fields = append(pc.Fields, f.name)
+		pc.Items = append(pc.Items, f.items)
+	}
+	return pc, nil
+}
+
+// decodeSXOper decodes the value of one of the SXOPER records (section 2.5.248).
+func decodeSXOper(recType recordType, data []byte) interface{} {
+	le := binary.LittleEndian
+	switch recType {
+	case RecTypeSXNum:
+		if len(data) >= 8 {
+			return math.Float64frombits(le.Uint64(data))
+		}
+	case RecTypeSxBool:
+		if len(data) >= 2 {
+			return le.Uint16(data) != 0
+		}
+	case RecTypeSxErr:
+		if len(data) >= 2 {
+			if s, ok := berrLookup[data[0]]; ok {
+				return s
+			}
+		}
+	case RecTypeSXInt:
+		if len(data) >= 2 {
+			return int(int16(le.Uint16(data)))
+		}
+	case RecTypeSXString:
+		if len(data) >= 2 {
+			cch := int(le.Uint16(data))
+			if cch == 0xFFFF {
+				return nil
+			}
+			return decodeXLUnicodeStringNoCch(data[2:], cch)
+		}
+	case RecTypeSXDtr:
+		if len(data) >= 8 {
+			return time.Date(int(le.Uint16(data)), time.Month(le.Uint16(data[2:])),
+				int(data[4]), int(data[5]), int(data[6]), int(data[7]), 0, time.UTC)
+		}
+	}
+	return nil
+}
+
+// decodeXLUnicodeStringNoCch decodes a string of cch characters (section 2.5.296),
+// truncating it if there is not enough data.
+func decodeXLUnicodeStringNoCch(raw []byte, cch int) string {
+	if len(raw) < 1 {
+		return ""
+	}
+	flags := raw[0]
+	raw = raw[1:]
+	if (flags & 0x1) == 0 {
+		if cch > len(raw) {
+			cch = len(raw)
+		}
+		content := make([]uint16, cch)
+		for i, x := range raw[:cch] {
+			content[i] = uint16(x)
+		}
+		return string(utf16.Decode(content))
+	}
+	if cch > len(raw)/2 {
+		cch = len(raw) / 2
+	}
+	content := make([]uint16, cch)
+	for i := range content {
+		content[i] = binary.LittleEndian.Uint16(raw[i*2:])
+	}
+	return string(utf16.Decode(content))
+}
+
+// decodeXLUnicodeStringSafe is decodeXLUnicodeString with bounds checks.
+func decodeXLUnicodeStringSafe(raw []byte) (string, int) {
+	if len(raw) < 3 {
+		return "", len(raw)
+	}
+	cch := int(binary.LittleEndian.Uint16(raw))
+	n := 3 + cch
+	if (raw[2] & 0x1) != 0 {
+		n += cch
+	}
+	if n > len(raw) {
+		n = len(raw)
+	}
+	return decodeXLUnicodeStringNoCch(raw[2:], cch), n
+}
+
+// decodeDConRef returns the source range of a DConRef record (section 2.4.86)
+// in "Sheet!A1:B2" form.
+func decodeDConRef(data []byte) string {
+	if len(data) < 8 {
+		return ""
+	}
+	le := binary.LittleEndian
+	ref := commonxl.CellRange{
+		FirstRow: int(le.Uint16(data)),
+		LastRow:  int(le.Uint16(data[2:])),
+		FirstCol: int(data[4]),
+		LastCol:  int(data[5]),
+	}
+	cch := int(le.Uint16(data[6:]))
+	if cch == 0 {
+		return ref.String()
+	}
+	file := []rune(decodeXLUnicodeStringNoCch(data[8:], cch))
+	// virtual path (section 2.5.277), a leading 0x02 marks a sheet
+	// within the same workbook
+	for len(file) > 0 && file[0] < 0x20 {
+		file = file[1:]
+	}
+	if len(file) == 0 {
+		return ref.String()
+	}
+	return string(file) + "!" + ref.String()
+}
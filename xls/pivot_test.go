@@ -0,0 +1,130 @@
+package xls
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// record encodes a record header and its data.
+func record(typ recordType, data []byte) []byte {
+	res := make([]byte, 4, 4+len(data))
+	binary.LittleEndian.PutUint16(res, uint16(typ))
+	binary.LittleEndian.PutUint16(res[2:], uint16(len(data)))
+	return append(res, data...)
+}
+
+// xlString encodes a compressed XLUnicodeString, without the cch field if
+// noCch is set.
+func xlString(s string, noCch bool) []byte {
+	var res []byte
+	if !noCch {
+		res = append(res, byte(len(s)), byte(len(s)>>8))
+	}
+	res = append(res, 0)
+	return append(res, s...)
+}
+
+func u16s(vals ...int) []byte {
+	res := make([]byte, 2*len(vals))
+	for i, v := range vals {
+		binary.LittleEndian.PutUint16(res[2*i:], uint16(v))
+	}
+	return res
+}
+
+func sxFDB(name string, flags, csxoper, catm int) []byte {
+	data := u16s(flags, 0, 0, 0, csxoper, 0, catm)
+	return record(RecTypeSXFDB, append(data, xlString(name, false)...))
+}
+
+func sxString(s string) []byte {
+	return record(RecTypeSXString, append(u16s(len(s)), xlString(s, true)...))
+}
+
+func sxNum(f float64) []byte {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, math.Float64bits(f))
+	return record(RecTypeSXNum, data)
+}
+
+func testPivotStream() []byte {
+	var raw []byte
+	for _, r := range [][]byte{
+		record(RecTypeSXDB, u16s(2, 0, 0, 0, 0, 3)),
+		// an indexed field with 2 items and a grouping item
+		sxFDB("Region", 0x0101, 1, 2),
+		sxString("East"),
+		sxString("West"),
+		sxString("East+West"),
+		// a field with inline values
+		sxFDB("Units", 0, 0, 0),
+		// a calculated field, which has no values in the records
+		sxFDB("Ratio", 0x4000, 0, 0),
+		record(RecTypeSXDBB, []byte{0}),
+		sxNum(10.5),
+		record(RecTypeSXDBB, []byte{1}),
+		record(RecTypeSXInt, u16s(7)),
+	} {
+		raw = append(raw, r...)
+	}
+	return raw
+}
+
+func TestParsePivotCache(t *testing.T) {
+	pc, err := parsePivotCache(pivotStream{id: 1, source: "Data!A1:B3"}, testPivotStream())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.ID != 1 || pc.Name != "PivotCache1" || pc.Source != "Data!A1:B3" {
+		t.Errorf("got cache %d %q from %q", pc.ID, pc.Name, pc.Source)
+	}
+	if want := []string{"Region", "Units", "Ratio"}; !reflect.DeepEqual(pc.Fields, want) {
+		t.Errorf("got fields %q, want %q", pc.Fields, want)
+	}
+	if want := [][]interface{}{{"East", "West"}, nil, nil}; !reflect.DeepEqual(pc.Items, want) {
+		t.Errorf("got items %v, want %v", pc.Items, want)
+	}
+
+	want := [][]string{{"Region", "Units"}, {"East", "10.5"}, {"West", "7"}}
+	var got [][]string
+	for pc.Records.Next() {
+		got = append(got, pc.Records.Strings())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got records %q, want %q", got, want)
+	}
+
+	if _, err := parsePivotCache(pivotStream{}, record(RecTypeSXFDB, u16s(0, 0))); err == nil {
+		t.Error("expected an error for a truncated SXFDB record")
+	}
+	if _, err := parsePivotCache(pivotStream{}, testPivotStream()[:20]); err == nil {
+		t.Error("expected an error for a truncated stream")
+	}
+}
+
+func TestDecodeSXOper(t *testing.T) {
+	tests := []struct {
+		recType recordType
+		data    []byte
+		want    interface{}
+	}{
+		{RecTypeSXNum, sxNum(-2.25)[4:], -2.25},
+		{RecTypeSxBool, u16s(1), true},
+		{RecTypeSxErr, u16s(0x07), "#DIV/0!"},
+		{RecTypeSXInt, u16s(-3), -3},
+		{RecTypeSXString, sxString("abc")[4:], "abc"},
+		{RecTypeSXString, u16s(0xFFFF), nil},
+		{RecTypeSXDtr, []byte{0xD5, 0x07, 3, 0, 14, 9, 26, 53},
+			time.Date(2005, 3, 14, 9, 26, 53, 0, time.UTC)},
+		{RecTypeSxNil, nil, nil},
+		{RecTypeSXNum, []byte{1, 2}, nil},
+	}
+	for _, tc := range tests {
+		if got := decodeSXOper(tc.recType, tc.data); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("decodeSXOper(%d, % x) = %#v, want %#v", tc.recType, tc.data, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package xls
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// pivotView collects the records describing a pivot table (section 2.1.7.20.5)
+// until the table can be resolved against its pivot cache.
+type pivotView struct {
+	pt     *commonxl.PivotTable
+	iCache int
+	cDimRw int
+
+	fieldNames []string
+	nIvd       int
+	rows, cols []int
+	pages      []int
+	data       []pivotDataItem
+}
+
+type pivotDataItem struct {
+	name     string
+	field    int
+	function int
+}
+
+// parseSxView decodes the SxView record (section 2.4.313) which starts a pivot table.
+func parseSxView(raw []byte) *pivotView {
+	if len(raw) < 44 {
+		return nil
+	}
+	le := binary.LittleEndian
+	pv := &pivotView{
+		pt: &commonxl.PivotTable{
+			Location: commonxl.CellRange{
+				FirstRow: int(le.Uint16(raw)),
+				LastRow:  int(le.Uint16(raw[2:])),
+				FirstCol: int(le.Uint16(raw[4:])),
+				LastCol:  int(le.Uint16(raw[6:])),
+			},
+		},
+		iCache: int(le.Uint16(raw[14:])),
+		cDimRw: int(le.Uint16(raw[24:])),
+	}
+	cchName := int(le.Uint16(raw[40:]))
+	if cchName != 0xFFFF {
+		pv.pt.Name = decodeXLUnicodeStringNoCch(raw[44:], cchName)
+	}
+	return pv
+}
+
+// addRecord adds the contents of the pivot table records which follow SxView.
+func (pv *pivotView) addRecord(r *rec) {
+	le := binary.LittleEndian
+	raw := r.Data
+	switch r.RecType {
+	case RecTypeSxvd:
+		name := ""
+		if len(raw) >= 10 {
+			cchName := int(le.Uint16(raw[8:]))
+			if cchName != 0xFFFF {
+				name = decodeXLUnicodeStringNoCch(raw[10:], cchName)
+			}
+		}
+		pv.fieldNames = append(pv.fieldNames, name)
+
+	case RecTypeSxIvd:
+		var fields []int
+		for ; len(raw) >= 2; raw = raw[2:] {
+			fields = append(fields, int(int16(le.Uint16(raw))))
+		}
+		if pv.nIvd == 0 && pv.cDimRw > 0 {
+			pv.rows = fields
+		} else {
+			pv.cols = fields
+		}
+		pv.nIvd++
+
+	case RecTypeSXPI:
+		for ; len(raw) >= 6; raw = raw[6:] {
+			pv.pages = append(pv.pages, int(int16(le.Uint16(raw))))
+		}
+
+	case RecTypeSXDI:
+		if len(raw) < 14 {
+			return
+		}
+		di := pivotDataItem{
+			field:    int(int16(le.Uint16(raw))),
+			function: int(le.Uint16(raw[2:])),
+		}
+		cchName := int(le.Uint16(raw[12:]))
+		if cchName != 0xFFFF {
+			di.name = decodeXLUnicodeStringNoCch(raw[14:], cchName)
+		}
+		pv.data = append(pv.data, di)
+	}
+}
+
+// resolve fills in the pivot table's field names from the view and cache.
+func (pv *pivotView) resolve(b *WorkBook) *commonxl.PivotTable {
+	var cache *commonxl.PivotCache
+	if pv.iCache < len(b.pivotStreams) {
+		pv.pt.CacheID = int(b.pivotStreams[pv.iCache].id)
+		caches, err := b.PivotCaches()
+		if err == nil && pv.iCache < len(caches) {
+			cache = caches[pv.iCache]
+		}
+	}
+
+	fieldName := func(i int) string {
+		if i >= 0 && i < len(pv.fieldNames) && pv.fieldNames[i] != "" {
+			return pv.fieldNames[i]
+		}
+		return cache.FieldName(i)
+	}
+	for _, i := range pv.rows {
+		pv.pt.RowFields = append(pv.pt.RowFields, fieldName(i))
+	}
+	for _, i := range pv.cols {
+		pv.pt.ColFields = append(pv.pt.ColFields, fieldName(i))
+	}
+	for _, i := range pv.pages {
+		pv.pt.PageFields = append(pv.pt.PageFields, fieldName(i))
+	}
+	for _, di := range pv.data {
+		df := commonxl.PivotDataField{
+			Name:  di.name,
+			Field: fieldName(di.field),
+		}
+		if di.function < len(commonxl.PivotFunctions) {
+			df.Function = commonxl.PivotFunctions[di.function]
+		}
+		if df.Name == "" && df.Function != "" {
+			df.Name = strings.ToUpper(df.Function[:1]) + df.Function[1:] + " of " + df.Field
+		}
+		pv.pt.DataFields = append(pv.pt.DataFields, df)
+	}
+	return pv.pt
+}
+
+// PivotTables returns the layout of the pivot tables on the sheet.
+func (s *WorkSheet) PivotTables() []*commonxl.PivotTable {
+	return s.pivotTables
+}
+
+// ExcludePivotTables blanks out the output areas of the pivot tables on the
+// sheet, so that only the remaining cells are returned during iteration.
+// It should be called before the first call to Next.
+func (s *WorkSheet) ExcludePivotTables() {
+	for _, pt := range s.pivotTables {
+		loc := pt.Location
+		for r := loc.FirstRow; r <= loc.LastRow && r < len(s.rows); r++ {
+			cols := s.rows[r].cols
+			for c := loc.FirstCol; c <= loc.LastCol && c < len(cols); c++ {
+				cols[c] = nil
+			}
+		}
+	}
+}
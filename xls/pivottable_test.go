@@ -0,0 +1,114 @@
+package xls
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// sxView builds an SxView record for a pivot table at the given location.
+func sxView(name string, loc commonxl.CellRange, iCache, cDimRw int) []byte {
+	raw := u16s(loc.FirstRow, loc.LastRow, loc.FirstCol, loc.LastCol)
+	raw = append(raw, make([]byte, 36)...)
+	copy(raw[14:], u16s(iCache))
+	copy(raw[24:], u16s(cDimRw))
+	copy(raw[40:], u16s(len(name)))
+	return append(raw, xlString(name, true)...)
+}
+
+func sxvd(name string) *rec {
+	data := u16s(0, 0, 0, 0, 0xFFFF)
+	if name != "" {
+		data = append(u16s(0, 0, 0, 0, len(name)), xlString(name, true)...)
+	}
+	return &rec{RecType: RecTypeSxvd, RecSize: uint16(len(data)), Data: data}
+}
+
+func sxdi(field, function int, name string) *rec {
+	data := u16s(field, function, 0, 0, 0, 0, 0xFFFF)
+	if name != "" {
+		data = append(u16s(field, function, 0, 0, 0, 0, len(name)), xlString(name, true)...)
+	}
+	return &rec{RecType: RecTypeSXDI, RecSize: uint16(len(data)), Data: data}
+}
+
+func sxRec(typ recordType, data []byte) *rec {
+	return &rec{RecType: typ, RecSize: uint16(len(data)), Data: data}
+}
+
+func TestParseSxView(t *testing.T) {
+	loc := commonxl.CellRange{FirstRow: 2, LastRow: 6, FirstCol: 1, LastCol: 3}
+	pv := parseSxView(sxView("Sales", loc, 0, 1))
+	if pv == nil {
+		t.Fatal("expected a pivot view")
+	}
+	if pv.pt.Name != "Sales" || pv.pt.Location != loc || pv.iCache != 0 || pv.cDimRw != 1 {
+		t.Errorf("got view %q at %v, cache %d, %d row fields", pv.pt.Name, pv.pt.Location, pv.iCache, pv.cDimRw)
+	}
+	if parseSxView(make([]byte, 43)) != nil {
+		t.Error("expected no view for a truncated record")
+	}
+
+	for _, r := range []*rec{
+		sxvd(""), sxvd(""), sxvd("Kind"),
+		sxRec(RecTypeSxIvd, u16s(0)),
+		sxRec(RecTypeSxIvd, u16s(0xFFFE)), // the data pseudo-field
+		sxRec(RecTypeSXPI, u16s(2, 0x7FFD, 1)),
+		sxdi(1, 0, ""),
+		sxdi(1, 1, "Orders"),
+	} {
+		pv.addRecord(r)
+	}
+
+	b := &WorkBook{
+		pivotStreams: []pivotStream{{id: 3}},
+		pivotCaches:  []*commonxl.PivotCache{{ID: 3, Fields: []string{"Region", "Units", "Type"}}},
+	}
+	want := &commonxl.PivotTable{
+		Name:       "Sales",
+		CacheID:    3,
+		Location:   loc,
+		RowFields:  []string{"Region"},
+		ColFields:  []string{commonxl.PivotValuesField},
+		PageFields: []string{"Kind"},
+		DataFields: []commonxl.PivotDataField{
+			{Name: "Sum of Units", Field: "Units", Function: "sum"},
+			{Name: "Orders", Field: "Units", Function: "count"},
+		},
+	}
+	if got := pv.resolve(b); !reflect.DeepEqual(got, want) {
+		t.Errorf("got pivot table %+v, want %+v", got, want)
+	}
+}
+
+func TestPivotViewNoRows(t *testing.T) {
+	// without row fields the first SxIvd lists the column fields, and
+	// without a cache the fields have placeholder names
+	pv := parseSxView(sxView("", commonxl.CellRange{}, 5, 0))
+	pv.addRecord(sxRec(RecTypeSxIvd, u16s(1, 0)))
+	pt := pv.resolve(&WorkBook{})
+	if pt.RowFields != nil || !reflect.DeepEqual(pt.ColFields, []string{"Field2", "Field1"}) || pt.CacheID != 0 {
+		t.Errorf("got pivot table %+v", pt)
+	}
+}
+
+func TestExcludePivotTables(t *testing.T) {
+	s := &WorkSheet{
+		rows: []*row{
+			{cols: []interface{}{"a", "b", "c"}},
+			{cols: []interface{}{"d", "e", "f"}},
+			{cols: []interface{}{"g", "h", "i"}},
+		},
+		pivotTables: []*commonxl.PivotTable{
+			{Location: commonxl.CellRange{FirstRow: 1, LastRow: 5, FirstCol: 1, LastCol: 9}},
+		},
+	}
+	s.ExcludePivotTables()
+	want := [][]interface{}{{"a", "b", "c"}, {"d", nil, nil}, {"g", nil, nil}}
+	for i, r := range s.rows {
+		if !reflect.DeepEqual(r.cols, want[i]) {
+			t.Errorf("got row %d %v, want %v", i, r.cols, want[i])
+		}
+	}
+}
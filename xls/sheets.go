@@ -10,6 +10,7 @@ import (
 	"unicode/utf16"
 
 	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
 )
 
 // List (visible) sheet names from the workbook.
@@ -38,7 +39,10 @@ func (b *WorkBook) ListHidden() ([]string, error) {
 func (b *WorkBook) Get(sheetName string) (grate.Collection, error) {
 	for _, s := range b.sheets {
 		if s.Name == sheetName {
-			ss := b.pos2substream[int64(s.Position)]
+			ss, ok := b.pos2substream[int64(s.Position)]
+			if !ok {
+				return nil, errors.New("xls: sheet substream not found")
+			}
 			ws := &WorkSheet{
 				b: b, s: s, ss: ss,
 				iterRow: -1,
@@ -63,6 +67,9 @@ type WorkSheet struct {
 	rows   []*row
 	empty  bool
 
+	condfmts    commonxl.CondFormats
+	pivotTables []*commonxl.PivotTable
+
 	iterRow int
 	iterMC  int
 }
@@ -178,6 +185,9 @@ func (s *WorkSheet) parse() error {
 	inSubstream = 0
 
 	var formulaRow, formulaCol uint16
+	var curCF *commonxl.CondFormat
+	cfPriority := 0
+	var views []*pivotView
 	for ridx, r := range s.b.substreams[s.ss] {
 		if inSubstream > 0 {
 			if r.RecType == RecTypeEOF {
@@ -493,6 +503,49 @@ func (s *WorkSheet) parse() error {
 					}
 				}
 			}
+
+		case RecTypeCondFmt, RecTypeCondFmt12:
+			// CF or CF12 records for these ranges will follow
+			raw := r.Data
+			if r.RecType == RecTypeCondFmt12 {
+				if len(raw) < 12 {
+					continue
+				}
+				raw = raw[12:] // skip FrtRefHeaderU
+			}
+			if len(raw) < 14 {
+				continue
+			}
+			curCF = &commonxl.CondFormat{
+				Ranges: decodeSqRef(raw[12:]),
+			}
+			s.condfmts = append(s.condfmts, curCF)
+
+		case RecTypeCF, RecTypeCF12:
+			if curCF == nil {
+				continue
+			}
+			cfPriority++
+			rule, err := s.b.parseCF(r, curCF, cfPriority)
+			if err != nil {
+				if grate.Debug {
+					log.Println("      Unable to parse conditional format:", err)
+				}
+				continue
+			}
+			curCF.Rules = append(curCF.Rules, rule)
+
+		case RecTypeSxView:
+			// pivot table layout, described by the records that follow
+			if pv := parseSxView(r.Data); pv != nil {
+				views = append(views, pv)
+			}
+
+		case RecTypeSxvd, RecTypeSxIvd, RecTypeSXPI, RecTypeSXDI:
+			if len(views) > 0 {
+				views[len(views)-1].addRecord(r)
+			}
+
 			/*
 				case RecTypeBlank, RecTypeMulBlank:
 					// cells default value is blank, no need for these
@@ -510,9 +563,32 @@ func (s *WorkSheet) parse() error {
 			*/
 		}
 	}
+
+	for _, pv := range views {
+		s.pivotTables = append(s.pivotTables, pv.resolve(s.b))
+	}
 	return nil
 }
 
+// ConditionalFormats returns the conditional formatting rules defined on the sheet.
+func (s *WorkSheet) ConditionalFormats() commonxl.CondFormats {
+	return s.condfmts
+}
+
+// MatchingRules returns the conditional formatting rules which apply to the
+// value in column col of the current row. Only the simple cell-value rules are
+// evaluated, see commonxl.CondRule.Evaluate for details.
+func (s *WorkSheet) MatchingRules(col int) []*commonxl.CondRule {
+	if s.iterRow < 0 || s.iterRow >= len(s.rows) {
+		return nil
+	}
+	currow := s.rows[s.iterRow]
+	if col < 0 || col >= len(currow.cols) {
+		return nil
+	}
+	return s.condfmts.Matching(s.iterRow, col, currow.cols[col])
+}
+
 // Err returns the last error that occured.
 func (s *WorkSheet) Err() error {
 	return s.err
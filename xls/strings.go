@@ -244,3 +244,43 @@ func parseSST(recs []*rec) ([]string, error) {
 
 	return all, nil
 }
+
+// built-in defined names, indexed by the Lbl record's character code.
+var builtinNames = []string{
+	"Consolidate_Area", "Auto_Open", "Auto_Close", "Extract", "Database",
+	"Criteria", "Print_Area", "Print_Titles", "Recorder", "Data_Form",
+	"Auto_Activate", "Auto_Deactivate", "Sheet_Title", "_FilterDatabase",
+}
+
+// 2.4.150
+func decodeLblName(raw []byte) string {
+	flags := binary.LittleEndian.Uint16(raw)
+	cch := int(raw[3])
+	name := raw[14:]
+	if len(name) == 0 {
+		return ""
+	}
+	fHighByte := name[0] & 1
+	name = name[1:]
+
+	us := make([]uint16, 0, cch)
+	for i := 0; i < cch; i++ {
+		if fHighByte == 0 {
+			if len(name) < 1 {
+				break
+			}
+			us = append(us, uint16(name[0]))
+			name = name[1:]
+		} else {
+			if len(name) < 2 {
+				break
+			}
+			us = append(us, binary.LittleEndian.Uint16(name))
+			name = name[2:]
+		}
+	}
+	if (flags&0x20) != 0 && len(us) == 1 && int(us[0]) < len(builtinNames) {
+		return builtinNames[us[0]]
+	}
+	return string(utf16.Decode(us))
+}
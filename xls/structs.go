@@ -105,3 +105,10 @@ func (r RKNumber) String() string {
 	}
 	return fmt.Sprint(r.Float64())
 }
+
+// XTI (section 2.5.345) maps a 3D reference to a range of sheets.
+type xti struct {
+	SupBook uint16 // index into the SupBook records
+	First   int16  // first sheet index (or -1/-2 for deleted/workbook-level)
+	Last    int16  // last sheet index
+}
@@ -0,0 +1,208 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// color returns the RRGGBB hex value of an indexed color, using the
+// workbook's custom palette if one was defined.
+func (b *WorkBook) color(icv int) string {
+	return commonxl.IndexedColor(b.palette, icv)
+}
+
+// parseDXFN decodes a DXFN structure (section 2.5.83) into a style, and
+// returns the number of bytes consumed.
+func (b *WorkBook) parseDXFN(raw []byte) (*commonxl.DiffStyle, int, error) {
+	if len(raw) < 6 {
+		return nil, 0, errors.New("xls: invalid DXFN structure")
+	}
+	le := binary.LittleEndian
+	flags := le.Uint32(raw)
+	fIfmtUser := (raw[4] & 1) != 0
+	n := 6
+
+	ds := &commonxl.DiffStyle{}
+	if (flags & (1 << 25)) != 0 { // ibitAtrNum
+		if fIfmtUser {
+			// DXFNumUsr (cb includes itself)
+			if len(raw) < n+2 {
+				return nil, 0, errors.New("xls: invalid DXFNumUsr structure")
+			}
+			cb := int(le.Uint16(raw[n:]))
+			if len(raw) < n+cb || cb < 5 {
+				return nil, 0, errors.New("xls: invalid DXFNumUsr structure")
+			}
+			s, _, err := decodeXLUnicodeString(raw[n+2 : n+cb])
+			if err != nil {
+				return nil, 0, err
+			}
+			ds.NumFmt = s
+			n += cb
+		} else {
+			// DXFNumIFmt
+			if len(raw) < n+2 {
+				return nil, 0, errors.New("xls: invalid DXFNumIFmt structure")
+			}
+			if code, ok := b.fmtCodes[uint16(raw[n+1])]; ok {
+				ds.NumFmt = code
+			} else {
+				ds.NumFmt = commonxl.BuiltInFormat(uint16(raw[n+1]))
+			}
+			n += 2
+		}
+	}
+	if (flags & (1 << 26)) != 0 { // ibitAtrFnt
+		if len(raw) < n+118 {
+			return nil, 0, errors.New("xls: invalid DXFFntD structure")
+		}
+		fnt := raw[n : n+118]
+		style := le.Uint32(fnt[68:])
+		weight := le.Uint16(fnt[72:])
+		underline := fnt[76]
+		icv := le.Uint32(fnt[80:])
+		flags1 := le.Uint32(fnt[88:])
+		flags3 := le.Uint32(fnt[96:])
+
+		if (flags1 & 0x02) == 0 {
+			ds.Italic = (style & 0x02) != 0
+		}
+		if (flags1 & 0x80) == 0 {
+			ds.Strike = (style & 0x80) != 0
+		}
+		if (flags3 & 0x01) == 0 {
+			ds.Underline = underline != 0
+		}
+		ds.Bold = weight >= 700 && weight <= 1000
+		if icv != 0xFFFFFFFF {
+			ds.FontColor = b.color(int(icv))
+		}
+		n += 118
+	}
+	if (flags & (1 << 27)) != 0 { // ibitAtrAlc
+		n += 8
+	}
+	if (flags & (1 << 28)) != 0 { // ibitAtrBdr
+		n += 8
+	}
+	if (flags & (1 << 29)) != 0 { // ibitAtrPat
+		if len(raw) < n+4 {
+			return nil, 0, errors.New("xls: invalid DXFPat structure")
+		}
+		colors := le.Uint16(raw[n+2:])
+		if (flags & (1 << 18)) == 0 { // icvBNinch
+			ds.FillColor = b.color(int((colors >> 7) & 0x7F))
+		} else if (flags & (1 << 17)) == 0 { // icvFNinch
+			ds.FillColor = b.color(int(colors & 0x7F))
+		}
+		n += 4
+	}
+	if (flags & (1 << 30)) != 0 { // ibitAtrProt
+		n += 2
+	}
+	if n > len(raw) {
+		return nil, 0, errors.New("xls: truncated DXFN structure")
+	}
+	return ds, n, nil
+}
+
+// parseXFExtNoFRT applies the full-color extensions (section 2.5.279) to a style.
+func parseXFExtNoFRT(raw []byte, ds *commonxl.DiffStyle) {
+	if len(raw) < 8 {
+		return
+	}
+	le := binary.LittleEndian
+	cexts := int(le.Uint16(raw[6:]))
+	raw = raw[8:]
+	for i := 0; i < cexts && len(raw) >= 4; i++ {
+		extType := le.Uint16(raw)
+		cb := int(le.Uint16(raw[2:]))
+		if cb < 4 || cb > len(raw) {
+			return
+		}
+		data := raw[4:cb]
+		raw = raw[cb:]
+
+		// FullColorExt, only RGB colors are supported
+		if len(data) < 8 || le.Uint16(data) != 2 {
+			continue
+		}
+		rgb := fmt.Sprintf("%02X%02X%02X", data[4], data[5], data[6])
+		switch extType {
+		case 0x04, 0x05: // cell interior foreground/background color
+			ds.FillColor = rgb
+		case 0x0D: // text color
+			ds.FontColor = rgb
+		}
+	}
+}
+
+// parseXFProps decodes the XFProps structure (section 2.5.281) used by DXF records.
+func (b *WorkBook) parseXFProps(raw []byte) *commonxl.DiffStyle {
+	ds := &commonxl.DiffStyle{}
+	if len(raw) < 4 {
+		return ds
+	}
+	le := binary.LittleEndian
+	cprops := int(le.Uint16(raw[2:]))
+	raw = raw[4:]
+	for i := 0; i < cprops && len(raw) >= 4; i++ {
+		propType := le.Uint16(raw)
+		cb := int(le.Uint16(raw[2:]))
+		if cb < 4 || cb > len(raw) {
+			break
+		}
+		data := raw[4:cb]
+		raw = raw[cb:]
+		if len(data) == 0 {
+			continue
+		}
+
+		switch propType {
+		case 0x01, 0x02, 0x05: // foreground, background, text color
+			// XFPropColor (section 2.5.282)
+			if len(data) < 8 {
+				continue
+			}
+			var rgb string
+			switch data[0] >> 1 {
+			case 1: // indexed
+				rgb = b.color(int(data[1]))
+			case 2: // RGB
+				rgb = fmt.Sprintf("%02X%02X%02X", data[4], data[5], data[6])
+			default:
+				continue
+			}
+			if propType == 0x05 {
+				ds.FontColor = rgb
+			} else {
+				ds.FillColor = rgb
+			}
+		case 0x19: // font weight
+			if len(data) >= 2 {
+				ds.Bold = le.Uint16(data) >= 700
+			}
+		case 0x1A: // underline
+			if len(data) >= 2 {
+				ds.Underline = le.Uint16(data) != 0
+			}
+		case 0x1C: // italic
+			ds.Italic = data[0] != 0
+		case 0x1D: // strikethrough
+			ds.Strike = data[0] != 0
+		case 0x29: // number format ID
+			if len(data) >= 2 {
+				ifmt := le.Uint16(data)
+				if code, ok := b.fmtCodes[ifmt]; ok {
+					ds.NumFmt = code
+				} else {
+					ds.NumFmt = commonxl.BuiltInFormat(ifmt)
+				}
+			}
+		}
+	}
+	return ds
+}
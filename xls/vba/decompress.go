@@ -0,0 +1,73 @@
+package vba
+
+import "errors"
+
+// ErrInvalidCompression is returned when a compressed container is malformed.
+var ErrInvalidCompression = errors.New("vba: invalid compressed container")
+
+// Decompress expands data using the MS-OVBA compression format (section 2.4.1).
+func Decompress(data []byte) ([]byte, error) {
+	if len(data) < 1 || data[0] != 0x01 {
+		return nil, ErrInvalidCompression
+	}
+	data = data[1:]
+	out := make([]byte, 0, len(data)*2)
+
+	for len(data) >= 2 {
+		header := uint16(data[0]) | uint16(data[1])<<8
+		chunkSize := int(header&0x0FFF) + 3
+		if (header>>12)&0x07 != 0x03 {
+			return nil, ErrInvalidCompression
+		}
+		if chunkSize > len(data) {
+			// tolerate a truncated final chunk
+			chunkSize = len(data)
+		}
+		chunk := data[2:chunkSize]
+		data = data[chunkSize:]
+
+		if (header & 0x8000) == 0 {
+			// uncompressed chunk
+			out = append(out, chunk...)
+			continue
+		}
+
+		chunkStart := len(out)
+		for len(chunk) > 0 {
+			flags := chunk[0]
+			chunk = chunk[1:]
+			for bit := 0; bit < 8 && len(chunk) > 0; bit++ {
+				if (flags & (1 << bit)) == 0 {
+					// literal token
+					out = append(out, chunk[0])
+					chunk = chunk[1:]
+					continue
+				}
+
+				// copy token
+				if len(chunk) < 2 {
+					return nil, ErrInvalidCompression
+				}
+				token := uint16(chunk[0]) | uint16(chunk[1])<<8
+				chunk = chunk[2:]
+
+				diff := len(out) - chunkStart
+				bitCount := uint(4)
+				for (1 << bitCount) < diff {
+					bitCount++
+				}
+				lengthMask := uint16(0xFFFF) >> bitCount
+				length := int(token&lengthMask) + 3
+				offset := int(token>>(16-bitCount)) + 1
+				if offset > diff {
+					return nil, ErrInvalidCompression
+				}
+				src := len(out) - offset
+				for i := 0; i < length; i++ {
+					out = append(out, out[src+i])
+				}
+			}
+		}
+	}
+	return out, nil
+}
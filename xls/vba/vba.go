@@ -0,0 +1,222 @@
+// Package vba extracts the VBA macro project stored within Office documents,
+// as described by the Office VBA File Format Structure (MS-OVBA).
+package vba
+
+// https://docs.microsoft.com/en-us/openspecs/office_file_formats/ms-ovba/575462ba-bf67-4190-9fac-c275523c75fc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate/commontext"
+	"github.com/pbnjay/grate/xls/cfb"
+)
+
+// Module types.
+const (
+	ModuleProcedural = "procedural"
+	ModuleDocument   = "document"
+)
+
+// Project is a VBA project containing one or more modules.
+type Project struct {
+	Name     string
+	CodePage uint16
+	Modules  []*Module
+}
+
+// Module is a single VBA code module.
+type Module struct {
+	Name       string
+	StreamName string
+	// Type is ModuleProcedural for standard modules, or ModuleDocument for
+	// document, class and form modules.
+	Type string
+	// Source is the decompressed module source code.
+	Source string
+
+	textOffset uint32
+}
+
+// dir stream record identifiers (section 2.3.4.2).
+const (
+	dirProjectCodePage    = 0x0003
+	dirProjectName        = 0x0004
+	dirProjectVersion     = 0x0009
+	dirEnd                = 0x0010
+	dirModuleName         = 0x0019
+	dirModuleStreamName   = 0x001A
+	dirModuleTypeProc     = 0x0021
+	dirModuleTypeDocument = 0x0022
+	dirModuleTerminator   = 0x002B
+	dirModuleOffset       = 0x0031
+)
+
+// ErrUnsupportedCodePage is returned when the text of a project is in a
+// codepage which cannot be decoded.
+var ErrUnsupportedCodePage = errors.New("vba: unsupported codepage")
+
+// Open reads the VBA project stored in the compound file doc. If the
+// project codepage is not supported, the project is returned along with
+// ErrUnsupportedCodePage, and its names and sources contain the raw bytes.
+func Open(doc *cfb.Document) (*Project, error) {
+	base := FindProject(doc)
+	if base == "" {
+		return nil, errors.New("vba: project not found")
+	}
+	rdr, err := doc.Open(base + "/dir")
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ioutil.ReadAll(rdr)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := Decompress(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := parseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	enc := codepageEncoding(p.CodePage)
+	for _, m := range p.Modules {
+		name := m.StreamName
+		if name == "" {
+			name = m.Name
+		}
+		rdr, err := doc.Open(base + "/" + name)
+		if err != nil {
+			// modules may legitimately be empty
+			continue
+		}
+		raw, err := ioutil.ReadAll(rdr)
+		if err != nil {
+			return nil, err
+		}
+		if int(m.textOffset) >= len(raw) {
+			continue
+		}
+		src, err := Decompress(raw[m.textOffset:])
+		if err != nil {
+			return nil, err
+		}
+		m.Source = decodeText(src, enc)
+	}
+	if enc == nil {
+		return p, fmt.Errorf("%w %d", ErrUnsupportedCodePage, p.CodePage)
+	}
+	return p, nil
+}
+
+// FindProject returns the path of the storage containing the VBA project
+// streams, e.g. "_VBA_PROJECT_CUR/VBA" in a .xls file or "VBA" in the
+// vbaProject.bin part of a .xlsm file. It returns an empty string if the
+// document does not contain a VBA project.
+func FindProject(doc *cfb.Document) string {
+	res := ""
+	doc.Walk(func(e *cfb.Entry) error {
+		if !e.IsStorage && strings.EqualFold(e.Name, "dir") &&
+			strings.EqualFold(path.Base(path.Dir(e.Path)), "VBA") {
+			res = path.Dir(e.Path)
+			return io.EOF
+		}
+		return nil
+	})
+	return res
+}
+
+// parseDir decodes the PROJECTINFORMATION and PROJECTMODULES records of a
+// decompressed dir stream (section 2.3.4.2).
+func parseDir(dir []byte) (*Project, error) {
+	p := &Project{CodePage: 1252}
+	enc := commontext.Windows1252
+	le := binary.LittleEndian
+	var cur *Module
+	for len(dir) >= 6 {
+		id := le.Uint16(dir)
+		size := int(le.Uint32(dir[2:]))
+		if id == dirProjectVersion {
+			// Reserved field is always 4, followed by major and minor versions
+			size = 6
+		}
+		dir = dir[6:]
+		if size > len(dir) {
+			return nil, errors.New("vba: invalid dir stream")
+		}
+		data := dir[:size]
+		dir = dir[size:]
+
+		switch id {
+		case dirProjectCodePage:
+			if len(data) >= 2 {
+				p.CodePage = le.Uint16(data)
+				enc = codepageEncoding(p.CodePage)
+			}
+		case dirProjectName:
+			p.Name = decodeText(data, enc)
+		case dirModuleName:
+			cur = &Module{Name: decodeText(data, enc), Type: ModuleProcedural}
+			p.Modules = append(p.Modules, cur)
+		case dirModuleStreamName:
+			if cur != nil {
+				cur.StreamName = decodeText(data, enc)
+			}
+		case dirModuleOffset:
+			if cur != nil && len(data) >= 4 {
+				cur.textOffset = le.Uint32(data)
+			}
+		case dirModuleTypeDocument:
+			if cur != nil {
+				cur.Type = ModuleDocument
+			}
+		case dirModuleTerminator:
+			cur = nil
+		}
+		if id == dirEnd {
+			break
+		}
+	}
+	return p, nil
+}
+
+// codepageEncoding returns the encoding of a project codepage, or nil if
+// it is not supported.
+func codepageEncoding(codepage uint16) *commontext.Encoding {
+	switch {
+	case codepage == 65001:
+		return commontext.UTF8
+	case codepage >= 1250 && codepage <= 1258:
+		return commontext.LookupEncoding("windows-" + strconv.Itoa(int(codepage)))
+	}
+	// OEM codepages
+	return commontext.LookupEncoding("ibm" + strconv.Itoa(int(codepage)))
+}
+
+// decodeText converts MBCS text in the project encoding to a string. If
+// the encoding is not supported, the text is returned as is.
+func decodeText(data []byte, enc *commontext.Encoding) string {
+	s := string(data)
+	if enc != nil && hasHighBytes(data) {
+		s = enc.Decode(data)
+	}
+	return strings.Replace(s, "\r\n", "\n", -1)
+}
+
+func hasHighBytes(data []byte) bool {
+	for _, c := range data {
+		if c >= 0x80 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package vba
+
+import "testing"
+
+func TestDecompress(t *testing.T) {
+	cases := []struct {
+		name   string
+		data   []byte
+		expect string
+	}{
+		{"literals", []byte{
+			0x01, 0x19, 0xB0,
+			0x00, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68,
+			0x00, 0x69, 0x6A, 0x6B, 0x6C, 0x6D, 0x6E, 0x6F, 0x70,
+			0x00, 0x71, 0x72, 0x73, 0x74, 0x75, 0x76, 0x2E,
+		}, "abcdefghijklmnopqrstuv."},
+		{"copy token", []byte{
+			0x01, 0x05, 0xB0,
+			0x08, 0x61, 0x62, 0x63, 0x03, 0x20,
+		}, "abcabcabc"},
+		{"overlapping copy", []byte{
+			0x01, 0x03, 0xB0,
+			0x02, 0x61, 0x07, 0x00,
+		}, "aaaaaaaaaaa"},
+	}
+	for _, c := range cases {
+		res, err := Decompress(c.data)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if string(res) != c.expect {
+			t.Errorf("%s: got %q, expected %q", c.name, res, c.expect)
+		}
+	}
+
+	if _, err := Decompress([]byte{0x00, 0x01, 0x02}); err != ErrInvalidCompression {
+		t.Errorf("expected invalid signature error, got %v", err)
+	}
+}
+
+func dirRecord(id uint16, data string) []byte {
+	n := len(data)
+	res := []byte{byte(id), byte(id >> 8), byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}
+	return append(res, data...)
+}
+
+func TestParseDir(t *testing.T) {
+	var dir []byte
+	dir = append(dir, dirRecord(dirProjectCodePage, "\xe4\x04")...)
+	dir = append(dir, dirRecord(dirProjectName, "VBAProject")...)
+	// PROJECTVERSION has a fixed size
+	dir = append(dir, 0x09, 0x00, 0x04, 0x00, 0x00, 0x00, 1, 0, 0, 0, 2, 0)
+	dir = append(dir, dirRecord(dirModuleName, "ThisWorkbook")...)
+	dir = append(dir, dirRecord(dirModuleStreamName, "ThisWorkbook")...)
+	dir = append(dir, dirRecord(0x0032, "T\x00h\x00i\x00s\x00")...)
+	dir = append(dir, dirRecord(dirModuleOffset, "\x10\x02\x00\x00")...)
+	dir = append(dir, dirRecord(dirModuleTypeDocument, "")...)
+	dir = append(dir, dirRecord(dirModuleTerminator, "")...)
+	dir = append(dir, dirRecord(dirModuleName, "Module1")...)
+	dir = append(dir, dirRecord(dirModuleStreamName, "Module1")...)
+	dir = append(dir, dirRecord(dirModuleTypeProc, "")...)
+	dir = append(dir, dirRecord(dirModuleTerminator, "")...)
+	dir = append(dir, dirRecord(dirEnd, "")...)
+
+	p, err := parseDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "VBAProject" || p.CodePage != 1252 || len(p.Modules) != 2 {
+		t.Fatalf("unexpected project %+v", p)
+	}
+	m := p.Modules[0]
+	if m.Name != "ThisWorkbook" || m.Type != ModuleDocument || m.textOffset != 0x210 {
+		t.Errorf("unexpected module %+v", m)
+	}
+	m = p.Modules[1]
+	if m.Name != "Module1" || m.StreamName != "Module1" || m.Type != ModuleProcedural {
+		t.Errorf("unexpected module %+v", m)
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	cases := []struct {
+		codepage uint16
+		data     string
+		expect   string
+	}{
+		{1252, "\x80 caf\xe9\r\nok", "€ café\nok"},
+		{1251, "\xcf\xf0\xe8\xe2\xe5\xf2", "Привет"},
+		{1250, "\x8a\xe8", "Šč"},
+		{866, "\x8f\xe0", "Пр"},
+		{65001, "caf\xc3\xa9", "café"},
+		// unsupported codepages are left as is
+		{932, "\x83\x65", "\x83\x65"},
+	}
+	for _, c := range cases {
+		if got := decodeText([]byte(c.data), codepageEncoding(c.codepage)); got != c.expect {
+			t.Errorf("codepage %d: got %q, expected %q", c.codepage, got, c.expect)
+		}
+	}
+
+	var dir []byte
+	dir = append(dir, dirRecord(dirProjectCodePage, "\xe3\x04")...)
+	dir = append(dir, dirRecord(dirModuleName, "\xcc\xee\xe4\xf3\xeb\xfc1")...)
+	dir = append(dir, dirRecord(dirEnd, "")...)
+	p, err := parseDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.CodePage != 1251 || len(p.Modules) != 1 || p.Modules[0].Name != "Модуль1" {
+		t.Errorf("unexpected project %+v", p)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"sync"
@@ -41,16 +42,37 @@ type WorkBook struct {
 	fpos          int64
 	pos2substream map[int64]int
 
-	nfmt commonxl.Formatter
-	xfs  []uint16
+	nfmt     commonxl.Formatter
+	xfs      []uint16
+	fmtCodes map[uint16]string
+
+	xtis    []xti
+	names   []string
+	palette []string
+	dxfs    []*commonxl.DiffStyle
+
+	pivotStreams []pivotStream
+	pivotCaches  []*commonxl.PivotCache
 }
 
 func (b *WorkBook) IsProtected() bool {
 	return b.prot
 }
 
+// DiffStyles returns the differential formats defined in the workbook.
+func (b *WorkBook) DiffStyles() []*commonxl.DiffStyle {
+	return b.dxfs
+}
+
 func Open(filename string) (grate.Source, error) {
-	doc, err := cfb.Open(filename)
+	doc, raw, err := openWorkbookStream(filename, cfb.Options{})
+	if err != nil && !errors.Is(err, grate.ErrNotInFormat) {
+		// salvage what we can from a damaged file
+		if grate.Debug {
+			log.Println("  Retrying in recovery mode:", err)
+		}
+		doc, raw, err = openWorkbookStream(filename, cfb.Options{Recover: true})
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -61,19 +83,46 @@ func Open(filename string) (grate.Source, error) {
 
 		pos2substream: make(map[int64]int, 16),
 		xfs:           make([]uint16, 0, 128),
+		fmtCodes:      make(map[uint16]string),
 	}
 
+	if err = b.loadFromStream(raw); err != nil {
+		doc.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// openWorkbookStream opens the compound file and reads the Workbook stream.
+// The Workbook stream is read into memory in full, as its records are kept
+// for the life of the workbook, so only the other streams of the file (such
+// as pivot caches and the VBA project) are read from disk on demand.
+func openWorkbookStream(filename string, opts cfb.Options) (*cfb.Document, []byte, error) {
+	doc, err := cfb.OpenWithOptions(filename, opts)
+	if err != nil {
+		return nil, nil, err
+	}
 	rdr, err := doc.Open("Workbook")
 	if err != nil {
-		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+		doc.Close()
+		return nil, nil, grate.WrapErr(err, grate.ErrNotInFormat)
 	}
 	raw, err := io.ReadAll(rdr)
 	if err != nil {
-		return nil, err
+		doc.Close()
+		return nil, nil, err
 	}
+	return doc, raw, nil
+}
 
-	err = b.loadFromStream(raw)
-	return b, err
+// BadSectors returns the damaged sectors of the file which were skipped in
+// order to open the workbook. A workbook with bad sectors may be missing
+// sheets or data.
+func (b *WorkBook) BadSectors() []uint32 {
+	if b.doc == nil {
+		return nil
+	}
+	return b.doc.BadSectors()
 }
 
 func (b *WorkBook) loadFromStream(raw []byte) error {
@@ -169,7 +218,10 @@ func (b *WorkBook) Close() error {
 		b.substreams[i] = b.substreams[i][:0]
 	}
 	b.substreams = b.substreams[:0]
-	return nil
+	if b.doc == nil {
+		return nil
+	}
+	return b.doc.Close()
 }
 
 func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
@@ -228,7 +280,8 @@ func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
 		b.substreams[substr] = append(b.substreams[substr], nr)
 		nr, no, err = b.nextRecord(raw)
 	}
-	if err == io.EOF {
+	if err == io.EOF || (err == io.ErrUnexpectedEOF && len(b.BadSectors()) > 0) {
+		// a damaged stream may end with a partial record
 		err = nil
 	}
 	if err != nil {
@@ -304,6 +357,7 @@ func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
 					return err
 				}
 				b.nfmt.Add(fmtNo, formatStr)
+				b.fmtCodes[fmtNo] = formatStr
 
 			case RecTypeXF:
 				// XF records merge multiple style and format directives to one ID
@@ -324,6 +378,71 @@ func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
 					return err
 				}
 				b.sheets = append(b.sheets, bs)
+
+			case RecTypeExternSheet:
+				// list of sheet ranges used by 3D references in formulas
+				if ss != 0 || len(nr.Data) < 2 {
+					continue
+				}
+				cxti := int(binary.LittleEndian.Uint16(nr.Data))
+				raw := nr.Data[2:]
+				for j := 0; j < cxti && len(raw) >= 6; j++ {
+					b.xtis = append(b.xtis, xti{
+						SupBook: binary.LittleEndian.Uint16(raw),
+						First:   int16(binary.LittleEndian.Uint16(raw[2:])),
+						Last:    int16(binary.LittleEndian.Uint16(raw[4:])),
+					})
+					raw = raw[6:]
+				}
+
+			case RecTypeLbl:
+				// defined names, referenced by index in formulas
+				if ss != 0 || len(nr.Data) < 15 {
+					continue
+				}
+				b.names = append(b.names, decodeLblName(nr.Data))
+
+			case RecTypePalette:
+				// custom colors replacing the default palette entries 8-63
+				if ss != 0 || len(nr.Data) < 2 {
+					continue
+				}
+				ccv := int(binary.LittleEndian.Uint16(nr.Data))
+				raw := nr.Data[2:]
+				b.palette = make([]string, 0, ccv)
+				for j := 0; j < ccv && len(raw) >= 4; j++ {
+					b.palette = append(b.palette, fmt.Sprintf("%02X%02X%02X", raw[0], raw[1], raw[2]))
+					raw = raw[4:]
+				}
+
+			case RecTypeSXStreamID:
+				// pivot cache stream, followed by a record describing its source
+				if ss != 0 || len(nr.Data) < 2 {
+					continue
+				}
+				b.pivotStreams = append(b.pivotStreams, pivotStream{id: binary.LittleEndian.Uint16(nr.Data)})
+
+			case RecTypeDConRef, RecTypeDConName:
+				if ss != 0 || len(b.pivotStreams) == 0 {
+					continue
+				}
+				ps := &b.pivotStreams[len(b.pivotStreams)-1]
+				if ps.source != "" {
+					continue
+				}
+				if nr.RecType == RecTypeDConRef {
+					ps.source = decodeDConRef(nr.Data)
+				} else {
+					ps.source, _ = decodeXLUnicodeStringSafe(nr.Data)
+				}
+
+			case RecTypeDXF:
+				// differential formats (used by table styles)
+				if ss != 0 || len(nr.Data) < 14 {
+					continue
+				}
+				b.dxfs = append(b.dxfs, b.parseXFProps(nr.Data[14:]))
+
 			default:
 				if grate.Debug && ss == 0 {
 					log.Println("    Unhandled record type:", nr.RecType, i)
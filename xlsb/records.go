@@ -0,0 +1,162 @@
+package xlsb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"unicode/utf16"
+)
+
+// record types used from [MS-XLSB] section 2.3.
+const (
+	brtRowHdr       = 0x0000
+	brtCellBlank    = 0x0001
+	brtCellRk       = 0x0002
+	brtCellError    = 0x0003
+	brtCellBool     = 0x0004
+	brtCellReal     = 0x0005
+	brtCellSt       = 0x0006
+	brtCellIsst     = 0x0007
+	brtFmlaString   = 0x0008
+	brtFmlaNum      = 0x0009
+	brtFmlaBool     = 0x000A
+	brtFmlaError    = 0x000B
+	brtSSTItem      = 0x0013
+	brtFmt          = 0x002C
+	brtXF           = 0x002F
+	brtCellRString  = 0x003E
+	brtWsDim        = 0x0094
+	brtWbProp       = 0x0099
+	brtBundleSh     = 0x009C
+	brtMergeCell    = 0x00B0
+	brtHLink        = 0x01EE
+	brtBeginCellXFs = 0x0269
+	brtEndCellXFs   = 0x026A
+)
+
+var errTruncated = errors.New("xlsb: truncated record")
+
+// recordReader reads BIFF12 records from a binary part. Each record starts
+// with a variable-length type (1-2 bytes) and size (1-4 bytes), where the
+// high bit of each byte indicates that another byte follows.
+type recordReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func newRecordReader(r io.Reader) *recordReader {
+	return &recordReader{r: bufio.NewReader(r)}
+}
+
+// next returns the type and contents of the next record. The data slice is
+// only valid until the following call.
+func (rr *recordReader) next() (uint16, []byte, error) {
+	rt, err := rr.varint(2)
+	if err != nil {
+		return 0, nil, err
+	}
+	size, err := rr.varint(4)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if cap(rr.buf) < int(size) {
+		rr.buf = make([]byte, size)
+	}
+	rr.buf = rr.buf[:size]
+	if _, err = io.ReadFull(rr.r, rr.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return uint16(rt), rr.buf, nil
+}
+
+func (rr *recordReader) varint(maxBytes int) (uint32, error) {
+	var v uint32
+	for i := 0; i < maxBytes; i++ {
+		b, err := rr.r.ReadByte()
+		if err != nil {
+			if i > 0 && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		v |= uint32(b&0x7F) << (7 * uint(i))
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	return v, nil
+}
+
+// wideString decodes an XLWideString (section 2.5.171) from the start of
+// data, and returns the string and the number of bytes consumed. A null
+// XLNullableWideString decodes as an empty string.
+func wideString(data []byte) (string, int, error) {
+	if len(data) < 4 {
+		return "", 0, errTruncated
+	}
+	cch := binary.LittleEndian.Uint32(data)
+	if cch == 0xFFFFFFFF {
+		return "", 4, nil
+	}
+	if uint64(len(data)-4) < uint64(cch)*2 {
+		return "", 0, errTruncated
+	}
+	us := make([]uint16, cch)
+	for i := range us {
+		us[i] = binary.LittleEndian.Uint16(data[4+i*2:])
+	}
+	return string(utf16.Decode(us)), 4 + int(cch)*2, nil
+}
+
+// rfx is a range of cells (section 2.5.153).
+type rfx struct {
+	rwFirst, rwLast, colFirst, colLast int
+}
+
+func readRfX(data []byte) (rfx, error) {
+	if len(data) < 16 {
+		return rfx{}, errTruncated
+	}
+	return rfx{
+		rwFirst:  int(binary.LittleEndian.Uint32(data)),
+		rwLast:   int(binary.LittleEndian.Uint32(data[4:])),
+		colFirst: int(binary.LittleEndian.Uint32(data[8:])),
+		colLast:  int(binary.LittleEndian.Uint32(data[12:])),
+	}, nil
+}
+
+// rkNumber decodes an RkNumber (section 2.5.122) to an int or float64.
+func rkNumber(rk uint32) interface{} {
+	var val float64
+	if rk&2 != 0 {
+		if rk&1 == 0 {
+			return int(int32(rk) >> 2)
+		}
+		val = float64(int32(rk) >> 2)
+	} else {
+		val = math.Float64frombits(uint64(rk&0xFFFFFFFC) << 32)
+	}
+	if rk&1 != 0 {
+		val /= 100.0
+	}
+	return val
+}
+
+var berrLookup = map[byte]string{
+	0x00: "#NULL!",
+	0x07: "#DIV/0!",
+	0x0F: "#VALUE!",
+	0x17: "#REF!",
+	0x1D: "#NAME?",
+	0x24: "#NUM!",
+	0x2A: "#N/A",
+	0x2B: "#GETTING_DATA",
+}
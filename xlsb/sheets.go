@@ -0,0 +1,213 @@
+package xlsb
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// limits of the cell grid, used when a sheet does not declare its dimensions
+const (
+	maxRows = 1 << 20
+	maxCols = 1 << 14
+)
+
+type sheetParser struct {
+	d     *Document
+	s     *commonxl.Sheet
+	dim   rfx
+	row   int
+	links map[string]string
+}
+
+func (d *Document) parseSheet(ref *sheetRef) (*commonxl.Sheet, error) {
+	p := &sheetParser{
+		d:     d,
+		s:     &commonxl.Sheet{},
+		dim:   rfx{rwLast: maxRows - 1, colLast: maxCols - 1},
+		links: make(map[string]string),
+	}
+	for id, rel := range d.PartRels(ref.docname) {
+		if rel.Type == relTypeHyperlink {
+			p.links[id] = rel.Target
+		}
+	}
+	if err := d.parsePart(ref.docname, p.record); err != nil {
+		return nil, err
+	}
+	return p.s, nil
+}
+
+func (p *sheetParser) record(rt uint16, data []byte) error {
+	switch rt {
+	case brtWsDim:
+		dim, err := readRfX(data)
+		if err != nil {
+			return err
+		}
+		if dim.rwLast < maxRows && dim.colLast < maxCols {
+			p.dim = dim
+			p.s.Resize(0, dim.colLast+1)
+		}
+		return nil
+
+	case brtRowHdr:
+		if len(data) < 4 {
+			return errTruncated
+		}
+		p.row = int(binary.LittleEndian.Uint32(data))
+		return nil
+
+	case brtMergeCell:
+		r, err := readRfX(data)
+		if err != nil {
+			return err
+		}
+		if r.rwLast > p.dim.rwLast || r.colLast > p.dim.colLast {
+			return nil
+		}
+		p.s.Merge(commonxl.CellRange{
+			FirstRow: r.rwFirst, LastRow: r.rwLast,
+			FirstCol: r.colFirst, LastCol: r.colLast,
+		})
+		return nil
+
+	case brtHLink:
+		return p.hyperlink(data)
+
+	case brtCellBlank, brtCellRk, brtCellError, brtCellBool, brtCellReal,
+		brtCellSt, brtCellIsst, brtCellRString,
+		brtFmlaString, brtFmlaNum, brtFmlaBool, brtFmlaError:
+		return p.cell(rt, data)
+	}
+	return nil
+}
+
+// cell decodes a cell record, which starts with a Cell structure (section
+// 2.5.9) of the column and style index, followed by the value.
+func (p *sheetParser) cell(rt uint16, data []byte) error {
+	if len(data) < 8 {
+		return errTruncated
+	}
+	col := int(binary.LittleEndian.Uint32(data))
+	ixfe := int(binary.LittleEndian.Uint32(data[4:]) & 0xFFFFFF)
+	data = data[8:]
+
+	var val interface{}
+	switch rt {
+	case brtCellBlank:
+		return nil
+
+	case brtCellRk:
+		if len(data) < 4 {
+			return errTruncated
+		}
+		val = p.format(ixfe, rkNumber(binary.LittleEndian.Uint32(data)))
+
+	case brtCellReal, brtFmlaNum:
+		if len(data) < 8 {
+			return errTruncated
+		}
+		val = p.format(ixfe, math.Float64frombits(binary.LittleEndian.Uint64(data)))
+
+	case brtCellBool, brtFmlaBool:
+		if len(data) < 1 {
+			return errTruncated
+		}
+		// FIXME: apply the ixfe format
+		val = data[0] != 0
+
+	case brtCellError, brtFmlaError:
+		if len(data) < 1 {
+			return errTruncated
+		}
+		be, ok := berrLookup[data[0]]
+		if !ok {
+			be = "<unknown error>"
+		}
+		val = be
+
+	case brtCellSt, brtFmlaString:
+		s, _, err := wideString(data)
+		if err != nil {
+			return err
+		}
+		val = s
+
+	case brtCellRString:
+		if len(data) < 1 {
+			return errTruncated
+		}
+		s, _, err := wideString(data[1:])
+		if err != nil {
+			return err
+		}
+		val = s
+
+	case brtCellIsst:
+		if len(data) < 4 {
+			return errTruncated
+		}
+		isst := int(binary.LittleEndian.Uint32(data))
+		if isst >= len(p.d.strings) {
+			return nil
+		}
+		val = p.d.strings[isst]
+	}
+	p.put(p.row, col, val)
+	return nil
+}
+
+func (p *sheetParser) format(ixfe int, val interface{}) interface{} {
+	var fno uint16
+	if ixfe < len(p.d.xfs) {
+		fno = p.d.xfs[ixfe]
+	}
+	s, _ := p.d.fmt.Apply(fno, val)
+	return s
+}
+
+// hyperlink decodes a BrtHLink record: the cell range, relationship id of
+// the target, location within the target, tooltip and display text.
+func (p *sheetParser) hyperlink(data []byte) error {
+	r, err := readRfX(data)
+	if err != nil {
+		return err
+	}
+	off := 16
+	var fields [4]string
+	for i := range fields {
+		s, n, err := wideString(data[off:])
+		if err != nil {
+			return err
+		}
+		fields[i] = s
+		off += n
+	}
+	link := p.links[fields[0]]
+	if fields[1] != "" {
+		link += "#" + fields[1]
+	}
+	if link == "" {
+		return nil
+	}
+
+	display := fields[3]
+	if s, ok := p.s.Get(r.rwFirst, r.colFirst).(string); ok && s != "" {
+		display = s
+	}
+	if display != "" {
+		link = display + " <" + link + ">"
+	}
+	p.put(r.rwFirst, r.colFirst, link)
+	return nil
+}
+
+func (p *sheetParser) put(row, col int, val interface{}) {
+	if row > p.dim.rwLast || col > p.dim.colLast {
+		// invalid
+		return
+	}
+	p.s.Put(row, col, val)
+}
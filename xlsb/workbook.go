@@ -0,0 +1,88 @@
+package xlsb
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+func (d *Document) parseWorkbook(rt uint16, data []byte) error {
+	switch rt {
+	case brtWbProp:
+		if len(data) < 4 {
+			return errTruncated
+		}
+		d.fmt.Mode1904(binary.LittleEndian.Uint32(data)&1 != 0)
+
+	case brtBundleSh:
+		// hsState, iTabID, strRelID, strName
+		if len(data) < 8 {
+			return errTruncated
+		}
+		relID, n, err := wideString(data[8:])
+		if err != nil {
+			return err
+		}
+		name, _, err := wideString(data[8+n:])
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return errors.New("xlsb: invalid sheet definition")
+		}
+		s := &sheetRef{
+			name:    name,
+			docname: d.Rels(relTypeWorksheet)[relID],
+		}
+		if s.docname == "" {
+			s.docname = d.Rels(relTypeChartsheet)[relID]
+		}
+		d.sheets = append(d.sheets, s)
+	}
+	return nil
+}
+
+func (d *Document) parseStyles(rt uint16, data []byte) error {
+	switch rt {
+	case brtFmt:
+		if len(data) < 2 {
+			return errTruncated
+		}
+		code, _, err := wideString(data[2:])
+		if err != nil {
+			return err
+		}
+		// built-in formats cannot be replaced, so errors are ignored
+		d.fmt.Add(binary.LittleEndian.Uint16(data), code)
+
+	case brtBeginCellXFs:
+		d.inCellXFs = true
+	case brtEndCellXFs:
+		d.inCellXFs = false
+	case brtXF:
+		// cell style XFs use the same record, but are not referenced by cells
+		if !d.inCellXFs {
+			return nil
+		}
+		if len(data) < 4 {
+			return errTruncated
+		}
+		d.xfs = append(d.xfs, binary.LittleEndian.Uint16(data[2:]))
+	}
+	return nil
+}
+
+func (d *Document) parseSharedStrings(rt uint16, data []byte) error {
+	if rt != brtSSTItem {
+		return nil
+	}
+	if len(data) < 1 {
+		return errTruncated
+	}
+	// RichStr: flags, then the plain text of the string
+	s, _, err := wideString(data[1:])
+	if err != nil {
+		return err
+	}
+	d.strings = append(d.strings, s)
+	return nil
+}
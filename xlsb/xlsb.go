@@ -0,0 +1,129 @@
+// Package xlsb reads Excel Binary Workbook (.xlsb) files. These use the same
+// Open Packaging Conventions container as .xlsx, but store the workbook,
+// worksheets, shared strings and styles as BIFF12 binary records.
+package xlsb
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+	"github.com/pbnjay/grate/xlsx"
+)
+
+var _ = grate.Register("xlsb", 6, Open)
+
+const (
+	relTypeWorksheet     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"
+	relTypeChartsheet    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/chartsheet"
+	relTypeStyles        = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+	relTypeSharedStrings = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings"
+	relTypeHyperlink     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+
+	binaryWorkbookContentType = "application/vnd.ms-excel.sheet.binary.macroEnabled.main"
+)
+
+// Document contains an Excel Binary Workbook.
+type Document struct {
+	*xlsx.Package
+
+	sheets  []*sheetRef
+	strings []string
+	xfs     []uint16
+	fmt     commonxl.Formatter
+
+	inCellXFs bool
+}
+
+type sheetRef struct {
+	name    string
+	docname string
+}
+
+// Open an Excel Binary Workbook.
+func Open(filename string) (grate.Source, error) {
+	p, err := xlsx.OpenPackage(filename)
+	if err != nil {
+		return nil, err
+	}
+	ct := p.ContentType(p.PrimaryDoc())
+	if !strings.EqualFold(ct, binaryWorkbookContentType) &&
+		!(ct == "" && strings.HasSuffix(p.PrimaryDoc(), ".bin")) {
+		p.Close()
+		return nil, grate.ErrNotInFormat
+	}
+	d := &Document{Package: p}
+
+	err = d.parsePart(p.PrimaryDoc(), d.parseWorkbook)
+	for _, fn := range p.Rels(relTypeStyles) {
+		if err == nil {
+			err = d.parsePart(fn, d.parseStyles)
+		}
+	}
+	for _, fn := range p.Rels(relTypeSharedStrings) {
+		if err == nil {
+			err = d.parsePart(fn, d.parseSharedStrings)
+		}
+	}
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// parsePart calls fn for every record in the named binary part.
+func (d *Document) parsePart(name string, fn func(rt uint16, data []byte) error) error {
+	rc, err := d.Open(name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	rr := newRecordReader(rc)
+	for {
+		rt, data, err := rr.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(rt, data); err != nil {
+			return err
+		}
+	}
+}
+
+// Close the workbook.
+func (d *Document) Close() error {
+	d.sheets = nil
+	d.strings = nil
+	d.xfs = nil
+	return d.Package.Close()
+}
+
+// List returns the names of the sheets in the workbook.
+func (d *Document) List() ([]string, error) {
+	res := make([]string, 0, len(d.sheets))
+	for _, s := range d.sheets {
+		res = append(res, s.name)
+	}
+	return res, nil
+}
+
+// Get the named sheet.
+func (d *Document) Get(sheetName string) (grate.Collection, error) {
+	for _, s := range d.sheets {
+		if s.name == sheetName {
+			sheet, err := d.parseSheet(s)
+			if err != nil {
+				return nil, err
+			}
+			return sheet, nil
+		}
+	}
+	return nil, errors.New("xlsb: sheet not found")
+}
@@ -0,0 +1,179 @@
+package xlsb
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/pbnjay/grate"
+)
+
+// rec encodes a BIFF12 record from its type and fields.
+func rec(rt uint16, fields ...interface{}) []byte {
+	var body bytes.Buffer
+	for _, f := range fields {
+		switch v := f.(type) {
+		case string:
+			u := utf16.Encode([]rune(v))
+			binary.Write(&body, binary.LittleEndian, uint32(len(u)))
+			binary.Write(&body, binary.LittleEndian, u)
+		default:
+			binary.Write(&body, binary.LittleEndian, v)
+		}
+	}
+	var res []byte
+	for v := uint32(rt); ; v >>= 7 {
+		if v < 0x80 {
+			res = append(res, byte(v))
+			break
+		}
+		res = append(res, byte(v&0x7F)|0x80)
+	}
+	for v := uint32(body.Len()); ; v >>= 7 {
+		if v < 0x80 {
+			res = append(res, byte(v))
+			break
+		}
+		res = append(res, byte(v&0x7F)|0x80)
+	}
+	return append(res, body.Bytes()...)
+}
+
+func cat(recs ...[]byte) []byte {
+	return bytes.Join(recs, nil)
+}
+
+func writeTestFile(t *testing.T, parts map[string][]byte) string {
+	fn := filepath.Join(t.TempDir(), "test.xlsb")
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	for name, data := range parts {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err = z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return fn
+}
+
+func TestOpen(t *testing.T) {
+	const rels = `<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`
+	const cell = uint32(0) // style index 0
+
+	parts := map[string][]byte{
+		"[Content_Types].xml": []byte(`<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="bin" ContentType="application/vnd.ms-excel.sheet.binary.macroEnabled.main"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.bin" ContentType="application/vnd.ms-excel.worksheet"/></Types>`),
+		"_rels/.rels": []byte(rels + `<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.bin"/></Relationships>`),
+		"xl/_rels/workbook.bin.rels": []byte(rels +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.bin"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.bin"/>` +
+			`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings" Target="sharedStrings.bin"/></Relationships>`),
+		"xl/worksheets/_rels/sheet1.bin.rels": []byte(rels +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/" TargetMode="External"/></Relationships>`),
+		"xl/workbook.bin": cat(
+			rec(0x0083),
+			rec(brtWbProp, uint32(0), uint32(0), uint32(0)),
+			rec(brtBundleSh, uint32(0), uint32(1), "rId1", "Data"),
+			rec(0x0084),
+		),
+		"xl/styles.bin": cat(
+			rec(brtFmt, uint16(164), "0.000"),
+			rec(0x0272),
+			rec(brtXF, uint16(0xFFFF), uint16(14), uint32(0), uint32(0)),
+			rec(0x0273),
+			rec(brtBeginCellXFs, uint32(3)),
+			rec(brtXF, uint16(0), uint16(0), uint32(0), uint32(0)),
+			rec(brtXF, uint16(0), uint16(164), uint32(0), uint32(0)),
+			rec(brtXF, uint16(0), uint16(14), uint32(0), uint32(0)),
+			rec(brtEndCellXFs),
+		),
+		"xl/sharedStrings.bin": cat(
+			rec(0x009F, uint32(2), uint32(2)),
+			rec(brtSSTItem, uint8(0), "Name"),
+			rec(brtSSTItem, uint8(0), "Value"),
+			rec(0x00A0),
+		),
+		"xl/worksheets/sheet1.bin": cat(
+			rec(0x0081),
+			rec(brtWsDim, uint32(0), uint32(3), uint32(0), uint32(2)),
+			rec(brtRowHdr, uint32(0), uint32(0), uint16(0), uint16(0), uint8(0)),
+			rec(brtCellIsst, uint32(0), cell, uint32(0)),
+			rec(brtCellIsst, uint32(1), cell, uint32(1)),
+			rec(brtCellSt, uint32(2), cell, "Link"),
+			rec(brtRowHdr, uint32(1), uint32(0), uint16(0), uint16(0), uint8(0)),
+			rec(brtCellSt, uint32(0), cell, "pi"),
+			rec(brtCellReal, uint32(1), uint32(1), math.Pi),
+			rec(brtCellBool, uint32(2), cell, uint8(1)),
+			rec(brtRowHdr, uint32(2), uint32(0), uint16(0), uint16(0), uint8(0)),
+			rec(brtCellRk, uint32(0), cell, uint32(42<<2|2)),
+			rec(brtCellRk, uint32(1), cell, uint32(1234<<2|3)),
+			rec(brtFmlaError, uint32(2), cell, uint8(0x2A), uint16(0)),
+			rec(brtRowHdr, uint32(3), uint32(0), uint16(0), uint16(0), uint8(0)),
+			rec(brtCellRk, uint32(0), uint32(2), uint32(44927<<2|2)),
+			rec(brtMergeCell, uint32(3), uint32(3), uint32(0), uint32(2)),
+			rec(brtHLink, uint32(0), uint32(0), uint32(2), uint32(2), "rId1", "", "", ""),
+			rec(0x0082),
+		),
+	}
+	fn := writeTestFile(t, parts)
+
+	wb, err := Open(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wb.Close()
+
+	names, err := wb.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"Data"}) {
+		t.Fatalf("got sheets %v", names)
+	}
+	sheet, err := wb.Get("Data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]string{
+		{"Name", "Value", "Link <https://example.com/>"},
+		{"pi", "3.142", "true"},
+		{"42", "12.34", "#N/A"},
+		{"01-01-23", "→", "⇥"},
+	}
+	var got [][]string
+	for sheet.Next() {
+		got = append(got, sheet.Strings())
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got %q\nexpected %q", got, expect)
+	}
+	if _, err = wb.Get("Missing"); err == nil {
+		t.Fatal("expected an error for a missing sheet")
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	fn := writeTestFile(t, map[string][]byte{
+		"_rels/.rels": []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`),
+		"xl/_rels/workbook.xml.rels": []byte(`<Relationships/>`),
+	})
+	if _, err := Open(fn); err != grate.ErrNotInFormat {
+		t.Fatalf("expected ErrNotInFormat, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// Charts returns the charts embedded in the named sheet, or the chart
+// displayed by a chart sheet.
+func (d *Document) Charts(sheetName string) ([]*commonxl.Chart, error) {
+	for _, s := range d.sheets {
+		if s.name != sheetName {
+			continue
+		}
+		var res []*commonxl.Chart
+		for _, rel := range d.PartRels(s.docname) {
+			if rel.Type != relTypeDrawing {
+				continue
+			}
+			objs, err := d.parseDrawing(rel.Target)
+			if err != nil {
+				return nil, err
+			}
+			for _, obj := range objs {
+				if obj.relType != relTypeChart {
+					continue
+				}
+				ch, err := d.parseChart(obj.target)
+				if err != nil {
+					return nil, err
+				}
+				ch.Sheet = s.name
+				ch.Anchor = obj.anchor
+				res = append(res, ch)
+			}
+		}
+		return res, nil
+	}
+	return nil, errors.New("xlsx: sheet not found")
+}
+
+// chartTypeName converts a plot type element name (e.g. "bar3DChart") to
+// the chart type.
+func chartTypeName(elem string) string {
+	t := strings.TrimSuffix(elem, "Chart")
+	t = strings.TrimSuffix(t, "3D")
+	if t == "ofPie" {
+		return "pie"
+	}
+	return t
+}
+
+// parseChart decodes a chart part (section 21.2).
+func (d *Document) parseChart(docname string) (*commonxl.Chart, error) {
+	dec, clo, err := d.OpenXML(docname)
+	if err != nil {
+		return nil, err
+	}
+	defer clo.Close()
+
+	ch := &commonxl.Chart{}
+	var stack []string
+	within := func(name string) bool {
+		for _, s := range stack {
+			if s == name {
+				return true
+			}
+		}
+		return false
+	}
+	parent := func(n int) string {
+		if len(stack) <= n {
+			return ""
+		}
+		return stack[len(stack)-1-n]
+	}
+
+	var cur *commonxl.ChartSeries
+	ptIndex := 0
+	text := ""
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			name := v.Name.Local
+			switch {
+			case strings.HasSuffix(name, "Chart") && parent(0) == "plotArea":
+				if ch.Type == "" {
+					ch.Type = chartTypeName(name)
+				}
+			case name == "barDir" && ch.Type == "bar" && ch.Series == nil:
+				ax := getAttrs(v.Attr, "val")
+				if ax[0] == "col" {
+					ch.Type = "column"
+				}
+			case name == "ser":
+				cur = &commonxl.ChartSeries{}
+				ch.Series = append(ch.Series, cur)
+			case name == "pt":
+				ax := getAttrs(v.Attr, "idx")
+				ptIndex, _ = strconv.Atoi(ax[0])
+			}
+			stack = append(stack, name)
+			text = ""
+
+		case xml.CharData:
+			text += string(v)
+
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+			name := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			switch {
+			case name == "ser":
+				cur = nil
+			case name == "t" && within("title") && !within("ser") && !within("plotArea"):
+				// rich text chart title
+				ch.Title += text
+			case name == "v" && within("title") && !within("ser") && !within("plotArea"):
+				if ch.Title == "" {
+					ch.Title = text
+				}
+			case cur == nil:
+				// not within a series
+			case name == "f":
+				switch {
+				case within("tx"):
+					cur.NameRef = text
+				case within("cat") || within("xVal"):
+					cur.CategoriesRef = text
+				case within("val") || within("yVal"):
+					cur.ValuesRef = text
+				}
+			case name == "v":
+				var val interface{} = text
+				if within("numCache") || within("numLit") {
+					if f, err := strconv.ParseFloat(text, 64); err == nil {
+						val = f
+					}
+				}
+				switch {
+				case within("tx"):
+					cur.Name = text
+				case within("cat") || within("xVal"):
+					cur.Categories = placePoint(cur.Categories, ptIndex, val)
+				case within("val") || within("yVal"):
+					cur.Values = placePoint(cur.Values, ptIndex, val)
+				}
+			}
+			text = ""
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return ch, err
+}
+
+// maxChartPoints limits the cached data points of a series, as the point
+// indexes are not checked against the point count.
+const maxChartPoints = 1 << 20
+
+func placePoint(pts []interface{}, idx int, val interface{}) []interface{} {
+	if idx < 0 || idx >= maxChartPoints {
+		return pts
+	}
+	for len(pts) <= idx {
+		pts = append(pts, nil)
+	}
+	pts[idx] = val
+	return pts
+}
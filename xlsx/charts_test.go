@@ -0,0 +1,79 @@
+package xlsx
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+const chartPart = `<?xml version="1.0" encoding="UTF-8"?>` +
+	`<c:chartSpace xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" ` +
+	`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><c:chart>` +
+	`<c:title><c:tx><c:rich><a:p><a:r><a:t>Sales </a:t></a:r><a:r><a:t>2024</a:t></a:r></a:p></c:rich></c:tx></c:title>` +
+	`<c:plotArea><c:layout/>` +
+	`<c:barChart><c:barDir val="col"/><c:grouping val="clustered"/>` +
+	`<c:ser><c:idx val="0"/><c:order val="0"/>` +
+	`<c:tx><c:strRef><c:f>Sheet1!$B$1</c:f><c:strCache><c:ptCount val="1"/><c:pt idx="0"><c:v>Units</c:v></c:pt></c:strCache></c:strRef></c:tx>` +
+	`<c:cat><c:strRef><c:f>Sheet1!$A$2:$A$4</c:f><c:strCache><c:ptCount val="3"/>` +
+	`<c:pt idx="0"><c:v>East</c:v></c:pt><c:pt idx="2"><c:v>West</c:v></c:pt></c:strCache></c:strRef></c:cat>` +
+	`<c:val><c:numRef><c:f>Sheet1!$B$2:$B$4</c:f><c:numCache><c:formatCode>General</c:formatCode><c:ptCount val="3"/>` +
+	`<c:pt idx="0"><c:v>10.5</c:v></c:pt><c:pt idx="1"><c:v>7</c:v></c:pt><c:pt idx="2000000000"><c:v>1</c:v></c:pt>` +
+	`</c:numCache></c:numRef></c:val>` +
+	`</c:ser>` +
+	`<c:ser><c:idx val="1"/><c:order val="1"/><c:tx><c:v>Literal</c:v></c:tx>` +
+	`<c:val><c:numLit><c:ptCount val="1"/><c:pt idx="0"><c:v>3</c:v></c:pt></c:numLit></c:val></c:ser>` +
+	`<c:axId val="1"/><c:axId val="2"/></c:barChart>` +
+	`<c:lineChart><c:grouping val="standard"/><c:axId val="1"/><c:axId val="2"/></c:lineChart>` +
+	`<c:catAx><c:axId val="1"/><c:title><c:tx><c:rich><a:p><a:r><a:t>Region</a:t></a:r></a:p></c:rich></c:tx></c:title></c:catAx>` +
+	`</c:plotArea></c:chart></c:chartSpace>`
+
+func TestCharts(t *testing.T) {
+	parts := drawingParts()
+	parts["xl/charts/chart1.xml"] = chartPart
+	d, _ := openTestSheet(t, parts)
+
+	got, err := d.Charts("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*commonxl.Chart{{
+		Type:   "column",
+		Title:  "Sales 2024",
+		Sheet:  "Sheet1",
+		Anchor: &commonxl.CellRange{FirstRow: 10, LastRow: 20, FirstCol: 0, LastCol: 5},
+		Series: []*commonxl.ChartSeries{
+			{
+				Name:          "Units",
+				NameRef:       "Sheet1!$B$1",
+				CategoriesRef: "Sheet1!$A$2:$A$4",
+				ValuesRef:     "Sheet1!$B$2:$B$4",
+				Categories:    []interface{}{"East", nil, "West"},
+				Values:        []interface{}{10.5, 7.0},
+			},
+			{Name: "Literal", Values: []interface{}{3.0}},
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got charts %+v, want %+v", got, want)
+	}
+
+	if _, err := d.Charts("Sheet9"); err == nil {
+		t.Error("expected an error for an unknown sheet")
+	}
+}
+
+func TestChartTypeName(t *testing.T) {
+	for elem, want := range map[string]string{
+		"barChart":      "bar",
+		"bar3DChart":    "bar",
+		"line3DChart":   "line",
+		"ofPieChart":    "pie",
+		"doughnutChart": "doughnut",
+		"scatterChart":  "scatter",
+	} {
+		if got := chartTypeName(elem); got != want {
+			t.Errorf("chartTypeName(%q) = %q, want %q", elem, got, want)
+		}
+	}
+}
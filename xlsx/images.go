@@ -0,0 +1,173 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+const (
+	relTypeDrawing = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing"
+	relTypeImage   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+	relTypeChart   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/chart"
+)
+
+// Images returns the pictures and other media embedded in the workbook.
+// Pictures placed on a sheet are listed once for each placement, with the
+// sheet name and anchor range, and unplaced media files are listed without
+// an anchor.
+func (d *Document) Images() ([]*commonxl.Image, error) {
+	var res []*commonxl.Image
+	used := make(map[string]bool)
+	for _, s := range d.sheets {
+		for _, rel := range d.PartRels(s.docname) {
+			if rel.Type != relTypeDrawing {
+				continue
+			}
+			objs, err := d.parseDrawing(rel.Target)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range objs {
+				if p.relType != relTypeImage {
+					continue
+				}
+				data, err := d.ReadFile(p.target)
+				if err != nil {
+					continue
+				}
+				used[p.target] = true
+				res = append(res, &commonxl.Image{
+					Name:   path.Base(p.target),
+					Format: imageFormat(p.target),
+					Data:   data,
+					Sheet:  s.name,
+					Anchor: p.anchor,
+				})
+			}
+		}
+	}
+
+	for _, fn := range d.Files() {
+		if !strings.HasPrefix(fn, "xl/media/") || used[fn] {
+			continue
+		}
+		data, err := d.ReadFile(fn)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &commonxl.Image{
+			Name:   path.Base(fn),
+			Format: imageFormat(fn),
+			Data:   data,
+		})
+	}
+	return res, nil
+}
+
+// imageFormat returns the normalized image type from a file name.
+func imageFormat(name string) string {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+	switch ext {
+	case "jpg", "jpe":
+		return "jpeg"
+	case "tif":
+		return "tiff"
+	}
+	return ext
+}
+
+type drawingObject struct {
+	relType string
+	target  string
+	anchor  *commonxl.CellRange
+}
+
+// parseDrawing finds the pictures and charts in a drawing part (section 20.5)
+// along with their anchors.
+func (d *Document) parseDrawing(docname string) ([]drawingObject, error) {
+	rels := d.PartRels(docname)
+	dec, clo, err := d.OpenXML(docname)
+	if err != nil {
+		return nil, err
+	}
+	defer clo.Close()
+
+	var res []drawingObject
+	var anchor *commonxl.CellRange
+	var cur *int // current row or col being read
+	inFrom, inTo := false, false
+	var text string
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case "twoCellAnchor", "oneCellAnchor":
+				anchor = &commonxl.CellRange{}
+			case "absoluteAnchor":
+				anchor = nil
+			case "from":
+				inFrom = true
+			case "to":
+				inTo = true
+			case "col", "row":
+				if anchor == nil || (!inFrom && !inTo) {
+					continue
+				}
+				text = ""
+				switch {
+				case inFrom && v.Name.Local == "col":
+					cur = &anchor.FirstCol
+				case inFrom:
+					cur = &anchor.FirstRow
+				case v.Name.Local == "col":
+					cur = &anchor.LastCol
+				default:
+					cur = &anchor.LastRow
+				}
+			case "blip", "chart":
+				ax := getAttrs(v.Attr, "embed", "id")
+				id := ax[0]
+				if v.Name.Local == "chart" {
+					id = ax[1]
+				}
+				rel, ok := rels[id]
+				if !ok || rel.External || (rel.Type != relTypeImage && rel.Type != relTypeChart) {
+					continue
+				}
+				res = append(res, drawingObject{relType: rel.Type, target: rel.Target, anchor: anchor})
+			}
+		case xml.CharData:
+			if cur != nil {
+				text += string(v)
+			}
+		case xml.EndElement:
+			switch v.Name.Local {
+			case "col", "row":
+				if cur != nil {
+					*cur, _ = strconv.Atoi(strings.TrimSpace(text))
+					cur = nil
+				}
+			case "from":
+				inFrom = false
+				if anchor != nil {
+					// one cell anchors only cover the first cell
+					anchor.LastRow, anchor.LastCol = anchor.FirstRow, anchor.FirstCol
+				}
+			case "to":
+				inTo = false
+			case "twoCellAnchor", "oneCellAnchor", "absoluteAnchor":
+				anchor = nil
+			}
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return res, err
+}
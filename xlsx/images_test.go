@@ -0,0 +1,92 @@
+package xlsx
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+const (
+	drawingSheetRels = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="` + relsNS + `/drawing" Target="../drawings/drawing1.xml"/>` +
+		`</Relationships>`
+	drawingRels = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="` + relsNS + `/image" Target="../media/image1.png"/>` +
+		`<Relationship Id="rId2" Type="` + relsNS + `/image" Target="../media/image2.jpg"/>` +
+		`<Relationship Id="rId3" Type="` + relsNS + `/image" Target="http://example.com/a.png" TargetMode="External"/>` +
+		`<Relationship Id="rId4" Type="` + relsNS + `/chart" Target="../charts/chart1.xml"/>` +
+		`</Relationships>`
+	drawingPart = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<xdr:wsDr xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing" ` +
+		`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+		`xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" xmlns:r="` + relsNS + `">` +
+		`<xdr:twoCellAnchor editAs="oneCell">` +
+		`<xdr:from><xdr:col>1</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>2</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from>` +
+		`<xdr:to><xdr:col>4</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>9</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:to>` +
+		`<xdr:pic><xdr:blipFill><a:blip r:embed="rId1"/></xdr:blipFill></xdr:pic><xdr:clientData/></xdr:twoCellAnchor>` +
+		`<xdr:oneCellAnchor>` +
+		`<xdr:from><xdr:col>6</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>0</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from>` +
+		`<xdr:ext cx="100" cy="100"/>` +
+		`<xdr:pic><xdr:blipFill><a:blip r:embed="rId2"/></xdr:blipFill></xdr:pic><xdr:clientData/></xdr:oneCellAnchor>` +
+		`<xdr:absoluteAnchor><xdr:pos x="0" y="0"/><xdr:ext cx="100" cy="100"/>` +
+		`<xdr:pic><xdr:blipFill><a:blip r:embed="rId1"/></xdr:blipFill></xdr:pic>` +
+		`<xdr:pic><xdr:blipFill><a:blip r:embed="rId3"/></xdr:blipFill></xdr:pic><xdr:clientData/></xdr:absoluteAnchor>` +
+		`<xdr:twoCellAnchor>` +
+		`<xdr:from><xdr:col>0</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>10</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from>` +
+		`<xdr:to><xdr:col>5</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>20</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:to>` +
+		`<xdr:graphicFrame><a:graphic><a:graphicData><c:chart r:id="rId4"/></a:graphicData></a:graphic></xdr:graphicFrame>` +
+		`<xdr:clientData/></xdr:twoCellAnchor>` +
+		`</xdr:wsDr>`
+)
+
+// drawingParts returns the parts of a workbook with a drawing on its sheet.
+func drawingParts() map[string]string {
+	return map[string]string{
+		"xl/worksheets/sheet1.xml":            testSheet("A1", `<drawing r:id="rId1"/>`),
+		"xl/worksheets/_rels/sheet1.xml.rels": drawingSheetRels,
+		"xl/drawings/drawing1.xml":            drawingPart,
+		"xl/drawings/_rels/drawing1.xml.rels": drawingRels,
+		"xl/media/image1.png":                 "png data",
+		"xl/media/image2.jpg":                 "jpeg data",
+		"xl/media/image3.emf":                 "emf data",
+	}
+}
+
+func TestParseDrawing(t *testing.T) {
+	d, _ := openTestSheet(t, drawingParts())
+	got, err := d.parseDrawing("xl/drawings/drawing1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []drawingObject{
+		{relTypeImage, "xl/media/image1.png", &commonxl.CellRange{FirstRow: 2, LastRow: 9, FirstCol: 1, LastCol: 4}},
+		{relTypeImage, "xl/media/image2.jpg", &commonxl.CellRange{FirstRow: 0, LastRow: 0, FirstCol: 6, LastCol: 6}},
+		{relTypeImage, "xl/media/image1.png", nil},
+		{relTypeChart, "xl/charts/chart1.xml", &commonxl.CellRange{FirstRow: 10, LastRow: 20, FirstCol: 0, LastCol: 5}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got drawing objects %+v, want %+v", got, want)
+	}
+}
+
+func TestImages(t *testing.T) {
+	d, _ := openTestSheet(t, drawingParts())
+	got, err := d.Images()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*commonxl.Image{
+		{Name: "image1.png", Format: "png", Data: []byte("png data"), Sheet: "Sheet1",
+			Anchor: &commonxl.CellRange{FirstRow: 2, LastRow: 9, FirstCol: 1, LastCol: 4}},
+		{Name: "image2.jpg", Format: "jpeg", Data: []byte("jpeg data"), Sheet: "Sheet1",
+			Anchor: &commonxl.CellRange{FirstRow: 0, LastRow: 0, FirstCol: 6, LastCol: 6}},
+		{Name: "image1.png", Format: "png", Data: []byte("png data"), Sheet: "Sheet1"},
+		{Name: "image3.emf", Format: "emf", Data: []byte("emf data")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got images %+v, want %+v", got, want)
+	}
+}
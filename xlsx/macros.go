@@ -0,0 +1,73 @@
+package xlsx
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/pbnjay/grate/xls/cfb"
+	"github.com/pbnjay/grate/xls/vba"
+)
+
+const relTypeVBAProject = "http://schemas.microsoft.com/office/2006/relationships/vbaProject"
+
+// workbookContentTypes are the content types of the main workbook part
+// accepted by Open. Macro-enabled workbooks (.xlsm), templates (.xltx and
+// .xltm) and add-ins (.xlam) share the same structure.
+var workbookContentTypes = []string{
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.template.main+xml",
+	"application/vnd.ms-excel.sheet.macroEnabled.main+xml",
+	"application/vnd.ms-excel.template.macroEnabled.main+xml",
+	"application/vnd.ms-excel.addin.macroEnabled.main+xml",
+}
+
+// isWorkbookPart returns false if the primary document of the package is
+// not a SpreadsheetML workbook.
+func (d *Document) isWorkbookPart() bool {
+	ct := d.ContentType(d.primaryDoc)
+	for _, wct := range workbookContentTypes {
+		if strings.EqualFold(ct, wct) {
+			return true
+		}
+	}
+	if ct == "" || ct == "application/xml" || ct == "text/xml" {
+		// undeclared or generic content types are tolerated
+		return strings.HasSuffix(d.primaryDoc, ".xml")
+	}
+	return false
+}
+
+// vbaProjectPart returns the name of the VBA project part, if any.
+func (d *Document) vbaProjectPart() string {
+	for _, fn := range d.rels[relTypeVBAProject] {
+		return fn
+	}
+	return ""
+}
+
+// HasMacros returns true if the workbook contains a VBA project.
+func (d *Document) HasMacros() bool {
+	return d.vbaProjectPart() != ""
+}
+
+// Macros returns the VBA project stored in the workbook, or nil if the
+// workbook does not contain any macros.
+func (d *Document) Macros() (*vba.Project, error) {
+	fn := d.vbaProjectPart()
+	if fn == "" {
+		return nil, nil
+	}
+	data, err := d.ReadFile(fn)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc, err := cfb.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return vba.Open(doc)
+}
@@ -0,0 +1,258 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pbnjay/grate"
+)
+
+// Package is an Open Packaging Conventions (OPC) container: a ZIP archive
+// of parts linked together by relationships. It is the container format of
+// .xlsx as well as other Office Open XML documents (.xlsb, .docx).
+type Package struct {
+	f          *os.File
+	r          *zip.Reader
+	primaryDoc string
+
+	// type => id => filename
+	rels map[string]map[string]string
+}
+
+// OpenPackage opens an OPC package, and loads the relationships of the
+// package and its primary document.
+func OpenPackage(filename string) (*Package, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPackage(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+func newPackage(f *os.File) (*Package, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	z, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+	}
+	p := &Package{
+		f: f,
+		r: z,
+	}
+
+	p.rels = make(map[string]map[string]string, 4)
+
+	// parse the primary relationships
+	dec, c, err := p.OpenXML("_rels/.rels")
+	if err != nil {
+		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+	}
+	err = p.parseRels(dec, "")
+	c.Close()
+	if err != nil {
+		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+	}
+	if p.primaryDoc == "" {
+		return nil, errors.New("xlsx: invalid document")
+	}
+
+	// parse the secondary relationships to primary doc
+	base := filepath.Base(p.primaryDoc)
+	sub := strings.TrimSuffix(p.primaryDoc, base)
+	relfn := filepath.Join(sub, "_rels", base+".rels")
+	dec, c, err = p.OpenXML(relfn)
+	if err != nil {
+		return nil, err
+	}
+	err = p.parseRels(dec, sub)
+	c.Close()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Close the package file.
+func (p *Package) Close() error {
+	return p.f.Close()
+}
+
+// PrimaryDoc returns the name of the main document part, e.g. "xl/workbook.xml".
+func (p *Package) PrimaryDoc() string {
+	return p.primaryDoc
+}
+
+// Rels returns the targets of the package and primary document
+// relationships of the given type, keyed by relationship id.
+func (p *Package) Rels(relType string) map[string]string {
+	return p.rels[relType]
+}
+
+func (p *Package) parseRels(dec *xml.Decoder, basedir string) error {
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case "Relationships":
+				// container
+			case "Relationship":
+				vals := make(map[string]string, 5)
+				for _, a := range v.Attr {
+					vals[a.Name.Local] = a.Value
+				}
+				if _, ok := p.rels[vals["Type"]]; !ok {
+					p.rels[vals["Type"]] = make(map[string]string)
+				}
+				p.rels[vals["Type"]][vals["Id"]] = filepath.Join(basedir, vals["Target"])
+				if vals["Type"] == "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" {
+					p.primaryDoc = vals["Target"]
+				}
+			default:
+				if grate.Debug {
+					log.Println("      Unhandled relationship xml tag", v.Name.Local, v.Attr)
+				}
+			}
+		case xml.EndElement:
+			// not needed
+		default:
+			if grate.Debug {
+				log.Printf("      Unhandled relationship xml tokens %T %+v", tok, tok)
+			}
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return err
+}
+
+// PartRel is a relationship from an individual part to another part.
+type PartRel struct {
+	Type   string
+	Target string // full name of the target part, or the URL of an external target
+
+	External bool
+}
+
+// PartRels returns the relationships of an individual part, keyed by
+// relationship id.
+func (p *Package) PartRels(docname string) map[string]PartRel {
+	res := make(map[string]PartRel)
+	base := filepath.Base(docname)
+	sub := strings.TrimSuffix(docname, base)
+	dec, clo, err := p.OpenXML(filepath.Join(sub, "_rels", base+".rels"))
+	if err != nil {
+		// rels might not exist for every part
+		return res
+	}
+	defer clo.Close()
+
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		if v, ok := tok.(xml.StartElement); ok && v.Name.Local == "Relationship" {
+			ax := getAttrs(v.Attr, "Id", "Type", "Target", "TargetMode")
+			if ax[3] == "External" {
+				res[ax[0]] = PartRel{Type: ax[1], Target: ax[2], External: true}
+				continue
+			}
+			rel := PartRel{Type: ax[1], Target: filepath.Join(sub, ax[2])}
+			if strings.HasPrefix(ax[2], "/") {
+				rel.Target = strings.TrimPrefix(ax[2], "/")
+			}
+			res[ax[0]] = rel
+		}
+	}
+	return res
+}
+
+// ContentType returns the content type of the named part, as declared in
+// the package's [Content_Types].xml, or an empty string if it is unknown.
+func (p *Package) ContentType(name string) string {
+	dec, clo, err := p.OpenXML("[Content_Types].xml")
+	if err != nil {
+		return ""
+	}
+	defer clo.Close()
+
+	partName := "/" + strings.TrimPrefix(name, "/")
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+	res := ""
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		v, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch v.Name.Local {
+		case "Override":
+			ax := getAttrs(v.Attr, "PartName", "ContentType")
+			if strings.EqualFold(ax[0], partName) {
+				return ax[1]
+			}
+		case "Default":
+			ax := getAttrs(v.Attr, "Extension", "ContentType")
+			if strings.EqualFold(ax[0], ext) {
+				res = ax[1]
+			}
+		}
+	}
+	return res
+}
+
+// OpenXML opens the named part for XML decoding.
+func (p *Package) OpenXML(name string) (*xml.Decoder, io.Closer, error) {
+	if grate.Debug {
+		log.Println("    openXML", name)
+	}
+	rc, err := p.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return xml.NewDecoder(rc), rc, nil
+}
+
+// Open the named part.
+func (p *Package) Open(name string) (io.ReadCloser, error) {
+	for _, zf := range p.r.File {
+		if zf.Name == name {
+			return zf.Open()
+		}
+	}
+	return nil, io.EOF
+}
+
+// ReadFile returns the contents of the named part.
+func (p *Package) ReadFile(name string) ([]byte, error) {
+	rc, err := p.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// Files returns the names of all the parts in the package.
+func (p *Package) Files() []string {
+	res := make([]string, 0, len(p.r.File))
+	for _, zf := range p.r.File {
+		res = append(res, zf.Name)
+	}
+	return res
+}
@@ -0,0 +1,196 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// pivotCacheRef is a pivot cache listed in the workbook.
+type pivotCacheRef struct {
+	id      int
+	docname string
+}
+
+// PivotCaches returns the pivot caches stored in the workbook.
+func (d *Document) PivotCaches() ([]*commonxl.PivotCache, error) {
+	if d.pivotCaches != nil || len(d.pivotRefs) == 0 {
+		return d.pivotCaches, nil
+	}
+	for _, ref := range d.pivotRefs {
+		pc, err := d.parsePivotCache(ref)
+		if err != nil {
+			return nil, err
+		}
+		d.pivotCaches = append(d.pivotCaches, pc)
+	}
+	return d.pivotCaches, nil
+}
+
+// GetPivotCache returns the records of the named pivot cache, with the
+// field names in the first row.
+func (d *Document) GetPivotCache(name string) (grate.Collection, error) {
+	caches, err := d.PivotCaches()
+	if err != nil {
+		return nil, err
+	}
+	for _, pc := range caches {
+		if pc.Name == name {
+			pc.Records.Rewind()
+			return pc.Records, nil
+		}
+	}
+	return nil, errors.New("xlsx: pivot cache not found")
+}
+
+// pivotValue decodes a shared item or record value element (section 18.10.1).
+func pivotValue(v xml.StartElement) (interface{}, bool) {
+	ax := getAttrs(v.Attr, "v")
+	switch v.Name.Local {
+	case "s", "e":
+		return ax[0], true
+	case "n":
+		f, _ := strconv.ParseFloat(ax[0], 64)
+		return f, true
+	case "b":
+		return ax[0] == "1" || ax[0] == "true", true
+	case "d":
+		t, err := time.Parse("2006-01-02T15:04:05", ax[0])
+		if err != nil {
+			return ax[0], true
+		}
+		return t, true
+	case "m":
+		return nil, true
+	}
+	return nil, false
+}
+
+func (d *Document) parsePivotCache(ref pivotCacheRef) (*commonxl.PivotCache, error) {
+	pc := &commonxl.PivotCache{
+		ID:      ref.id,
+		Name:    "PivotCache" + strconv.Itoa(ref.id),
+		Records: &commonxl.Sheet{},
+	}
+
+	dec, clo, err := d.OpenXML(ref.docname)
+	if err != nil {
+		return nil, err
+	}
+	defer clo.Close()
+
+	recordsID := ""
+	var recFields []int
+	inShared := false
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case "pivotCacheDefinition":
+				ax := getAttrs(v.Attr, "id")
+				recordsID = ax[0]
+			case "worksheetSource":
+				ax := getAttrs(v.Attr, "ref", "sheet", "name")
+				if ax[2] != "" {
+					pc.Source = ax[2]
+				} else if ax[1] != "" {
+					pc.Source = ax[1] + "!" + ax[0]
+				} else {
+					pc.Source = ax[0]
+				}
+			case "cacheField":
+				ax := getAttrs(v.Attr, "name", "formula", "databaseField")
+				if ax[1] == "" && ax[2] != "0" {
+					recFields = append(recFields, len(pc.Fields))
+				}
+				pc.Fields = append(pc.Fields, ax[0])
+				pc.Items = append(pc.Items, nil)
+			case "sharedItems":
+				inShared = true
+			default:
+				if inShared && len(pc.Items) > 0 {
+					if val, ok := pivotValue(v); ok {
+						n := len(pc.Items) - 1
+						pc.Items[n] = append(pc.Items[n], val)
+					}
+				}
+			}
+		case xml.EndElement:
+			if v.Name.Local == "sharedItems" {
+				inShared = false
+			}
+		}
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+
+	header := make([]interface{}, len(recFields))
+	for i, fi := range recFields {
+		header[i] = pc.Fields[fi]
+	}
+	pc.Records.Append(header...)
+
+	rel, ok := d.PartRels(ref.docname)[recordsID]
+	if recordsID == "" || !ok {
+		// records are not always saved with the cache
+		return pc, nil
+	}
+	dec, clo2, err := d.OpenXML(rel.Target)
+	if err != nil {
+		return nil, err
+	}
+	defer clo2.Close()
+
+	var record []interface{}
+	tok, err = dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case "pivotCacheRecords":
+				// container
+			case "r":
+				record = make([]interface{}, 0, len(recFields))
+			case "x":
+				if len(record) >= len(recFields) {
+					continue
+				}
+				ax := getAttrs(v.Attr, "v")
+				idx, _ := strconv.Atoi(ax[0])
+				items := pc.Items[recFields[len(record)]]
+				if idx >= 0 && idx < len(items) {
+					record = append(record, items[idx])
+				} else {
+					record = append(record, nil)
+				}
+			default:
+				val, ok := pivotValue(v)
+				if !ok {
+					if grate.Debug {
+						log.Println("      Unhandled pivot record xml tag", v.Name.Local, v.Attr)
+					}
+					continue
+				}
+				if len(record) < len(recFields) {
+					record = append(record, val)
+				}
+			}
+		case xml.EndElement:
+			if v.Name.Local == "r" {
+				pc.Records.Append(record...)
+			}
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return pc, err
+}
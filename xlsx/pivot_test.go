@@ -0,0 +1,88 @@
+package xlsx
+
+import (
+	"reflect"
+	"testing"
+)
+
+const (
+	pivotWorkbookRels = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="` + relsNS + `/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="` + relsNS + `/styles" Target="styles.xml"/>` +
+		`<Relationship Id="rId3" Type="` + relsNS + `/pivotCacheDefinition" Target="pivotCache/pivotCacheDefinition1.xml"/>` +
+		`</Relationships>`
+	pivotWorkbook = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="` + relsNS + `">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+		`<pivotCaches><pivotCache cacheId="4" r:id="rId3"/></pivotCaches></workbook>`
+	pivotCacheRels = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="` + relsNS + `/pivotCacheRecords" Target="pivotCacheRecords1.xml"/>` +
+		`</Relationships>`
+	pivotCacheDefinition = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<pivotCacheDefinition xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="` + relsNS + `" r:id="rId1">` +
+		`<cacheSource type="worksheet"><worksheetSource ref="A1:C3" sheet="Data"/></cacheSource>` +
+		`<cacheFields count="4">` +
+		`<cacheField name="Region" numFmtId="0"><sharedItems count="2"><s v="East"/><s v="West"/></sharedItems></cacheField>` +
+		`<cacheField name="Units" numFmtId="0"><sharedItems containsNumber="1"/></cacheField>` +
+		`<cacheField name="Shipped" numFmtId="0"><sharedItems count="3"><b v="1"/><b v="0"/><m/></sharedItems></cacheField>` +
+		`<cacheField name="Ratio" numFmtId="0" formula="Units/2" databaseField="0"/>` +
+		`</cacheFields></pivotCacheDefinition>`
+	pivotCacheRecords = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<pivotCacheRecords xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="2">` +
+		`<r><x v="0"/><n v="10.5"/><x v="0"/></r>` +
+		`<r><x v="1"/><n v="7"/><x v="2"/></r>` +
+		`</pivotCacheRecords>`
+)
+
+// pivotParts returns the parts of a workbook with one pivot cache.
+func pivotParts() map[string]string {
+	return map[string]string{
+		"xl/_rels/workbook.xml.rels":                         pivotWorkbookRels,
+		"xl/workbook.xml":                                    pivotWorkbook,
+		"xl/pivotCache/pivotCacheDefinition1.xml":            pivotCacheDefinition,
+		"xl/pivotCache/_rels/pivotCacheDefinition1.xml.rels": pivotCacheRels,
+		"xl/pivotCache/pivotCacheRecords1.xml":               pivotCacheRecords,
+		"xl/worksheets/sheet1.xml":                           testSheet("A1", ""),
+	}
+}
+
+func TestPivotCaches(t *testing.T) {
+	d, _ := openTestSheet(t, pivotParts())
+	caches, err := d.PivotCaches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(caches) != 1 {
+		t.Fatalf("got %d pivot caches, want 1", len(caches))
+	}
+	pc := caches[0]
+	if pc.ID != 4 || pc.Name != "PivotCache4" || pc.Source != "Data!A1:C3" {
+		t.Errorf("got cache %d %q from %q", pc.ID, pc.Name, pc.Source)
+	}
+	if want := []string{"Region", "Units", "Shipped", "Ratio"}; !reflect.DeepEqual(pc.Fields, want) {
+		t.Errorf("got fields %q, want %q", pc.Fields, want)
+	}
+	wantItems := [][]interface{}{{"East", "West"}, nil, {true, false, nil}, nil}
+	if !reflect.DeepEqual(pc.Items, wantItems) {
+		t.Errorf("got items %v, want %v", pc.Items, wantItems)
+	}
+
+	c, err := d.GetPivotCache("PivotCache4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"Region", "Units", "Shipped"}, {"East", "10.5", "true"}, {"West", "7", ""}}
+	var got [][]string
+	for c.Next() {
+		got = append(got, c.Strings())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got records %q, want %q", got, want)
+	}
+
+	if _, err := d.GetPivotCache("PivotCache1"); err == nil {
+		t.Error("expected an error for an unknown pivot cache")
+	}
+}
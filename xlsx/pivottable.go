@@ -0,0 +1,150 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+const relTypePivotTable = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotTable"
+
+// PivotTables returns the layout of the pivot tables on the sheet.
+func (s *Sheet) PivotTables() []*commonxl.PivotTable {
+	if s.pivotTables != nil {
+		return s.pivotTables
+	}
+	s.pivotTables = []*commonxl.PivotTable{}
+	for _, rel := range s.d.PartRels(s.docname) {
+		if rel.Type != relTypePivotTable {
+			continue
+		}
+		pt, err := s.d.parsePivotTable(rel.Target)
+		if err != nil {
+			continue
+		}
+		s.pivotTables = append(s.pivotTables, pt)
+	}
+	return s.pivotTables
+}
+
+// ExcludePivotTables blanks out the output areas of the pivot tables on the
+// sheet, so that only the remaining cells are returned during iteration.
+// It should be called before the first call to Next.
+func (s *Sheet) ExcludePivotTables() {
+	for _, pt := range s.PivotTables() {
+		loc := pt.Location
+		// sheet rows are indexed by the 1-based row number
+		for r := loc.FirstRow + 1; r <= loc.LastRow+1 && r < len(s.rows); r++ {
+			cols := s.rows[r].cols
+			for c := loc.FirstCol; c <= loc.LastCol && c < len(cols); c++ {
+				cols[c] = nil
+			}
+		}
+	}
+}
+
+// parsePivotTable decodes a pivotTableDefinition part (section 18.10.1.73).
+func (d *Document) parsePivotTable(docname string) (*commonxl.PivotTable, error) {
+	dec, clo, err := d.OpenXML(docname)
+	if err != nil {
+		return nil, err
+	}
+	defer clo.Close()
+
+	pt := &commonxl.PivotTable{}
+	var cache *commonxl.PivotCache
+	var fieldNames []string
+	var rows, cols, pages []int
+	var data []commonxl.PivotDataField
+	var dataFields []int
+	var axis *[]int
+	depth := 0 // nesting within pivotField elements
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case "pivotTableDefinition":
+				ax := getAttrs(v.Attr, "name", "cacheId")
+				pt.Name = ax[0]
+				pt.CacheID, _ = strconv.Atoi(ax[1])
+				caches, err := d.PivotCaches()
+				if err == nil {
+					for _, c := range caches {
+						if c.ID == pt.CacheID {
+							cache = c
+						}
+					}
+				}
+			case "location":
+				ax := getAttrs(v.Attr, "ref")
+				pt.Location = refToRange(ax[0])
+			case "pivotField":
+				if depth == 0 {
+					ax := getAttrs(v.Attr, "name")
+					fieldNames = append(fieldNames, ax[0])
+				}
+				depth++
+			case "rowFields":
+				axis = &rows
+			case "colFields":
+				axis = &cols
+			case "field":
+				if axis != nil {
+					ax := getAttrs(v.Attr, "x")
+					x, _ := strconv.Atoi(ax[0])
+					*axis = append(*axis, x)
+				}
+			case "pageField":
+				ax := getAttrs(v.Attr, "fld")
+				x, _ := strconv.Atoi(ax[0])
+				pages = append(pages, x)
+			case "dataField":
+				ax := getAttrs(v.Attr, "name", "fld", "subtotal")
+				x, _ := strconv.Atoi(ax[1])
+				if ax[2] == "" {
+					ax[2] = "sum"
+				}
+				data = append(data, commonxl.PivotDataField{Name: ax[0], Function: ax[2]})
+				dataFields = append(dataFields, x)
+			}
+		case xml.EndElement:
+			switch v.Name.Local {
+			case "pivotField":
+				depth--
+			case "rowFields", "colFields":
+				axis = nil
+			}
+		}
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+
+	fieldName := func(i int) string {
+		if i >= 0 && i < len(fieldNames) && fieldNames[i] != "" {
+			return fieldNames[i]
+		}
+		return cache.FieldName(i)
+	}
+	for _, i := range rows {
+		pt.RowFields = append(pt.RowFields, fieldName(i))
+	}
+	for _, i := range cols {
+		pt.ColFields = append(pt.ColFields, fieldName(i))
+	}
+	for _, i := range pages {
+		pt.PageFields = append(pt.PageFields, fieldName(i))
+	}
+	for i, df := range data {
+		df.Field = fieldName(dataFields[i])
+		if df.Name == "" {
+			df.Name = strings.ToUpper(df.Function[:1]) + df.Function[1:] + " of " + df.Field
+		}
+		pt.DataFields = append(pt.DataFields, df)
+	}
+	return pt, nil
+}
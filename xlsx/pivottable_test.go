@@ -0,0 +1,67 @@
+package xlsx
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+const (
+	pivotSheetRels = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="` + relsNS + `/pivotTable" Target="../pivotTables/pivotTable1.xml"/>` +
+		`</Relationships>`
+	pivotTableDefinition = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<pivotTableDefinition xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" name="Sales" cacheId="4" dataCaption="Values">` +
+		`<location ref="B2:C4" firstHeaderRow="1" firstDataRow="1" firstDataCol="1"/>` +
+		`<pivotFields count="3">` +
+		`<pivotField axis="axisRow" showAll="0"><items count="3"><item x="0"/><item x="1"/><item t="default"/></items></pivotField>` +
+		`<pivotField dataField="1" showAll="0"/>` +
+		`<pivotField name="Sent" axis="axisPage" showAll="0"><items count="1"><item t="default"/></items></pivotField>` +
+		`</pivotFields>` +
+		`<rowFields count="1"><field x="0"/></rowFields>` +
+		`<colFields count="1"><field x="-2"/></colFields>` +
+		`<pageFields count="1"><pageField fld="2" hier="-1"/></pageFields>` +
+		`<dataFields count="2"><dataField fld="1"/><dataField name="Largest" fld="1" subtotal="max"/></dataFields>` +
+		`</pivotTableDefinition>`
+)
+
+func TestPivotTables(t *testing.T) {
+	parts := pivotParts()
+	parts["xl/worksheets/sheet1.xml"] = testSheet("A1:D4",
+		`<sheetData>`+
+			`<row r="1"><c r="A1"><v>1</v></c></row>`+
+			`<row r="2"><c r="A2"><v>2</v></c><c r="B2"><v>3</v></c><c r="C2"><v>4</v></c><c r="D2"><v>5</v></c></row>`+
+			`<row r="4"><c r="B4"><v>6</v></c></row>`+
+			`</sheetData>`)
+	parts["xl/worksheets/_rels/sheet1.xml.rels"] = pivotSheetRels
+	parts["xl/pivotTables/pivotTable1.xml"] = pivotTableDefinition
+	_, s := openTestSheet(t, parts)
+
+	want := []*commonxl.PivotTable{{
+		Name:       "Sales",
+		CacheID:    4,
+		Location:   commonxl.CellRange{FirstRow: 1, LastRow: 3, FirstCol: 1, LastCol: 2},
+		RowFields:  []string{"Region"},
+		ColFields:  []string{commonxl.PivotValuesField},
+		PageFields: []string{"Sent"},
+		DataFields: []commonxl.PivotDataField{
+			{Name: "Sum of Units", Field: "Units", Function: "sum"},
+			{Name: "Largest", Field: "Units", Function: "max"},
+		},
+	}}
+	if got := s.PivotTables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got pivot tables %+v, want %+v", got, want)
+	}
+
+	s.ExcludePivotTables()
+	wantRows := [][]string{{"1", "", "", ""}, {"2", "", "", "5"}, {"", "", "", ""}, {"", "", "", ""}}
+	var got [][]string
+	for s.Next() {
+		got = append(got, s.Strings())
+	}
+	if !reflect.DeepEqual(got, wantRows) {
+		t.Errorf("got rows %q, want %q", got, wantRows)
+	}
+}
@@ -30,6 +30,9 @@ type Sheet struct {
 	rows   []*row
 	empty  bool
 
+	condfmts    commonxl.CondFormats
+	pivotTables []*commonxl.PivotTable
+
 	iterRow int
 }
 
@@ -45,7 +48,7 @@ func (s *Sheet) parseSheet() error {
 	base := filepath.Base(s.docname)
 	sub := strings.TrimSuffix(s.docname, base)
 	relsname := filepath.Join(sub, "_rels", base+".rels")
-	dec, clo, err := s.d.openXML(relsname)
+	dec, clo, err := s.d.OpenXML(relsname)
 	if err == nil {
 		// rels might not exist for every sheet
 		tok, err := dec.RawToken()
@@ -60,7 +63,7 @@ func (s *Sheet) parseSheet() error {
 		clo.Close()
 	}
 
-	dec, clo, err = s.d.openXML(s.docname)
+	dec, clo, err = s.d.OpenXML(s.docname)
 	if err != nil {
 		return err
 	}
@@ -69,10 +72,17 @@ func (s *Sheet) parseSheet() error {
 	currentCellType := BlankCellType
 	currentCell := ""
 	var numFormat commonxl.FmtFunc
+	var curCF *commonxl.CondFormat
+	var curRule *commonxl.CondRule
+	inCFFormula := false
 	tok, err := dec.RawToken()
 	for ; err == nil; tok, err = dec.RawToken() {
 		switch v := tok.(type) {
 		case xml.CharData:
+			if inCFFormula {
+				curRule.Formulas[len(curRule.Formulas)-1] += string(v)
+				continue
+			}
 			if currentCell == "" {
 				continue
 			}
@@ -191,6 +201,38 @@ func (s *Sheet) parseSheet() error {
 				}
 				s.placeValue(row, col, link)
 
+			case "conditionalFormatting":
+				ax := getAttrs(v.Attr, "sqref")
+				curCF = &commonxl.CondFormat{}
+				for _, ref := range strings.Fields(ax[0]) {
+					curCF.Ranges = append(curCF.Ranges, refToRange(ref))
+				}
+				s.condfmts = append(s.condfmts, curCF)
+			case "cfRule":
+				if curCF == nil {
+					continue
+				}
+				ax := getAttrs(v.Attr, "type", "operator", "priority", "stopIfTrue", "dxfId", "text")
+				curRule = &commonxl.CondRule{
+					Type:       ax[0],
+					Operator:   ax[1],
+					StopIfTrue: ax[3] == "1" || ax[3] == "true",
+					Text:       ax[5],
+				}
+				curRule.Priority, _ = strconv.Atoi(ax[2])
+				if ax[4] != "" {
+					dxfID, _ := strconv.Atoi(ax[4])
+					if dxfID >= 0 && dxfID < len(s.d.dxfs) {
+						curRule.Style = s.d.dxfs[dxfID]
+					}
+				}
+				curCF.Rules = append(curCF.Rules, curRule)
+			case "formula":
+				if curRule != nil {
+					inCFFormula = true
+					curRule.Formulas = append(curRule.Formulas, "")
+				}
+
 			case "worksheet", "mergeCells", "hyperlinks":
 				// containers
 			case "f":
@@ -203,6 +245,12 @@ func (s *Sheet) parseSheet() error {
 		case xml.EndElement:
 
 			switch v.Name.Local {
+			case "formula":
+				inCFFormula = false
+			case "cfRule":
+				curRule = nil
+			case "conditionalFormatting":
+				curCF = nil
 			case "c":
 				currentCell = ""
 			case "row":
@@ -279,6 +327,26 @@ func (s *Sheet) Scan(args ...interface{}) error {
 	return nil
 }
 
+// ConditionalFormats returns the conditional formatting rules defined on the sheet.
+func (s *Sheet) ConditionalFormats() commonxl.CondFormats {
+	return s.condfmts
+}
+
+// MatchingRules returns the conditional formatting rules which apply to the
+// value in column col of the current row. Only the simple cell-value rules are
+// evaluated, see commonxl.CondRule.Evaluate for details.
+func (s *Sheet) MatchingRules(col int) []*commonxl.CondRule {
+	if s.iterRow < 0 || s.iterRow >= len(s.rows) {
+		return nil
+	}
+	currow := s.rows[s.iterRow]
+	if col < 0 || col >= len(currow.cols) {
+		return nil
+	}
+	// sheet rows are indexed by the 1-based row number
+	return s.condfmts.Matching(s.iterRow-1, col, currow.cols[col])
+}
+
 func (s *Sheet) IsEmpty() bool {
 	return s.empty
 }
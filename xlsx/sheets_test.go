@@ -0,0 +1,140 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+const (
+	relsNS = `http://schemas.openxmlformats.org/officeDocument/2006/relationships`
+
+	testTypes = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`</Types>`
+	testRels = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="` + relsNS + `/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	testWorkbookRels = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="` + relsNS + `/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="` + relsNS + `/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+	testWorkbook = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="` + relsNS + `">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	testStyles = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<cellStyleXfs count="1"><xf numFmtId="0"/></cellStyleXfs>` +
+		`<cellXfs count="1"><xf numFmtId="0" xfId="0"/></cellXfs>` +
+		`<dxfs count="2">` +
+		`<dxf><font><b/><color rgb="FF9C0006"/></font><fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill></dxf>` +
+		`<dxf><numFmt numFmtId="164" formatCode="0.0%"/></dxf>` +
+		`</dxfs></styleSheet>`
+)
+
+// writeTestFile writes a workbook with a single sheet, where parts replace
+// or add to the default parts.
+func writeTestFile(t *testing.T, parts map[string]string) string {
+	t.Helper()
+	files := map[string]string{
+		"[Content_Types].xml":        testTypes,
+		"_rels/.rels":                testRels,
+		"xl/_rels/workbook.xml.rels": testWorkbookRels,
+		"xl/workbook.xml":            testWorkbook,
+		"xl/styles.xml":              testStyles,
+	}
+	for name, data := range parts {
+		files[name] = data
+	}
+
+	fn := filepath.Join(t.TempDir(), "test.xlsx")
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	for name, data := range files {
+		w, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	if err = z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return fn
+}
+
+// testSheet returns a worksheet part with the given dimension and content.
+func testSheet(dimension, content string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="` + relsNS + `">` +
+		`<dimension ref="` + dimension + `"/>` + content + `</worksheet>`
+}
+
+func openTestSheet(t *testing.T, parts map[string]string) (*Document, *Sheet) {
+	t.Helper()
+	src, err := Open(writeTestFile(t, parts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { src.Close() })
+	c, err := src.Get("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src.(*Document), c.(*Sheet)
+}
+
+func TestConditionalFormats(t *testing.T) {
+	d, s := openTestSheet(t, map[string]string{
+		"xl/worksheets/sheet1.xml": testSheet("A1:B3",
+			`<sheetData><row r="1"><c r="A1"><v>150</v></c></row></sheetData>`+
+				`<conditionalFormatting sqref="A1:A3 B2">`+
+				`<cfRule type="cellIs" dxfId="0" priority="2" operator="between" stopIfTrue="1">`+
+				`<formula>10</formula><formula>$B$1*2</formula></cfRule>`+
+				`<cfRule type="containsText" dxfId="1" priority="1" operator="containsText" text="ok">`+
+				`<formula>NOT(ISERROR(SEARCH("ok",A1)))</formula></cfRule>`+
+				`</conditionalFormatting>`+
+				`<conditionalFormatting sqref="B1:B3"><cfRule type="dataBar" priority="3">`+
+				`<dataBar><cfvo type="min"/><cfvo type="max"/><color rgb="FF638EC6"/></dataBar></cfRule>`+
+				`</conditionalFormatting>`),
+	})
+
+	wantStyles := []*commonxl.DiffStyle{
+		{Bold: true, FontColor: "9C0006", FillColor: "FFC7CE"},
+		{NumFmt: "0.0%"},
+	}
+	if !reflect.DeepEqual(d.DiffStyles(), wantStyles) {
+		t.Errorf("got styles %+v, want %+v", d.DiffStyles(), wantStyles)
+	}
+
+	want := commonxl.CondFormats{
+		{
+			Ranges: []commonxl.CellRange{{FirstRow: 0, LastRow: 2}, {FirstRow: 1, LastRow: 1, FirstCol: 1, LastCol: 1}},
+			Rules: []*commonxl.CondRule{
+				{Type: commonxl.CondCellIs, Operator: "between", Formulas: []string{"10", "$B$1*2"},
+					Priority: 2, StopIfTrue: true, Style: wantStyles[0]},
+				{Type: commonxl.CondContainsText, Operator: "containsText", Text: "ok",
+					Formulas: []string{`NOT(ISERROR(SEARCH("ok",A1)))`}, Priority: 1, Style: wantStyles[1]},
+			},
+		},
+		{
+			Ranges: []commonxl.CellRange{{FirstRow: 0, LastRow: 2, FirstCol: 1, LastCol: 1}},
+			Rules:  []*commonxl.CondRule{{Type: commonxl.CondDataBar, Priority: 3}},
+		},
+	}
+	if got := s.ConditionalFormats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got conditional formats %+v, want %+v", got, want)
+	}
+}
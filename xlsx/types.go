@@ -4,6 +4,8 @@ import (
 	"encoding/xml"
 	"strconv"
 	"strings"
+
+	"github.com/pbnjay/grate/commonxl"
 )
 
 type CellType string
@@ -90,3 +92,29 @@ func getAttrs(attrs []xml.Attr, keys ...string) []string {
 	}
 	return res
 }
+
+// refToRange converts an A1-style range reference (e.g. "B2:D10") to a
+// 0-based cell range.
+func refToRange(ref string) commonxl.CellRange {
+	dims := strings.Split(ref, ":")
+	c1, r1 := refToIndexes(strings.ReplaceAll(dims[0], "$", ""))
+	c2, r2 := c1, r1
+	if len(dims) > 1 {
+		c2, r2 = refToIndexes(strings.ReplaceAll(dims[1], "$", ""))
+	}
+	return commonxl.CellRange{FirstRow: r1 - 1, LastRow: r2 - 1, FirstCol: c1, LastCol: c2}
+}
+
+// getColor returns the RRGGBB hex value of a CT_Color element's attributes.
+// Theme colors are not supported and return an empty string.
+func getColor(attrs []xml.Attr) string {
+	ax := getAttrs(attrs, "rgb", "indexed")
+	if len(ax[0]) >= 6 {
+		return strings.ToUpper(ax[0][len(ax[0])-6:])
+	}
+	if ax[1] != "" {
+		icv, _ := strconv.Atoi(ax[1])
+		return commonxl.IndexedColor(nil, icv)
+	}
+	return ""
+}
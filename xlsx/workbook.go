@@ -5,52 +5,12 @@ import (
 	"errors"
 	"io"
 	"log"
-	"path/filepath"
 	"strconv"
 
 	"github.com/pbnjay/grate"
 	"github.com/pbnjay/grate/commonxl"
 )
 
-func (d *Document) parseRels(dec *xml.Decoder, basedir string) error {
-	tok, err := dec.RawToken()
-	for ; err == nil; tok, err = dec.RawToken() {
-		switch v := tok.(type) {
-		case xml.StartElement:
-			switch v.Name.Local {
-			case "Relationships":
-				// container
-			case "Relationship":
-				vals := make(map[string]string, 5)
-				for _, a := range v.Attr {
-					vals[a.Name.Local] = a.Value
-				}
-				if _, ok := d.rels[vals["Type"]]; !ok {
-					d.rels[vals["Type"]] = make(map[string]string)
-				}
-				d.rels[vals["Type"]][vals["Id"]] = filepath.Join(basedir, vals["Target"])
-				if vals["Type"] == "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" {
-					d.primaryDoc = vals["Target"]
-				}
-			default:
-				if grate.Debug {
-					log.Println("      Unhandled relationship xml tag", v.Name.Local, v.Attr)
-				}
-			}
-		case xml.EndElement:
-			// not needed
-		default:
-			if grate.Debug {
-				log.Printf("      Unhandled relationship xml tokens %T %+v", tok, tok)
-			}
-		}
-	}
-	if err == io.EOF {
-		err = nil
-	}
-	return err
-}
-
 func (d *Document) parseWorkbook(dec *xml.Decoder) error {
 	tok, err := dec.RawToken()
 	for ; err == nil; tok, err = dec.RawToken() {
@@ -74,8 +34,18 @@ func (d *Document) parseWorkbook(dec *xml.Decoder) error {
 					docname: d.rels["http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"][sheetID],
 					err:     errNotLoaded,
 				}
+				if s.docname == "" {
+					s.docname = d.rels["http://schemas.openxmlformats.org/officeDocument/2006/relationships/chartsheet"][sheetID]
+				}
 				d.sheets = append(d.sheets, s)
-			case "workbook", "sheets":
+			case "pivotCache":
+				ax := getAttrs(v.Attr, "cacheId", "id")
+				cacheID, _ := strconv.Atoi(ax[0])
+				d.pivotRefs = append(d.pivotRefs, pivotCacheRef{
+					id:      cacheID,
+					docname: d.rels["http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheDefinition"][ax[1]],
+				})
+			case "workbook", "sheets", "pivotCaches":
 				// containers
 			default:
 				if grate.Debug {
@@ -101,10 +71,49 @@ func (d *Document) parseStyles(dec *xml.Decoder) error {
 	d.xfs = d.xfs[:0]
 
 	section := 0
+	inFont := false
+	var dxf *commonxl.DiffStyle
 	tok, err := dec.RawToken()
 	for ; err == nil; tok, err = dec.RawToken() {
 		switch v := tok.(type) {
 		case xml.StartElement:
+			if section == 3 {
+				// differential formats re-use many of the tag names below
+				switch v.Name.Local {
+				case "dxf":
+					dxf = &commonxl.DiffStyle{}
+				case "font":
+					inFont = true
+				case "numFmt":
+					ax := getAttrs(v.Attr, "formatCode")
+					dxf.NumFmt = ax[0]
+				case "b", "i", "u", "strike":
+					ax := getAttrs(v.Attr, "val")
+					on := ax[0] != "0" && ax[0] != "false" && ax[0] != "none"
+					switch v.Name.Local {
+					case "b":
+						dxf.Bold = on
+					case "i":
+						dxf.Italic = on
+					case "u":
+						dxf.Underline = on
+					case "strike":
+						dxf.Strike = on
+					}
+				case "color":
+					if inFont {
+						dxf.FontColor = getColor(v.Attr)
+					}
+				case "bgColor":
+					dxf.FillColor = getColor(v.Attr)
+				case "fgColor":
+					if dxf.FillColor == "" {
+						dxf.FillColor = getColor(v.Attr)
+					}
+				}
+				continue
+			}
+
 			switch v.Name.Local {
 			case "styleSheet":
 				// container
@@ -113,6 +122,9 @@ func (d *Document) parseStyles(dec *xml.Decoder) error {
 				fmtNo, _ := strconv.ParseInt(ax[0], 10, 16)
 				d.fmt.Add(uint16(fmtNo), ax[1])
 
+			case "dxfs":
+				section = 3
+
 			case "cellStyleXfs":
 				section = 1
 			case "cellXfs":
@@ -167,6 +179,15 @@ func (d *Document) parseStyles(dec *xml.Decoder) error {
 				section = 0
 			case "cellXfs":
 				section = 0
+			case "dxfs":
+				section = 0
+			case "font":
+				inFont = false
+			case "dxf":
+				if dxf != nil {
+					d.dxfs = append(d.dxfs, dxf)
+				}
+				dxf = nil
 			}
 		default:
 			if grate.Debug {
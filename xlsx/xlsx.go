@@ -1,14 +1,7 @@
 package xlsx
 
 import (
-	"archive/zip"
-	"encoding/xml"
 	"errors"
-	"io"
-	"log"
-	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/pbnjay/grate"
 	"github.com/pbnjay/grate/commonxl"
@@ -18,17 +11,22 @@ var _ = grate.Register("xlsx", 5, Open)
 
 // Document contains an Office Open XML document.
 type Document struct {
-	filename   string
-	f          *os.File
-	r          *zip.Reader
-	primaryDoc string
+	*Package
 
-	// type => id => filename
-	rels    map[string]map[string]string
-	sheets  []*Sheet
-	strings []string
-	xfs     []commonxl.FmtFunc
-	fmt     commonxl.Formatter
+	filename string
+	sheets   []*Sheet
+	strings  []string
+	xfs      []commonxl.FmtFunc
+	fmt      commonxl.Formatter
+	dxfs     []*commonxl.DiffStyle
+
+	pivotRefs   []pivotCacheRef
+	pivotCaches []*commonxl.PivotCache
+}
+
+// DiffStyles returns the differential formats defined in the workbook.
+func (d *Document) DiffStyles() []*commonxl.DiffStyle {
+	return d.dxfs
 }
 
 func (d *Document) Close() error {
@@ -38,60 +36,25 @@ func (d *Document) Close() error {
 	d.strings = nil
 	d.sheets = d.sheets[:0]
 	d.sheets = nil
-	return d.f.Close()
+	return d.Package.Close()
 }
 
 func Open(filename string) (grate.Source, error) {
-	f, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	info, err := f.Stat()
+	p, err := OpenPackage(filename)
 	if err != nil {
 		return nil, err
 	}
-	z, err := zip.NewReader(f, info.Size())
-	if err != nil {
-		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
-	}
 	d := &Document{
+		Package:  p,
 		filename: filename,
-		f:        f,
-		r:        z,
-	}
-
-	d.rels = make(map[string]map[string]string, 4)
-
-	// parse the primary relationships
-	dec, c, err := d.openXML("_rels/.rels")
-	if err != nil {
-		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
-	}
-	err = d.parseRels(dec, "")
-	c.Close()
-	if err != nil {
-		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
 	}
-	if d.primaryDoc == "" {
-		return nil, errors.New("xlsx: invalid document")
-	}
-
-	// parse the secondary relationships to primary doc
-	base := filepath.Base(d.primaryDoc)
-	sub := strings.TrimSuffix(d.primaryDoc, base)
-	relfn := filepath.Join(sub, "_rels", base+".rels")
-	dec, c, err = d.openXML(relfn)
-	if err != nil {
-		return nil, err
-	}
-	err = d.parseRels(dec, sub)
-	c.Close()
-	if err != nil {
-		return nil, err
+	if !d.isWorkbookPart() {
+		p.Close()
+		return nil, grate.ErrNotInFormat
 	}
 
 	// parse the workbook structure
-	dec, c, err = d.openXML(d.primaryDoc)
+	dec, c, err := d.OpenXML(d.primaryDoc)
 	if err != nil {
 		return nil, err
 	}
@@ -104,7 +67,7 @@ func Open(filename string) (grate.Source, error) {
 	styn := d.rels["http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"]
 	for _, sst := range styn {
 		// parse the shared string table
-		dec, c, err = d.openXML(sst)
+		dec, c, err = d.OpenXML(sst)
 		if err != nil {
 			return nil, err
 		}
@@ -118,7 +81,7 @@ func Open(filename string) (grate.Source, error) {
 	ssn := d.rels["http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings"]
 	for _, sst := range ssn {
 		// parse the shared string table
-		dec, c, err = d.openXML(sst)
+		dec, c, err = d.OpenXML(sst)
 		if err != nil {
 			return nil, err
 		}
@@ -132,23 +95,6 @@ func Open(filename string) (grate.Source, error) {
 	return d, nil
 }
 
-func (d *Document) openXML(name string) (*xml.Decoder, io.Closer, error) {
-	if grate.Debug {
-		log.Println("    openXML", name)
-	}
-	for _, zf := range d.r.File {
-		if zf.Name == name {
-			zfr, err := zf.Open()
-			if err != nil {
-				return nil, nil, err
-			}
-			dec := xml.NewDecoder(zfr)
-			return dec, zfr, nil
-		}
-	}
-	return nil, nil, io.EOF
-}
-
 func (d *Document) List() ([]string, error) {
 	res := make([]string, 0, len(d.sheets))
 	for _, s := range d.sheets {
@@ -0,0 +1,249 @@
+package xmlss
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// limits of the cell grid, used to bound row and cell indexes
+const (
+	maxRows = 1 << 20
+	maxCols = 1 << 14
+)
+
+type style struct {
+	parent    string
+	format    string
+	hasFormat bool
+}
+
+type parser struct {
+	d *Document
+
+	curStyle *style
+
+	cur     *worksheet
+	merges  []commonxl.CellRange
+	row     int
+	nextRow int
+	col     int
+
+	// current cell
+	inCell     bool
+	cellAttrs  []string
+	dataType   string
+	dataDepth  int
+	text       strings.Builder
+	commentDep int
+}
+
+func (d *Document) parse(dec *xml.Decoder) error {
+	p := &parser{d: d}
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			p.start(v)
+		case xml.EndElement:
+			p.end(v)
+		case xml.CharData:
+			if p.dataDepth > 0 {
+				p.text.Write(v)
+			}
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return err
+}
+
+func (p *parser) start(v xml.StartElement) {
+	if p.commentDep > 0 {
+		p.commentDep++
+		return
+	}
+	if p.dataDepth > 0 {
+		// rich text formatting within the cell data
+		p.dataDepth++
+		return
+	}
+
+	switch v.Name.Local {
+	case "Style":
+		ax := getAttrs(v.Attr, "ID", "Parent")
+		p.curStyle = &style{parent: ax[1]}
+		p.d.styles[ax[0]] = p.curStyle
+	case "NumberFormat":
+		if p.curStyle != nil {
+			p.curStyle.format = getAttrs(v.Attr, "Format")[0]
+			p.curStyle.hasFormat = true
+		}
+
+	case "Worksheet":
+		p.cur = &worksheet{
+			name:  getAttrs(v.Attr, "Name")[0],
+			sheet: &commonxl.Sheet{},
+		}
+		p.merges = p.merges[:0]
+		p.nextRow = 0
+	case "Row":
+		if p.cur == nil {
+			return
+		}
+		ax := getAttrs(v.Attr, "Index", "Span")
+		p.row = p.nextRow
+		if idx, err := strconv.Atoi(ax[0]); err == nil && idx > 0 {
+			p.row = idx - 1
+		}
+		span, _ := strconv.Atoi(ax[1])
+		if span < 0 {
+			span = 0
+		}
+		p.nextRow = p.row + 1 + span
+		p.col = 0
+	case "Cell":
+		if p.cur == nil {
+			return
+		}
+		p.inCell = true
+		p.cellAttrs = getAttrs(v.Attr, "Index", "MergeAcross", "MergeDown", "StyleID")
+		if idx, err := strconv.Atoi(p.cellAttrs[0]); err == nil && idx > 0 {
+			p.col = idx - 1
+		}
+		p.dataType = ""
+		p.text.Reset()
+	case "Data":
+		if p.inCell {
+			p.dataType = getAttrs(v.Attr, "Type")[0]
+			p.dataDepth = 1
+		}
+	case "Comment":
+		p.commentDep = 1
+
+	case "Workbook", "Styles", "Table", "Column", "Alignment", "Borders", "Border",
+		"Font", "Interior", "Protection", "NamedCell":
+		// containers, or not needed
+	default:
+		if grate.Debug {
+			log.Println("      Unhandled spreadsheet xml tag", v.Name.Local, v.Attr)
+		}
+	}
+}
+
+func (p *parser) end(v xml.EndElement) {
+	if p.commentDep > 0 {
+		p.commentDep--
+		return
+	}
+	if p.dataDepth > 0 {
+		p.dataDepth--
+		return
+	}
+
+	switch v.Name.Local {
+	case "Style":
+		p.curStyle = nil
+	case "Cell":
+		if p.inCell {
+			p.endCell()
+		}
+	case "Worksheet":
+		if p.cur == nil {
+			return
+		}
+		for _, m := range p.merges {
+			p.cur.sheet.Merge(m)
+		}
+		p.d.sheets = append(p.d.sheets, p.cur)
+		p.cur = nil
+	}
+}
+
+func (p *parser) endCell() {
+	p.inCell = false
+	ax := p.cellAttrs
+	across, _ := strconv.Atoi(ax[1])
+	down, _ := strconv.Atoi(ax[2])
+	if across < 0 {
+		across = 0
+	}
+	if down < 0 {
+		down = 0
+	}
+	if p.row >= maxRows || p.col >= maxCols {
+		return
+	}
+
+	if p.dataType != "" {
+		p.cur.sheet.Put(p.row, p.col, p.d.cellValue(p.dataType, p.text.String(), ax[3]))
+	}
+	if (across > 0 || down > 0) && p.row+down < maxRows && p.col+across < maxCols {
+		p.merges = append(p.merges, commonxl.CellRange{
+			FirstRow: p.row, LastRow: p.row + down,
+			FirstCol: p.col, LastCol: p.col + across,
+		})
+	}
+	p.col += 1 + across
+}
+
+// cellValue decodes the data of a cell according to its ss:Type, applying
+// the number format of the cell's style.
+func (d *Document) cellValue(dataType, text, styleID string) interface{} {
+	switch dataType {
+	case "Number":
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return text
+		}
+		s, _ := d.fmt.Apply(d.formatID(styleID), f)
+		return s
+	case "DateTime":
+		t, err := time.Parse("2006-01-02T15:04:05", strings.TrimSpace(text))
+		if err != nil {
+			return text
+		}
+		if fno := d.formatID(styleID); fno != 0 {
+			s, _ := d.fmt.Apply(fno, t)
+			return s
+		}
+		return t
+	case "Boolean":
+		b, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return text
+		}
+		return b
+	}
+	// String and Error values
+	return text
+}
+
+// formatID returns the number format of a style, following the chain of
+// parent styles. Cells without a style use the "Default" style.
+func (d *Document) formatID(styleID string) uint16 {
+	if styleID == "" {
+		styleID = "Default"
+	}
+	for i := 0; i < 16; i++ {
+		st, ok := d.styles[styleID]
+		if !ok {
+			break
+		}
+		if st.hasFormat {
+			return d.fmt.AddCode(st.format)
+		}
+		if st.parent == "" {
+			break
+		}
+		styleID = st.parent
+	}
+	return 0
+}
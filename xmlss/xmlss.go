@@ -0,0 +1,136 @@
+// Package xmlss reads Excel 2003 XML Spreadsheet (SpreadsheetML) files.
+// These are often exported with an .xls extension, so they are detected by
+// their content rather than by name.
+package xmlss
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commontext"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+var _ = grate.Register("xmlss", 8, Open)
+
+const spreadsheetNS = "urn:schemas-microsoft-com:office:spreadsheet"
+
+// Document contains an Excel 2003 XML Spreadsheet.
+type Document struct {
+	filename string
+	sheets   []*worksheet
+	styles   map[string]*style
+	fmt      commonxl.Formatter
+}
+
+type worksheet struct {
+	name  string
+	sheet *commonxl.Sheet
+}
+
+// Open an Excel 2003 XML Spreadsheet.
+func Open(filename string) (grate.Source, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := xml.NewDecoder(bufio.NewReader(f))
+	dec.CharsetReader = charsetReader
+	for {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+		}
+		switch v := tok.(type) {
+		case xml.ProcInst, xml.Comment, xml.Directive:
+			continue
+		case xml.CharData:
+			if len(bytes.TrimSpace(v)) == 0 {
+				continue
+			}
+			return nil, grate.ErrNotInFormat
+		case xml.StartElement:
+			if v.Name.Local != "Workbook" || !declaresNamespace(v) {
+				return nil, grate.ErrNotInFormat
+			}
+			d := &Document{
+				filename: filename,
+				styles:   make(map[string]*style),
+			}
+			if err = d.parse(dec); err != nil {
+				return nil, err
+			}
+			return d, nil
+		default:
+			return nil, grate.ErrNotInFormat
+		}
+	}
+}
+
+// charsetReader decodes files which declare a legacy encoding, as is
+// common in exports from older systems.
+func charsetReader(label string, r io.Reader) (io.Reader, error) {
+	enc := commontext.LookupEncoding(label)
+	if enc == nil {
+		return nil, fmt.Errorf("xmlss: unsupported encoding %q", label)
+	}
+	return enc.NewReader(r), nil
+}
+
+// declaresNamespace returns true if the element declares the spreadsheet
+// namespace, either as the default or with a prefix.
+func declaresNamespace(v xml.StartElement) bool {
+	for _, a := range v.Attr {
+		if (a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns")) && a.Value == spreadsheetNS {
+			return true
+		}
+	}
+	return false
+}
+
+// Close the document.
+func (d *Document) Close() error {
+	d.sheets = nil
+	d.styles = nil
+	return nil
+}
+
+// List returns the names of the worksheets in the document.
+func (d *Document) List() ([]string, error) {
+	res := make([]string, 0, len(d.sheets))
+	for _, s := range d.sheets {
+		res = append(res, s.name)
+	}
+	return res, nil
+}
+
+// Get the named worksheet.
+func (d *Document) Get(sheetName string) (grate.Collection, error) {
+	for _, s := range d.sheets {
+		if s.name == sheetName {
+			s.sheet.Rewind()
+			return s.sheet, nil
+		}
+	}
+	return nil, errors.New("xmlss: sheet not found")
+}
+
+func getAttrs(attrs []xml.Attr, keys ...string) []string {
+	res := make([]string, len(keys))
+	for _, a := range attrs {
+		for i, k := range keys {
+			if a.Name.Local == k {
+				res[i] = a.Value
+			}
+		}
+	}
+	return res
+}
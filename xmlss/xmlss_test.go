@@ -0,0 +1,133 @@
+package xmlss
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pbnjay/grate"
+)
+
+const testDoc = `<?xml version="1.0"?>
+<?mso-application progid="Excel.Sheet"?>
+<Workbook xmlns="urn:schemas-microsoft-com:office:spreadsheet"
+ xmlns:o="urn:schemas-microsoft-com:office:office"
+ xmlns:ss="urn:schemas-microsoft-com:office:spreadsheet"
+ xmlns:html="http://www.w3.org/TR/REC-html40">
+ <Styles>
+  <Style ss:ID="Default" ss:Name="Normal"><Alignment ss:Vertical="Bottom"/></Style>
+  <Style ss:ID="s21"><NumberFormat ss:Format="Short Date"/></Style>
+  <Style ss:ID="s22"><NumberFormat ss:Format="0.000"/></Style>
+  <Style ss:ID="s23" ss:Parent="s22"><Font ss:Bold="1"/></Style>
+  <Style ss:ID="s24"><NumberFormat ss:Format="Percent"/></Style>
+ </Styles>
+ <Worksheet ss:Name="Orders">
+  <Table ss:ExpandedColumnCount="4" ss:ExpandedRowCount="6">
+   <Row>
+    <Cell><Data ss:Type="String">Item</Data></Cell>
+    <Cell ss:Index="3"><Data ss:Type="String">Qty</Data></Cell>
+    <Cell><ss:Data ss:Type="String" xmlns="http://www.w3.org/TR/REC-html40"><B>Big</B> deal</ss:Data></Cell>
+   </Row>
+   <Row>
+    <Cell><Data ss:Type="Number">42</Data><Comment><Data>not this</Data></Comment></Cell>
+    <Cell ss:StyleID="s23"><Data ss:Type="Number">3.14159</Data></Cell>
+    <Cell ss:StyleID="s24"><Data ss:Type="Number">0.5</Data></Cell>
+    <Cell><Data ss:Type="Boolean">1</Data></Cell>
+   </Row>
+   <Row ss:Index="4">
+    <Cell ss:StyleID="s21"><Data ss:Type="DateTime">2021-03-04T00:00:00.000</Data></Cell>
+    <Cell><Data ss:Type="DateTime">2021-03-04T05:06:07.000</Data></Cell>
+    <Cell><Data ss:Type="Error">#N/A</Data></Cell>
+   </Row>
+   <Row>
+    <Cell ss:MergeAcross="1" ss:MergeDown="1"><Data ss:Type="String">merged</Data></Cell>
+    <Cell><Data ss:Type="String">x</Data></Cell>
+   </Row>
+   <Row>
+    <Cell ss:Index="3"><Data ss:Type="String">y</Data></Cell>
+   </Row>
+  </Table>
+ </Worksheet>
+ <Worksheet ss:Name="Empty"><Table/></Worksheet>
+</Workbook>`
+
+func TestOpen(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "export.xls")
+	if err := os.WriteFile(fn, []byte(testDoc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wb, err := grate.Open(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wb.Close()
+
+	names, _ := wb.List()
+	if !reflect.DeepEqual(names, []string{"Orders", "Empty"}) {
+		t.Fatalf("got sheets %v", names)
+	}
+	sheet, err := wb.Get("Orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]string{
+		{"Item", "", "Qty", "Big deal"},
+		{"42", "3.142", "50.00%", "true"},
+		{"", "", "", ""},
+		{"03-04-21", "2021-03-04 05:06:07", "#N/A", ""},
+		{"merged", "⇥", "x", ""},
+		{"⤓", "⇥", "y", ""},
+	}
+	var got [][]string
+	for sheet.Next() {
+		got = append(got, sheet.Strings())
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("got %q\nexpected %q", got, expect)
+	}
+
+	sheet, _ = wb.Get("Empty")
+	if !sheet.IsEmpty() {
+		t.Fatal("expected an empty sheet")
+	}
+}
+
+func TestLegacyEncoding(t *testing.T) {
+	doc := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		`<Workbook xmlns="urn:schemas-microsoft-com:office:spreadsheet" xmlns:ss="urn:schemas-microsoft-com:office:spreadsheet">` +
+		`<Worksheet ss:Name="Kunden"><Table><Row>` +
+		"<Cell><Data ss:Type=\"String\">M\xfcller \x80</Data></Cell>" +
+		`</Row></Table></Worksheet></Workbook>`
+	fn := filepath.Join(t.TempDir(), "export.xls")
+	if err := os.WriteFile(fn, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wb, err := grate.Open(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wb.Close()
+	sheet, err := wb.Get("Kunden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sheet.Next() || sheet.Strings()[0] != "Müller €" {
+		t.Fatalf("got %q", sheet.Strings())
+	}
+}
+
+func TestNotInFormat(t *testing.T) {
+	for _, data := range []string{
+		"a,b,c\n1,2,3\n",
+		`<?xml version="1.0"?><Workbook xmlns="urn:example"/>`,
+		`<html><table><tr><td>1</td></tr></table></html>`,
+	} {
+		fn := filepath.Join(t.TempDir(), "test.xls")
+		os.WriteFile(fn, []byte(data), 0644)
+		if _, err := Open(fn); !errors.Is(err, grate.ErrNotInFormat) {
+			t.Fatalf("expected ErrNotInFormat, got %v", err)
+		}
+	}
+}